@@ -2,21 +2,42 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"sync"
 
+	"github.com/accuknox/rinc/internal/alerttest"
 	"github.com/accuknox/rinc/internal/conf"
-	"github.com/accuknox/rinc/internal/db"
 	"github.com/accuknox/rinc/internal/job"
 	"github.com/accuknox/rinc/internal/kube"
 	"github.com/accuknox/rinc/internal/schema"
+	"github.com/accuknox/rinc/internal/store"
+	"github.com/accuknox/rinc/internal/util"
 	"github.com/accuknox/rinc/internal/web"
 )
 
 func main() {
-	conf, err := conf.New(os.Args[1:]...)
+	if len(os.Args) > 2 && os.Args[1] == "alerts" && os.Args[2] == "test" {
+		err := alerttest.Run(context.Background(), os.Args[3:], os.Stdout)
+		if errors.Is(err, alerttest.ErrFailed) {
+			os.Exit(1)
+		}
+		if err != nil {
+			log.Fatalf("testing alerts: %s", err.Error())
+		}
+		return
+	}
+
+	migrateOnly := len(os.Args) > 1 && os.Args[1] == "migrate"
+	args := os.Args[1:]
+	if migrateOnly {
+		args = os.Args[2:]
+	}
+
+	conf, err := conf.New(args...)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -34,29 +55,42 @@ func main() {
 		return
 	}
 
-	mongo, err := db.NewMongoDBClient(conf.Mongodb)
+	store, err := store.New(context.Background(), *conf)
 	if err != nil {
-		log.Fatalf("creating mongo client: %s", err.Error())
+		log.Fatalf("connecting to %s: %s", conf.Storage.Backend, err.Error())
 	}
 	defer func() {
 		ctx := context.TODO()
-		err := mongo.Disconnect(ctx)
+		err := store.Close(ctx)
 		if err != nil {
 			slog.LogAttrs(
 				ctx,
 				slog.LevelError,
-				"closing mongodb client connection",
+				"closing database connection",
 				slog.String("error", err.Error()),
 			)
 		}
 		slog.LogAttrs(
 			ctx,
 			slog.LevelDebug,
-			"closed mongodb client connection",
+			"closed database connection",
 		)
 	}()
 
-	if conf.RunAsScraper {
+	if migrateOnly || conf.Storage.AutoMigrate {
+		slog.SetDefault(util.NewLogger(conf.Log))
+		from, to, err := store.Migrate(context.Background())
+		if err != nil {
+			log.Fatalf("migrating database: %s", err.Error())
+		}
+		if migrateOnly {
+			fmt.Printf("schema version: %d -> %d\n", from, to)
+			return
+		}
+	}
+
+	var daemon sync.WaitGroup
+	if conf.RunAsScraper || conf.RunAsDaemon {
 		kubeClient, err := kube.NewClient(conf.KubernetesClient)
 		if err != nil {
 			log.Fatalf("kubernetes client: %s", err.Error())
@@ -65,17 +99,40 @@ func main() {
 		if err != nil {
 			log.Fatalf("kubernetes metrics client: %s", err.Error())
 		}
-		job := job.New(*conf, kubeClient, metricsClient, mongo)
-		err = job.GenerateAll(context.Background())
-		if err != nil {
-			log.Fatalf("generating reports: %s", err.Error())
+		job := job.New(*conf, kubeClient, metricsClient, store)
+		switch {
+		case conf.RunAsDaemon && conf.RunAsWebServer:
+			// the daemon runs alongside the web server, sharing its
+			// connection to the database. Embedded backends lock their
+			// database file, so that it can't be opened by two processes.
+			daemon.Add(1)
+			go func() {
+				defer daemon.Done()
+				err := job.RunDaemon(context.Background())
+				if err != nil {
+					log.Fatalf("running scraper daemon: %s", err.Error())
+				}
+			}()
+		case conf.RunAsDaemon:
+			err := job.RunDaemon(context.Background())
+			if err != nil {
+				log.Fatalf("running scraper daemon: %s", err.Error())
+			}
+			return
+		default:
+			summary, err := job.GenerateAll(context.Background())
+			if err != nil {
+				log.Fatalf("generating reports: %d of %d reporters failed: %s",
+					len(summary.Failed()), len(summary.Ran()), err.Error())
+			}
+			return
 		}
-		return
 	}
 
-	srv, err := web.NewSrv(*conf, mongo)
+	srv, err := web.NewSrv(*conf, store)
 	if err != nil {
 		log.Fatalf("creating web server instance: %s", err.Error())
 	}
 	srv.Run(context.Background())
+	daemon.Wait()
 }
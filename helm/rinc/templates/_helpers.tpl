@@ -60,6 +60,30 @@ app.kubernetes.io/managed-by: {{ .Release.Service }}
 {{- end }}
 {{- end }}
 
+{{- define "daemon.name" -}}
+  {{- if .Values.reportingDaemon.fullnameOverride }}
+    {{- .Values.reportingDaemon.fullnameOverride | trunc 63 | trimSuffix "-" }}
+  {{- else if .Values.reportingDaemon.nameOverride }}
+    {{- printf "%s-%s" .Chart.Name .Values.reportingDaemon.nameOverride | trunc 63 | trimSuffix "-" }}
+  {{- else }}
+    {{- printf "%s-reporting-daemon" .Chart.Name | trunc 63 | trimSuffix "-" }}
+  {{- end }}
+{{- end }}
+
+{{- define "daemon.selectorLabels" -}}
+app.kubernetes.io/name: {{ include "daemon.name" . }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end }}
+
+{{- define "daemon.labels" -}}
+helm.sh/chart: {{ include "rinc.chart" . }}
+app.kubernetes.io/managed-by: {{ .Release.Service }}
+{{ if .Chart.AppVersion -}}
+  app.kubernetes.io/version: {{ .Chart.AppVersion | quote }}
+{{- end }}
+{{ include "daemon.selectorLabels" . }}
+{{- end }}
+
 {{- define "serviceAccount.name" -}}
   {{- if .Values.rbac.serviceAccount.fullnameOverride }}
     {{- .Values.rbac.serviceAccount.fullnameOverride | trunc 63 | trimSuffix "-" }}
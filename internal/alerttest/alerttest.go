@@ -0,0 +1,201 @@
+// Package alerttest implements the `rinc alerts test` command, which
+// evaluates the configured alerts offline, either against a fixture, a
+// stored snapshot, or the snapshots declared in golden test files.
+package alerttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/expr"
+	"github.com/accuknox/rinc/internal/report"
+	"github.com/accuknox/rinc/internal/schema"
+	"github.com/accuknox/rinc/internal/store"
+
+	flag "github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// ErrFailed is returned by Run when an alert fails to evaluate or a golden
+// test fails. The details are written to the output.
+var ErrFailed = errors.New("alert tests failed")
+
+// Run runs the `rinc alerts test` command with the provided arguments,
+// writing its report to w.
+//
+// With `--fixture` or `--snapshot`, the alerts configured for `--source` are
+// evaluated against the fixture file or the stored snapshot, and the alerts
+// that fire are printed along with any evaluation errors. Otherwise, the
+// arguments are golden test files, see RunTests.
+func Run(ctx context.Context, args []string, w io.Writer) error {
+	f := flag.NewFlagSet("alerts test", flag.ContinueOnError)
+	f.SetOutput(w)
+	confF := f.StringSlice("conf", nil, "comma-seperated list of config files (default /etc/rinc/config.yaml)")
+	source := f.String("source", "", "name of the collection the alerts are evaluated for, e.g. \"dass\"")
+	fixture := f.String("fixture", "", "json or yaml file holding the metrics to evaluate")
+	snapshot := f.String("snapshot", "", "id of the stored document to evaluate, or \"latest\"")
+	err := f.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var confArgs []string
+	for _, c := range *confF {
+		confArgs = append(confArgs, "--conf", c)
+	}
+	c, err := conf.New(confArgs...)
+	if err != nil {
+		return err
+	}
+	// the alerts are type-checked like at startup, while the rest of the
+	// configuration, such as the Kubernetes client, is not needed here.
+	if err := c.ValidateAlerts(); err != nil {
+		return fmt.Errorf("validating provided config: %w", err)
+	}
+
+	if *fixture == "" && *snapshot == "" {
+		if f.NArg() == 0 {
+			return errors.New("either `--fixture`, `--snapshot` or test files must be provided")
+		}
+		return RunTests(ctx, *c, f.Args(), w)
+	}
+	if *fixture != "" && *snapshot != "" {
+		return errors.New("`--fixture` and `--snapshot` are mutually exclusive")
+	}
+	alerts, err := Alerts(*c, *source)
+	if err != nil {
+		return err
+	}
+
+	var h expr.History
+	if *fixture != "" {
+		metrics, err := loadFixture(*source, *fixture)
+		if err != nil {
+			return err
+		}
+		h = expr.Snapshots{{Timestamp: time.Now(), Data: metrics}}
+	} else {
+		s, err := store.New(ctx, *c)
+		if err != nil {
+			return fmt.Errorf("connecting to the database: %w", err)
+		}
+		defer s.Close(ctx)
+		h, err = loadSnapshot(ctx, s, *source, *snapshot)
+		if err != nil {
+			return err
+		}
+	}
+
+	firing, errs := report.EvaluateAlerts(ctx, alerts, h)
+	Print(w, firing, errs)
+	if len(errs) != 0 {
+		return ErrFailed
+	}
+	return nil
+}
+
+// Print writes the alerts that fired, followed by the evaluation errors, to
+// w.
+func Print(w io.Writer, firing []db.Alert, errs []error) {
+	for _, a := range firing {
+		fmt.Fprintf(w, "FIRING [%s] %s\n", a.Severity, describe(a))
+	}
+	for _, err := range errs {
+		fmt.Fprintf(w, "ERROR  %s\n", err.Error())
+	}
+	fmt.Fprintf(w, "%d alert(s) firing, %d error(s)\n", len(firing), len(errs))
+}
+
+// describe returns the message of the alert, followed by its ID and
+// resource, if any.
+func describe(a db.Alert) string {
+	s := a.Message
+	if a.Resource != "" {
+		s += " (resource: " + a.Resource + ")"
+	}
+	if a.ID != "" {
+		s += " (id: " + a.ID + ")"
+	}
+	return s
+}
+
+// Alerts returns the alerts configured for the reporter writing to the
+// source collection.
+func Alerts(c conf.C, source string) ([]conf.Alert, error) {
+	switch source {
+	case db.CollectionRabbitmq:
+		return c.RabbitMQ.Alerts, nil
+	case db.CollectionCeph:
+		return c.Ceph.Alerts, nil
+	case db.CollectionImageTag:
+		return c.ImageTag.Alerts, nil
+	case db.CollectionDass:
+		return c.DaSS.Alerts, nil
+	case db.CollectionLongJobs:
+		return c.LongJobs.Alerts, nil
+	case db.CollectionPVUtilizaton:
+		return c.PVUtilization.Alerts, nil
+	case db.CollectionResourceUtilization:
+		return c.ResourceUtilization.Alerts, nil
+	case db.CollectionConnectivity:
+		return c.Connectivity.Alerts, nil
+	case db.CollectionPodStatus:
+		return c.PodStatus.Alerts, nil
+	default:
+		return nil, fmt.Errorf("invalid source: %q", source)
+	}
+}
+
+// loadFixture decodes the json or yaml file at path into the metrics of the
+// source collection.
+func loadFixture(source, path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading fixture: %w", err)
+	}
+	return decodeMetrics(source, data)
+}
+
+// decodeMetrics decodes json or yaml data into the metrics of the source
+// collection. Fields are named after their json tags, if any, and after the
+// Go struct fields otherwise.
+func decodeMetrics(source string, data []byte) (any, error) {
+	metrics, err := schema.New(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, metrics); err != nil {
+		return nil, fmt.Errorf("decoding %s metrics: %w", source, err)
+	}
+	return reflect.ValueOf(metrics).Elem().Interface(), nil
+}
+
+// loadSnapshot loads the document with the given ID, or the latest one, from
+// the source collection. Its earlier documents remain available to the
+// history functions.
+func loadSnapshot(ctx context.Context, s store.Store, source, id string) (expr.History, error) {
+	metrics, err := schema.New(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var at time.Time
+	if id == "latest" {
+		at, err = s.Latest(ctx, source, metrics)
+	} else {
+		at, err = s.FindByID(ctx, source, id, metrics)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading snapshot %q from %q: %w", id, source, err)
+	}
+
+	current := reflect.ValueOf(metrics).Elem().Interface()
+	return report.NewHistory(s, source, at, current), nil
+}
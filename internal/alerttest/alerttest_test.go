@@ -0,0 +1,66 @@
+package alerttest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunFixture(t *testing.T) {
+	a := assert.New(t)
+	var out strings.Builder
+	err := Run(context.TODO(), []string{
+		"--conf", "testdata/config.yaml",
+		"--source", "dass",
+		"--fixture", "testdata/fixtures/dass.yaml",
+	}, &out)
+	a.NoError(err)
+	a.Equal("FIRING [critical] deployment foo is unavailable (resource: default/foo)\n"+
+		"1 alert(s) firing, 0 error(s)\n", out.String())
+
+	out.Reset()
+	err = Run(context.TODO(), []string{
+		"--conf", "testdata/config.yaml",
+		"--source", "ceph",
+		"--fixture", "testdata/fixtures/dass.yaml",
+	}, &out)
+	a.Error(err, "fixture does not match the source")
+
+	out.Reset()
+	err = Run(context.TODO(), []string{
+		"--conf", "testdata/invalid.yaml",
+		"--source", "dass",
+		"--fixture", "testdata/fixtures/dass.yaml",
+	}, &out)
+	if a.Error(err, "the alerts are type-checked") {
+		a.Contains(err.Error(), "unknown parameter Available")
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	a := assert.New(t)
+	var out strings.Builder
+	err := Run(context.TODO(), []string{
+		"--conf", "testdata/config.yaml",
+		"testdata/tests.yaml",
+	}, &out)
+	a.NoError(err)
+	a.Equal("PASS testdata/tests.yaml: unavailable deployments\n"+
+		"PASS testdata/tests.yaml: added deployments\n"+
+		"2 of 2 test(s) passed\n", out.String())
+
+	out.Reset()
+	err = Run(context.TODO(), []string{
+		"--conf", "testdata/config.yaml",
+		"testdata/failing.yaml",
+	}, &out)
+	a.ErrorIs(err, ErrFailed)
+	a.Equal("FAIL testdata/failing.yaml: wrong expectations\n"+
+		"    missing: [critical] deployment foo is unavailable\n"+
+		"    unexpected: [warning] statefulset foo exists\n"+
+		"FAIL testdata/failing.yaml: evaluation errors\n"+
+		"    error: evaluating boolean expression \"Statefulsets[0].Name == \\\"foo\\\"\": unknown parameter Statefulsets.0\n"+
+		"0 of 2 test(s) passed\n", out.String())
+}
@@ -0,0 +1,191 @@
+package alerttest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/expr"
+	"github.com/accuknox/rinc/internal/report"
+
+	"sigs.k8s.io/yaml"
+)
+
+// File is a golden test file. It holds a list of test cases, each
+// evaluating the configured alerts of a reporter against a series of
+// snapshots and comparing the alerts that fire with the expected ones.
+//
+//	tests:
+//	  - name: unavailable deployments
+//	    source: dass
+//	    snapshots:
+//	      - timestamp: 2024-01-01T00:00:00Z
+//	        file: fixtures/dass.yaml
+//	      - timestamp: 2024-01-01T08:00:00Z
+//	        metrics:
+//	          Deployments:
+//	            - Name: foo
+//	              Namespace: default
+//	    expectedAlerts:
+//	      - severity: critical
+//	        message: deployment foo is unavailable
+//	        resource: default/foo
+type File struct {
+	Tests []Test `json:"tests"`
+}
+
+// Test is a single test case of a golden test file.
+type Test struct {
+	Name string `json:"name"`
+	// Source is the name of the collection the alerts are evaluated for,
+	// such as "dass".
+	Source string `json:"source"`
+	// Snapshots are the snapshots of the metrics, sorted by their
+	// timestamp. The alerts are evaluated against the last one; the earlier
+	// ones are used by the history functions, such as `delta`.
+	Snapshots []Snapshot `json:"snapshots"`
+	// ExpectedAlerts are the alerts expected to fire, in any order.
+	ExpectedAlerts []ExpectedAlert `json:"expectedAlerts"`
+}
+
+// Snapshot is a snapshot of the metrics of a test case, either read from a
+// fixture file, relative to the test file, or declared inline.
+type Snapshot struct {
+	Timestamp time.Time       `json:"timestamp"`
+	File      string          `json:"file"`
+	Metrics   json.RawMessage `json:"metrics"`
+}
+
+// ExpectedAlert is an alert expected to fire. ID and Resource are only
+// compared when the alert is expected to have them.
+type ExpectedAlert struct {
+	ID       string        `json:"id,omitempty"`
+	Severity conf.Severity `json:"severity"`
+	Message  string        `json:"message"`
+	Resource string        `json:"resource,omitempty"`
+}
+
+// RunTests runs the test cases of the provided golden test files, writing
+// the outcome of each to w. It returns ErrFailed if any of them fails.
+//
+// A test case fails if an alert fails to evaluate, if an expected alert does
+// not fire, or if an unexpected alert fires. `for` clauses are ignored: an
+// alert is expected to fire as soon as its condition holds.
+func RunTests(ctx context.Context, c conf.C, paths []string, w io.Writer) error {
+	var total, failed int
+	for _, path := range paths {
+		tests, err := loadTests(path)
+		if err != nil {
+			return err
+		}
+		for _, test := range tests {
+			total++
+			problems, err := runTest(ctx, c, filepath.Dir(path), test)
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+			if len(problems) == 0 {
+				fmt.Fprintf(w, "PASS %s: %s\n", path, test.Name)
+				continue
+			}
+			failed++
+			fmt.Fprintf(w, "FAIL %s: %s\n", path, test.Name)
+			for _, p := range problems {
+				fmt.Fprintf(w, "    %s\n", p)
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d of %d test(s) passed\n", total-failed, total)
+	if failed != 0 {
+		return ErrFailed
+	}
+	return nil
+}
+
+func loadTests(path string) ([]Test, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading test file: %w", err)
+	}
+	var f File
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("decoding test file %q: %w", path, err)
+	}
+	return f.Tests, nil
+}
+
+// runTest runs a single test case, returning the differences between the
+// expected and actual outcomes. dir is the directory fixture files are
+// relative to.
+func runTest(ctx context.Context, c conf.C, dir string, test Test) ([]string, error) {
+	alerts, err := Alerts(c, test.Source)
+	if err != nil {
+		return nil, err
+	}
+	if len(test.Snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshots")
+	}
+
+	h := make(expr.Snapshots, 0, len(test.Snapshots))
+	for idx, s := range test.Snapshots {
+		var metrics any
+		switch {
+		case s.File != "" && s.Metrics != nil:
+			return nil, fmt.Errorf("snapshot %d: `file` and `metrics` are mutually exclusive", idx)
+		case s.File != "":
+			path := s.File
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			metrics, err = loadFixture(test.Source, path)
+		default:
+			metrics, err = decodeMetrics(test.Source, s.Metrics)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %d: %w", idx, err)
+		}
+		h = append(h, expr.Snapshot{Timestamp: s.Timestamp, Data: metrics})
+	}
+
+	firing, errs := report.EvaluateAlerts(ctx, alerts, h)
+	var problems []string
+	for _, err := range errs {
+		problems = append(problems, "error: "+err.Error())
+	}
+	return append(problems, compare(test.ExpectedAlerts, firing)...), nil
+}
+
+// compare returns the expected alerts that did not fire, followed by the
+// alerts that fired unexpectedly.
+func compare(expected []ExpectedAlert, firing []db.Alert) []string {
+	var problems []string
+	unmatched := slices.Clone(firing)
+	for _, e := range expected {
+		idx := slices.IndexFunc(unmatched, func(a db.Alert) bool {
+			return a.Severity == e.Severity &&
+				a.Message == e.Message &&
+				(e.ID == "" || a.ID == e.ID) &&
+				(e.Resource == "" || a.Resource == e.Resource)
+		})
+		if idx < 0 {
+			problems = append(problems, fmt.Sprintf("missing: [%s] %s", e.Severity, describe(db.Alert{
+				ID:       e.ID,
+				Message:  e.Message,
+				Resource: e.Resource,
+			})))
+			continue
+		}
+		unmatched = slices.Delete(unmatched, idx, idx+1)
+	}
+	for _, a := range unmatched {
+		problems = append(problems, fmt.Sprintf("unexpected: [%s] %s", a.Severity, describe(a)))
+	}
+	return problems
+}
@@ -3,9 +3,12 @@ package conf
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
 
 	"github.com/accuknox/rinc/internal/expr"
 
@@ -15,6 +18,10 @@ import (
 // Alert includes a message template, a severity level, and a conditional
 // expression to trigger the alert.
 type Alert struct {
+	// ID is an optional identifier of the alert, unique among the alerts of
+	// a reporter. When set, the alert keeps its identity even if its `when`
+	// expression is edited.
+	ID string `koanf:"id"`
 	// Message can be a go template literal or a string literal.
 	Message StringExpr `koanf:"message"`
 	// Severity can be "info", "warning", "critical"
@@ -22,6 +29,87 @@ type Alert struct {
 	// When is a gval boolean expressions that when evaluated to true, fires
 	// the alert.
 	When Expr `koanf:"when"`
+	// ForEach is an optional gval expression selecting a list, such as
+	// `Deployments`. When set, `when` and `message` are evaluated against
+	// each element of the list instead of the whole metrics, firing one
+	// alert per matching element.
+	ForEach Expr `koanf:"forEach"`
+	// Resource is a message template identifying the element an alert fired
+	// for when using `forEach`. Defaults to "<Namespace>/<Name>", or
+	// "<Name>", when the elements have such fields, and to the element's
+	// index otherwise.
+	Resource StringExpr `koanf:"resource"`
+	// Labels are additional labels attached to the alert, such as the team
+	// owning it. They are used to filter notifications and are pushed to
+	// Alertmanager.
+	Labels map[string]string `koanf:"labels"`
+	// Annotations hold additional information about the alert, such as a
+	// description.
+	Annotations map[string]string `koanf:"annotations"`
+	// RunbookURL links to the runbook describing how to handle the alert.
+	RunbookURL string `koanf:"runbookUrl"`
+	// For is how long `when` must keep evaluating to true before the alert
+	// fires, either as a duration, such as "15m", or as a number of
+	// consecutive evaluations, such as 3. Until then, the alert is pending.
+	// Leave empty to fire right away.
+	For For `koanf:"for"`
+}
+
+// For specifies how long the condition of an alert must hold before the
+// alert fires. It implements the encoding.TextUnmarshaler interface.
+type For struct {
+	Text string
+	// Duration is the minimum time between the first evaluation that
+	// satisfied the condition and the current one.
+	Duration time.Duration
+	// Evaluations is the minimum number of consecutive evaluations that
+	// satisfied the condition, including the current one.
+	Evaluations int
+}
+
+// UnmarshalText parses either a duration or a number of evaluations.
+// Implements encoding.TextUnmarshaler.
+func (f *For) UnmarshalText(text []byte) error {
+	if text == nil {
+		return nil
+	}
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 {
+			return fmt.Errorf("invalid for %q: evaluations must be at least 1", s)
+		}
+		f.Text = s
+		f.Evaluations = n
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid for %q: must be a duration or a number of evaluations", s)
+	}
+	if d <= 0 {
+		return fmt.Errorf("invalid for %q: duration must be positive", s)
+	}
+	f.Text = s
+	f.Duration = d
+	return nil
+}
+
+// IsSet reports whether the alert has to be pending before firing.
+func (f For) IsSet() bool {
+	return f.Duration != 0 || f.Evaluations != 0
+}
+
+// Satisfied reports whether a condition that first held at `since` and has
+// held for `evaluations` consecutive evaluations, as of `now`, has held long
+// enough.
+func (f For) Satisfied(since, now time.Time, evaluations int) bool {
+	if f.Evaluations != 0 {
+		return evaluations >= f.Evaluations
+	}
+	return now.Sub(since) >= f.Duration
 }
 
 // Severity defines different levels of alert severity.
@@ -47,7 +135,7 @@ func (e *Expr) UnmarshalText(text []byte) error {
 		return nil
 	}
 	s := strings.TrimSpace(string(text))
-	ev, err := gval.Full(expr.Full()...).NewEvaluable(s)
+	ev, err := expr.Language().NewEvaluable(s)
 	if err != nil {
 		return fmt.Errorf("invalid expression %q: %w", s, err)
 	}
@@ -56,48 +144,177 @@ func (e *Expr) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// embeddedExpr matches the gval expressions embedded between backticks in a
+// StringExpr.
+var embeddedExpr = regexp.MustCompile("`.+?`")
+
+// StringExpr is a message template. It is rendered as a Go text/template
+// with the metrics as dot, having access to the functions of the expression
+// language, see expr.TemplateFuncs. The gval expressions embedded between
+// backticks outside of template actions are replaced with their results,
+// evaluated against the current dot. It implements the
+// encoding.TextUnmarshaler interface.
 type StringExpr struct {
 	Text string
+	// tmpl is the template compiled when unmarshalling.
+	tmpl *stringTemplate
 }
 
+// stringTemplate is a compiled StringExpr. The functions it calls are bound
+// to the context of each execution, see StringExpr.Evaluate.
+type stringTemplate struct {
+	// trees are the parse trees of the template and of the templates it
+	// defines, by name.
+	trees map[string]*parse.Tree
+	// funcs are the names of the functions the templates call.
+	funcs []string
+}
+
+// UnmarshalText parses a message template. Implements
+// encoding.TextUnmarshaler.
 func (e *StringExpr) UnmarshalText(text []byte) error {
 	if text == nil {
 		return nil
 	}
 	s := strings.TrimSpace(string(text))
+	tmpl, err := parseStringExpr(s)
+	if err != nil {
+		return err
+	}
 	e.Text = s
+	e.tmpl = tmpl
 	return nil
 }
 
+// parseStringExpr parses the text of a StringExpr into a template, turning
+// each expression embedded between backticks into a template action
+// evaluating it.
+func parseStringExpr(text string) (*stringTemplate, error) {
+	var b strings.Builder
+	last := 0
+	for _, loc := range embeddedExpr.FindAllStringIndex(text, -1) {
+		if inAction(text, loc[0]) {
+			continue
+		}
+		s := text[loc[0]+1 : loc[1]-1]
+		if _, err := expr.Compile(s); err != nil {
+			return nil, fmt.Errorf("invalid expression %q: %w", s, err)
+		}
+		b.WriteString(text[last:loc[0]])
+		fmt.Fprintf(&b, "{{ format (eval %s .) }}", strconv.Quote(s))
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+
+	tmpl, err := template.
+		New("message").
+		Funcs(expr.TemplateFuncs(context.Background())).
+		Parse(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", text, err)
+	}
+
+	t := &stringTemplate{trees: make(map[string]*parse.Tree)}
+	funcs := make(map[string]bool)
+	for _, tmpl := range tmpl.Templates() {
+		t.trees[tmpl.Name()] = tmpl.Tree
+		identifiers(tmpl.Tree.Root, funcs)
+	}
+	for name := range funcs {
+		t.funcs = append(t.funcs, name)
+	}
+	return t, nil
+}
+
+// identifiers adds the identifiers of the functions called within node to
+// names.
+func identifiers(node parse.Node, names map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, node := range n.Nodes {
+			identifiers(node, names)
+		}
+	case *parse.ActionNode:
+		identifiers(n.Pipe, names)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			identifiers(cmd, names)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			identifiers(arg, names)
+		}
+	case *parse.ChainNode:
+		identifiers(n.Node, names)
+	case *parse.IdentifierNode:
+		names[n.Ident] = true
+	case *parse.IfNode:
+		identifiers(&n.BranchNode, names)
+	case *parse.RangeNode:
+		identifiers(&n.BranchNode, names)
+	case *parse.WithNode:
+		identifiers(&n.BranchNode, names)
+	case *parse.BranchNode:
+		identifiers(n.Pipe, names)
+		identifiers(n.List, names)
+		identifiers(n.ElseList, names)
+	case *parse.TemplateNode:
+		identifiers(n.Pipe, names)
+	}
+}
+
+// inAction reports whether the offset of text lies within a template action.
+func inAction(text string, offset int) bool {
+	open := strings.LastIndex(text[:offset], "{{")
+	return open >= 0 && !strings.Contains(text[open:offset], "}}")
+}
+
+// Evaluate renders the template with data as dot, its functions receiving
+// ctx. Leading and trailing whitespace is trimmed from the output.
 func (e StringExpr) Evaluate(ctx context.Context, data any) (string, error) {
-	reg := regexp.MustCompile("`.+?`")
-	expressions := reg.FindAllString(e.Text, -1)
-	var results []any
-	for _, e := range expressions {
-		e = strings.TrimPrefix(e, "`")
-		e = strings.TrimSuffix(e, "`")
-		res, err := gval.Full(expr.Full()...).EvaluateWithContext(ctx, e, data)
+	t := e.tmpl
+	if t == nil {
+		// the template was not unmarshalled, e.g., it was declared as a
+		// literal.
+		var err error
+		t, err = parseStringExpr(e.Text)
 		if err != nil {
-			return "", fmt.Errorf("evaluating expr %q: %w", e, err)
+			return "", err
 		}
-		results = append(results, res)
 	}
-	idx := -1
-	output := reg.ReplaceAllStringFunc(e.Text, func(s string) string {
-		idx++
-		if idx >= len(results) {
-			return s
+
+	// the parse trees are shared by the executions, each of them binding
+	// the functions to its own context, so that the template can be
+	// rendered concurrently.
+	funcs := make(template.FuncMap, len(t.funcs))
+	for _, name := range t.funcs {
+		if fn, ok := expr.TemplateFunc(ctx, name); ok {
+			funcs[name] = fn
+		}
+	}
+	tmpl := template.New("message").Funcs(funcs)
+	if expr.Checking(ctx) {
+		// every branch is executed while type-checking.
+		tmpl.Funcs(checkFuncs)
+	}
+	for name, tree := range t.trees {
+		if expr.Checking(ctx) {
+			tree = checkTree(tree)
 		}
-		val := reflect.ValueOf(results[idx])
-		if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
-			var str string
-			for idx := 0; idx < val.Len(); idx++ {
-				item := val.Index(idx)
-				str += fmt.Sprintf("%v, ", item.Interface())
-			}
-			return strings.TrimSuffix(str, ", ")
+		if _, err := tmpl.AddParseTree(name, tree); err != nil {
+			return "", fmt.Errorf("rendering %q: %w", e.Text, err)
 		}
-		return fmt.Sprintf("%v", results[idx])
-	})
-	return output, nil
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering %q: %w", e.Text, err)
+	}
+	return strings.TrimSpace(b.String()), nil
 }
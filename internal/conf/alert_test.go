@@ -2,9 +2,15 @@ package conf_test
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/expr"
+	"github.com/accuknox/rinc/types/pod"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,3 +33,110 @@ func TestStringExprEvaluate(t *testing.T) {
 		}
 	}
 }
+
+func TestStringExprTemplate(t *testing.T) {
+	a := assert.New(t)
+	metrics := pod.Metrics{
+		Deployments: []pod.Resource{
+			{Name: "foo", Namespace: "default", Pods: make([]pod.Pod, 2)},
+			{Name: "bar", Namespace: "kube-system"},
+		},
+	}
+	inputs := map[string]string{
+		"{{ len .Deployments }} deployments":                                        "2 deployments",
+		"{{ range .Deployments }}\n- `Namespace`/`Name`: `len(Pods)` pods{{ end }}": "- default/foo: 2 pods\n- kube-system/bar: 0 pods",
+		"{{ with findOne .Deployments \"Name\" \"bar\" }}{{ .Namespace }}{{ end }}": "kube-system",
+		"{{ if gt (eval `len(Deployments)` .) 1 }}many{{ else }}few{{ end }}":       "many",
+		"{{ printf `%s!` \"foo\" }} `Deployments ~> \"Name\"`":                      "foo! foo, bar",
+		"{{ humanBytes 1536 }}": "1.5 KiB",
+		"{{ define \"size\" }}{{ humanBytes . }}{{ end }}{{ template \"size\" 1536 }}": "1.5 KiB",
+	}
+	for input, want := range inputs {
+		var e conf.StringExpr
+		if !a.NoErrorf(e.UnmarshalText([]byte(input)), "INPUT=%s", input) {
+			continue
+		}
+		got, err := e.Evaluate(context.TODO(), metrics)
+		if a.NoErrorf(err, "INPUT=%s", input) {
+			a.Equalf(want, got, "INPUT=%s", input)
+		}
+	}
+
+	var e conf.StringExpr
+	a.Error(e.UnmarshalText([]byte("{{ range .Deployments }}")), "unterminated action")
+	a.Error(e.UnmarshalText([]byte("`len(`")), "invalid expression")
+	a.NoError(e.UnmarshalText([]byte("{{ .Foo }}")))
+	_, err := e.Evaluate(context.TODO(), metrics)
+	a.Error(err, "unknown field")
+}
+
+func TestStringExprConcurrent(t *testing.T) {
+	a := assert.New(t)
+	var e conf.StringExpr
+	if !a.NoError(e.UnmarshalText([]byte("{{ (now).Hour }}"))) {
+		return
+	}
+	// each rendering sees the time of its own context.
+	got := make([]string, 24)
+	errs := make([]error, 24)
+	var wg sync.WaitGroup
+	for hour := range 24 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := expr.WithHistory(context.TODO(), expr.Snapshots{
+				{Timestamp: time.Date(2024, 7, 1, hour, 0, 0, 0, time.UTC)},
+			})
+			got[hour], errs[hour] = e.Evaluate(ctx, nil)
+		}()
+	}
+	wg.Wait()
+	for hour := range 24 {
+		if a.NoError(errs[hour], "INPUT=%d", hour) {
+			a.Equal(strconv.Itoa(hour), got[hour], "INPUT=%d", hour)
+		}
+	}
+}
+
+func TestForUnmarshalText(t *testing.T) {
+	a := assert.New(t)
+	inputs := map[string]conf.For{
+		"3":   {Text: "3", Evaluations: 3},
+		"15m": {Text: "15m", Duration: 15 * time.Minute},
+		"":    {},
+	}
+	for input, want := range inputs {
+		var got conf.For
+		if a.NoErrorf(got.UnmarshalText([]byte(input)), "INPUT=%s", input) {
+			a.Equalf(want, got, "INPUT=%s", input)
+		}
+	}
+	for _, input := range []string{"0", "-1", "-5m", "foo"} {
+		var got conf.For
+		a.Errorf(got.UnmarshalText([]byte(input)), "INPUT=%s", input)
+	}
+}
+
+func BenchmarkStringExprEvaluate(b *testing.B) {
+	m := pod.Metrics{Deployments: make([]pod.Resource, 2000)}
+	for idx := range m.Deployments {
+		m.Deployments[idx] = pod.Resource{
+			Name:      fmt.Sprintf("deploy-%d", idx),
+			Namespace: fmt.Sprintf("ns-%d", idx%10),
+			Pods:      make([]pod.Pod, 3),
+		}
+	}
+	var e conf.StringExpr
+	if err := e.UnmarshalText([]byte("Deployment `Namespace`/`Name` has `len(Pods)` pods")); err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.TODO()
+	b.ResetTimer()
+	for range b.N {
+		for _, d := range m.Deployments {
+			if _, err := e.Evaluate(ctx, d); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/accuknox/rinc/internal/expr"
+)
+
+// checkAlerts type-checks the expressions of the alerts against the metrics
+// of their reporter, rejecting references to fields that do not exist and
+// function arguments of the wrong type. The expressions are evaluated
+// against a sample of the metrics, see expr.Sample, so that every field is
+// reached regardless of the values the metrics will hold.
+func checkAlerts(alerts []Alert, metrics any) error {
+	sample := expr.Sample(metrics)
+	for idx, a := range alerts {
+		field, err := checkAlert(a, sample)
+		if err == nil {
+			continue
+		}
+		if a.ID != "" {
+			return fmt.Errorf("`[%d].%s` (id %q): %w", idx, field, a.ID, err)
+		}
+		return fmt.Errorf("`[%d].%s`: %w", idx, field, err)
+	}
+	return nil
+}
+
+// checkAlert evaluates the expressions of the alert against the sample,
+// returning the name of the first field whose expression fails to evaluate.
+// The sample is also used as the earlier snapshot of the history functions.
+func checkAlert(a Alert, sample any) (string, error) {
+	current := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := expr.Snapshots{
+		{Data: sample},
+		{Timestamp: current, Data: sample},
+	}
+	ctx := expr.WithHistory(expr.WithCheck(context.Background()), h)
+
+	data := sample
+	if a.ForEach.Evaluable != nil {
+		list, err := a.ForEach.Evaluable(ctx, sample)
+		if err := typeError(err); err != nil {
+			return "forEach", err
+		}
+		rlist := reflect.ValueOf(list)
+		if rlist.Kind() != reflect.Slice && rlist.Kind() != reflect.Array {
+			return "forEach", fmt.Errorf("want a list, got %s", rlist.Kind())
+		}
+		if rlist.Len() == 0 {
+			return "", nil
+		}
+		data = rlist.Index(0).Interface()
+	}
+	if a.When.Evaluable != nil {
+		_, err := a.When.Evaluable.EvalBool(ctx, data)
+		if err := typeError(err); err != nil {
+			return "when", err
+		}
+	}
+	_, err := a.Message.Evaluate(ctx, data)
+	if err := typeError(err); err != nil {
+		return "message", err
+	}
+	_, err = a.Resource.Evaluate(ctx, data)
+	if err := typeError(err); err != nil {
+		return "resource", err
+	}
+	return "", nil
+}
+
+// typeError returns err, unless it only stems from the values held by the
+// sample: indexing a list past its single element, or looking up a key
+// that the sample's maps do not hold.
+func typeError(err error) error {
+	var unknown expr.ErrUnknownParameter
+	if !errors.As(err, &unknown) {
+		return err
+	}
+	switch unknown.On {
+	case reflect.Map:
+		return nil
+	case reflect.Slice:
+		if _, err := strconv.Atoi(unknown.Path[len(unknown.Path)-1]); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// checkFuncs are the functions called by the templates returned by
+// checkTree, along with those of the expression language.
+var checkFuncs = template.FuncMap{
+	"checkOnce": func(v any) []any { return []any{v} },
+	"checkAnd": func(arg any, args ...any) any {
+		for _, a := range args {
+			if truth, _ := template.IsTrue(arg); !truth {
+				break
+			}
+			arg = a
+		}
+		return arg
+	},
+	"checkOr": func(arg any, args ...any) any {
+		for _, a := range args {
+			if truth, _ := template.IsTrue(arg); truth {
+				break
+			}
+			arg = a
+		}
+		return arg
+	},
+}
+
+// checkTree returns a copy of tree in which every branch is executed, for
+// type-checking a message template: the bodies and the else branches of
+// `if`, `with` and `range` are executed regardless of the pipeline, and
+// `and` and `or` evaluate every argument.
+func checkTree(tree *parse.Tree) *parse.Tree {
+	tree = tree.Copy()
+	checkList(tree.Root)
+	return tree
+}
+
+func checkList(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+	for idx, node := range list.Nodes {
+		list.Nodes[idx] = checkNode(node)
+	}
+}
+
+// checkNode returns node, or the nodes replacing it, with every branch
+// executed.
+func checkNode(node parse.Node) parse.Node {
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		checkPipe(n.Pipe)
+	case *parse.TemplateNode:
+		checkPipe(n.Pipe)
+	case *parse.IfNode:
+		checkPipe(n.Pipe)
+		checkList(n.List)
+		checkList(n.ElseList)
+		// the pipeline is printed, or assigned, followed by both branches.
+		return newList(n.Position(), &parse.ActionNode{
+			NodeType: parse.NodeAction,
+			Pos:      n.Pos,
+			Line:     n.Line,
+			Pipe:     n.Pipe,
+		}, n.List, n.ElseList)
+	case *parse.WithNode:
+		checkPipe(n.Pipe)
+		checkList(n.List)
+		checkList(n.ElseList)
+		// the body is ranged over the value of the pipeline, so that it
+		// runs with the value as dot even if it is empty.
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier("checkOnce").SetPos(n.Pos)},
+		})
+		return newList(n.Position(), &parse.RangeNode{BranchNode: parse.BranchNode{
+			NodeType: parse.NodeRange,
+			Pos:      n.Pos,
+			Line:     n.Line,
+			Pipe:     n.Pipe,
+			List:     n.List,
+		}}, n.ElseList)
+	case *parse.RangeNode:
+		checkPipe(n.Pipe)
+		checkList(n.List)
+		checkList(n.ElseList)
+		elseList := n.ElseList
+		n.ElseList = nil
+		return newList(n.Position(), n, elseList)
+	}
+	return node
+}
+
+// checkPipe replaces the calls to `and` and `or` within pipe with calls to
+// their counterparts of checkFuncs, which evaluate every argument.
+func checkPipe(pipe *parse.PipeNode) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch arg := arg.(type) {
+			case *parse.IdentifierNode:
+				switch arg.Ident {
+				case "and":
+					arg.Ident = "checkAnd"
+				case "or":
+					arg.Ident = "checkOr"
+				}
+			case *parse.PipeNode:
+				checkPipe(arg)
+			case *parse.ChainNode:
+				if pipe, ok := arg.Node.(*parse.PipeNode); ok {
+					checkPipe(pipe)
+				}
+			}
+		}
+	}
+}
+
+// newList returns a list of the provided nodes, skipping the nil lists.
+func newList(pos parse.Pos, nodes ...parse.Node) *parse.ListNode {
+	list := &parse.ListNode{NodeType: parse.NodeList, Pos: pos}
+	for _, node := range nodes {
+		if l, ok := node.(*parse.ListNode); ok && l == nil {
+			continue
+		}
+		list.Nodes = append(list.Nodes, node)
+	}
+	return list
+}
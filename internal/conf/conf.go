@@ -1,10 +1,13 @@
 package conf
 
 import (
+	"encoding"
 	"fmt"
 	"os"
+	"reflect"
 	"time"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/confmap"
 	"github.com/knadh/koanf/providers/file"
@@ -17,6 +20,7 @@ var defaultConfig = "/etc/rinc/config.yaml"
 // C contains all configuration data that can be passed to the reporter.
 type C struct {
 	RunAsScraper   bool
+	RunAsDaemon    bool
 	RunAsWebServer bool
 	GenerateSchema string
 	// Log contains configuration for logs.
@@ -28,7 +32,19 @@ type C struct {
 	// KubernetesClient contains the configuration needed to communicate with
 	// the Kubernetes API server.
 	KubernetesClient KubernetesClient `koanf:"kubernetesClient"`
-	Mongodb          Mongodb          `koanf:"mongodb"`
+	// Storage contains configuration related to the storage of the
+	// reports.
+	Storage Storage `koanf:"storage"`
+	// Mongodb contains the configuration of the MongoDB storage backend.
+	Mongodb Mongodb `koanf:"mongodb"`
+	// Postgres contains the configuration of the PostgreSQL storage
+	// backend.
+	Postgres Postgres `koanf:"postgres"`
+	// Bolt contains the configuration of the embedded bbolt storage
+	// backend.
+	Bolt Bolt `koanf:"bolt"`
+	// Scraper contains configuration related to the scraper job.
+	Scraper Scraper `koanf:"scraper"`
 	// RabbitMQ contains the rabbitmq configuration.
 	RabbitMQ RabbitMQ `koanf:"rabbitmq"`
 	// LongJobs contains configuration related to the long-running job
@@ -53,6 +69,14 @@ type C struct {
 	Connectivity Connectivity `koanf:"connectivity"`
 	// PodStatus contains configuration related to the pod status reporter.
 	PodStatus PodStatus `koanf:"podStatus"`
+	// Notifications contains configuration related to the delivery of
+	// firing alerts to external channels.
+	Notifications Notifications `koanf:"notifications"`
+	// Web contains configuration related to the web server.
+	Web Web `koanf:"web"`
+	// Retention contains configuration related to the pruning of old
+	// reports.
+	Retention Retention `koanf:"retention"`
 }
 
 // New creates a configuration using the provided arguments and config file.
@@ -63,8 +87,16 @@ func New(args ...string) (*C, error) {
 		"log.level":                  "info",
 		"log.format":                 "text",
 		"terminationGracePeriod":     time.Second * 10,
+		"storage.backend":            "mongodb",
+		"storage.autoMigrate":        true,
+		"scraper.concurrency":        4,
+		"scraper.timeout":            time.Minute * 5,
+		"scraper.schedule":           "0 */8 * * *",
 		"longRunningJobs.olderThan":  time.Hour * 12,
 		"connectivity.postgres.port": 5432,
+		"web.auth.basic.realm":       "RINC",
+		"web.auth.oidc.scopes":       []string{"email", "profile"},
+		"web.auth.oidc.sessionTTL":   time.Hour * 12,
 	}, "."), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load default configuration: %w", err)
@@ -81,6 +113,11 @@ func New(args ...string) (*C, error) {
 		return nil, fmt.Errorf("failed to parse flags: %w", err)
 	}
 
+	asDaemon, err := f.GetBool("daemon")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse flags: %w", err)
+	}
+
 	asWebServer, err := f.GetBool("serve")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse flags: %w", err)
@@ -99,12 +136,23 @@ func New(args ...string) (*C, error) {
 	}
 
 	conf := new(C)
-	err = k.Unmarshal("", conf)
+	err = k.UnmarshalWithConf("", conf, koanf.UnmarshalConf{
+		DecoderConfig: &mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				numberToTextHook,
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.TextUnmarshallerHookFunc(),
+			),
+			Result:           conf,
+			WeaklyTypedInput: true,
+		},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal configuration: %w", err)
 	}
 
 	conf.RunAsScraper = asScraper
+	conf.RunAsDaemon = asDaemon
 	conf.RunAsWebServer = asWebServer
 	conf.GenerateSchema = generateSchema
 
@@ -120,7 +168,25 @@ func parseFlags(args []string) *flag.FlagSet {
 	f.StringSlice("conf", []string{defaultConfig}, "comma-seperated list of config files")
 	f.String("generate-schema", "", "generate json schema")
 	f.Bool("scrape", false, "scrape & store metrics")
+	f.Bool("daemon", false, "keep running & scrape metrics on schedule")
 	f.Bool("serve", false, "serve static reports")
 	f.Parse(args)
 	return f
 }
+
+// numberToTextHook converts numbers into strings when decoding into types
+// implementing encoding.TextUnmarshaler, so that values like `for: 3` can be
+// written without quotes.
+func numberToTextHook(f reflect.Type, t reflect.Type, data any) (any, error) {
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	default:
+		return data, nil
+	}
+	if _, ok := reflect.New(t).Interface().(encoding.TextUnmarshaler); !ok {
+		return data, nil
+	}
+	return fmt.Sprint(data), nil
+}
@@ -17,6 +17,9 @@ type LongJobs struct {
 	// IncludeSuspended specifies whether long-running suspended jobs should be
 	// included in the report.
 	IncludeSuspended bool `koanf:"includeSuspended"`
+	// Schedule specifies when the reporter runs in daemon mode. It can be
+	// an interval or a cron expression. Defaults to `scraper.schedule`.
+	Schedule Schedule `koanf:"schedule"`
 	// Alerts contain a message template, a severity level, and a conditional
 	// expression to trigger the respective alert.
 	Alerts []Alert `koanf:"alerts"`
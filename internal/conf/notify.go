@@ -0,0 +1,119 @@
+package conf
+
+import "time"
+
+// Notifications contains configuration related to the delivery of firing
+// alerts to external channels.
+type Notifications struct {
+	// Webhooks is a list of generic webhook receivers. The alerts are
+	// POSTed as a JSON document.
+	Webhooks []WebhookNotifier `koanf:"webhooks"`
+	// Slack is a list of Slack-compatible incoming webhook receivers. This
+	// also works with Microsoft Teams and Mattermost incoming webhooks.
+	Slack []SlackNotifier `koanf:"slack"`
+	// SMTP is a list of email receivers.
+	SMTP []SMTPNotifier `koanf:"smtp"`
+	// Alertmanager is a list of Prometheus Alertmanager instances. Unlike
+	// the other notifiers, alerts are pushed on every scrape for as long as
+	// they keep firing, as expected by Alertmanager.
+	Alertmanager []AlertmanagerNotifier `koanf:"alertmanager"`
+}
+
+// NotifierFilter decides which alerts are delivered to a notifier.
+type NotifierFilter struct {
+	// Severities is the list of severities that are delivered. Leave empty
+	// to deliver alerts of all severities.
+	Severities []Severity `koanf:"severities"`
+	// Sources is the list of reporters whose alerts are delivered. A source
+	// is the name of the reporter's collection, such as "rabbitmq" or
+	// "ceph". Leave empty to deliver alerts from all reporters.
+	Sources []string `koanf:"sources"`
+	// Labels are the labels an alert must have, with the same values, to be
+	// delivered. Leave empty to deliver alerts regardless of their labels.
+	Labels map[string]string `koanf:"labels"`
+}
+
+// WebhookNotifier contains configuration for a generic JSON webhook
+// receiver.
+type WebhookNotifier struct {
+	NotifierFilter `koanf:",squash"`
+	// URL is the webhook URL.
+	//
+	// Required.
+	URL string `koanf:"url"`
+	// Headers are additional HTTP headers sent with each request, such as
+	// an authorization header.
+	Headers map[string]string `koanf:"headers"`
+	// Timeout is the HTTP request timeout.
+	//
+	// Default: 10s
+	Timeout time.Duration `koanf:"timeout"`
+}
+
+// SlackNotifier contains configuration for a Slack-compatible incoming
+// webhook receiver.
+type SlackNotifier struct {
+	NotifierFilter `koanf:",squash"`
+	// URL is the incoming webhook URL.
+	//
+	// Required.
+	URL string `koanf:"url"`
+	// Timeout is the HTTP request timeout.
+	//
+	// Default: 10s
+	Timeout time.Duration `koanf:"timeout"`
+}
+
+// SMTPNotifier contains configuration for an email receiver.
+type SMTPNotifier struct {
+	NotifierFilter `koanf:",squash"`
+	// Host is the SMTP server host (without the port).
+	//
+	// Required.
+	Host string `koanf:"host"`
+	// Port is the SMTP server port.
+	//
+	// Default: 587
+	Port uint16 `koanf:"port"`
+	// Username is the SMTP auth username. Leave blank to disable
+	// authentication.
+	Username string `koanf:"username"`
+	// Password is the SMTP auth password.
+	Password string `koanf:"password"`
+	// From is the sender address.
+	//
+	// Required.
+	From string `koanf:"from"`
+	// To is the list of recipient addresses.
+	//
+	// Required.
+	To []string `koanf:"to"`
+}
+
+// AlertmanagerNotifier contains configuration for a Prometheus Alertmanager
+// receiver.
+type AlertmanagerNotifier struct {
+	NotifierFilter `koanf:",squash"`
+	// URL is the Alertmanager base URL, such as
+	// "http://alertmanager.monitoring:9093". Alerts are POSTed to its
+	// `/api/v2/alerts` endpoint.
+	//
+	// Required.
+	URL string `koanf:"url"`
+	// Headers are additional HTTP headers sent with each request, such as
+	// an authorization header.
+	Headers map[string]string `koanf:"headers"`
+	// Labels are static labels attached to every alert pushed to this
+	// Alertmanager, such as the cluster name.
+	Labels map[string]string `koanf:"labels"`
+	// Timeout is the HTTP request timeout.
+	//
+	// Default: 10s
+	Timeout time.Duration `koanf:"timeout"`
+	// TTL is how long a firing alert keeps firing in Alertmanager after
+	// being pushed, i.e., its `endsAt`. Alertmanager resolves it unless it
+	// is pushed again by the next run of its reporter before then.
+	//
+	// Default: 3 times the interval between the runs of the reporter.
+	TTL time.Duration `koanf:"ttl"`
+}
@@ -7,6 +7,9 @@ type PodStatus struct {
 	// Namespace is the Kubernetes namespace that the pod status reporter will
 	// be limited to. Leave blank for all namespaces.
 	Namespace string `koanf:"namespace"`
+	// Schedule specifies when the reporter runs in daemon mode. It can be
+	// an interval or a cron expression. Defaults to `scraper.schedule`.
+	Schedule Schedule `koanf:"schedule"`
 	// Alerts contain a message template, a severity level, and a conditional
 	// expression to trigger the respective alert.
 	Alerts []Alert `koanf:"alerts"`
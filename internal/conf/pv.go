@@ -9,6 +9,9 @@ type PVUtilization struct {
 	//
 	// E.g., http://prometheus.monitoring.svc.cluster.local:9090
 	PrometheusURL string `koanf:"prometheusUrl"`
+	// Schedule specifies when the reporter runs in daemon mode. It can be
+	// an interval or a cron expression. Defaults to `scraper.schedule`.
+	Schedule Schedule `koanf:"schedule"`
 	// Alerts contain a message template, a severity level, and a conditional
 	// expression to trigger the respective alert.
 	Alerts []Alert `koanf:"alerts"`
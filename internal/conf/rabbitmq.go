@@ -15,6 +15,9 @@ type RabbitMQ struct {
 	//
 	// Required.
 	HeadlessSvcAddr string `koanf:"headlessSvcAddr"`
+	// Schedule specifies when the reporter runs in daemon mode. It can be
+	// an interval or a cron expression. Defaults to `scraper.schedule`.
+	Schedule Schedule `koanf:"schedule"`
 	// Alerts contain a message template, a severity level, and a conditional
 	// expression to trigger the respective alert.
 	Alerts []Alert `koanf:"alerts"`
@@ -8,6 +8,9 @@ type ResourceUtilization struct {
 	// Namespace is the Kubernetes namespace that the resource utilization
 	// reporter will be limited to.
 	Namespace string `koanf:"namespace"`
+	// Schedule specifies when the reporter runs in daemon mode. It can be
+	// an interval or a cron expression. Defaults to `scraper.schedule`.
+	Schedule Schedule `koanf:"schedule"`
 	// Alerts contain a message template, a severity level, and a conditional
 	// expression to trigger the respective alert.
 	Alerts []Alert `koanf:"alerts"`
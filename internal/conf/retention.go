@@ -0,0 +1,55 @@
+package conf
+
+import "time"
+
+// Retention contains configuration related to the pruning of old reports.
+// The scraper prunes the reports after every run.
+type Retention struct {
+	// Default is how long the documents of every collection are kept. A
+	// value of 0 keeps them forever.
+	//
+	// Default: 0
+	Default time.Duration `koanf:"default"`
+	// Collections overrides the default retention of individual
+	// collections, keyed by collection name, e.g., `podstatus`.
+	Collections map[string]time.Duration `koanf:"collections"`
+	// Downsample lists the rules thinning out the reports as they age, in
+	// increasing order of `after`. Each rule applies until the `after` of
+	// the next one.
+	Downsample []Downsample `koanf:"downsample"`
+}
+
+// Downsample keeps a single report per interval of `every` among the
+// reports older than `after`, e.g., one report per day after 7 days. The
+// earliest report of each interval is kept for every reporter, along with
+// the alerts and the run manifest written with it.
+type Downsample struct {
+	After time.Duration `koanf:"after"`
+	Every time.Duration `koanf:"every"`
+}
+
+// RetentionFor returns how long the documents of the collection are kept. A
+// value of 0 means forever.
+func (r Retention) RetentionFor(coll string) time.Duration {
+	if d, ok := r.Collections[coll]; ok {
+		return d
+	}
+	return r.Default
+}
+
+// RetentionCollections are the names of the collections the retention can
+// be configured for. They mirror the names declared by package db, which
+// imports this package.
+var RetentionCollections = []string{
+	"rabbitmq",
+	"ceph",
+	"imagetag",
+	"dass",
+	"longjobs",
+	"pv_utilization",
+	"resource_utilization",
+	"connectivity",
+	"podstatus",
+	"alerts",
+	"runs",
+}
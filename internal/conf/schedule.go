@@ -0,0 +1,52 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule specifies when a reporter runs in daemon mode. It implements the
+// encoding.TextUnmarshaler interface.
+//
+// A schedule can either be an interval, such as "5m" or "@every 5m", a
+// standard five-field cron expression, such as "*/5 * * * *", or a
+// predefined descriptor, such as "@daily".
+type Schedule struct {
+	Text     string
+	Schedule cron.Schedule
+}
+
+// UnmarshalText parses a string into a schedule. Implements
+// encoding.TextUnmarshaler.
+func (s *Schedule) UnmarshalText(text []byte) error {
+	if text == nil {
+		return nil
+	}
+	str := strings.TrimSpace(string(text))
+	if str == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(str); err == nil {
+		if d < time.Second {
+			return fmt.Errorf("invalid schedule %q: interval must be at least 1s", str)
+		}
+		s.Text = str
+		s.Schedule = cron.Every(d)
+		return nil
+	}
+	sched, err := cron.ParseStandard(str)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", str, err)
+	}
+	s.Text = str
+	s.Schedule = sched
+	return nil
+}
+
+// IsSet reports whether a schedule was provided.
+func (s Schedule) IsSet() bool {
+	return s.Schedule != nil
+}
@@ -0,0 +1,24 @@
+package conf
+
+import "time"
+
+// Scraper contains configuration related to the scraper job that runs the
+// enabled reporters.
+type Scraper struct {
+	// Concurrency is the maximum number of reporters that are allowed to
+	// run at the same time.
+	//
+	// Default: 4
+	Concurrency int `koanf:"concurrency"`
+	// Timeout is the maximum duration a single reporter is allowed to run
+	// for. A value of 0 implies no timeout.
+	//
+	// Default: 5m
+	Timeout time.Duration `koanf:"timeout"`
+	// Schedule is the default schedule of the reporters in daemon mode.
+	// Reporters may override it using their own `schedule` field. It can be
+	// an interval, such as "5m", or a cron expression.
+	//
+	// Default: "0 */8 * * *"
+	Schedule Schedule `koanf:"schedule"`
+}
@@ -3,6 +3,20 @@ package conf
 import (
 	"fmt"
 	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/accuknox/rinc/types/ceph"
+	"github.com/accuknox/rinc/types/connectivity"
+	"github.com/accuknox/rinc/types/dass"
+	"github.com/accuknox/rinc/types/imagetag"
+	"github.com/accuknox/rinc/types/longjobs"
+	"github.com/accuknox/rinc/types/pod"
+	"github.com/accuknox/rinc/types/pv"
+	"github.com/accuknox/rinc/types/rabbitmq"
+	"github.com/accuknox/rinc/types/resource"
 )
 
 // Validate validates the provided configuration.
@@ -16,8 +30,14 @@ func (c C) Validate() error {
 	if err := validateKubernetesClient(c.KubernetesClient); err != nil {
 		return fmt.Errorf("`kubernetesClient`: %w", err)
 	}
-	if err := validateMongodb(c.Mongodb); err != nil {
-		return fmt.Errorf("`mongodb`: %w", err)
+	if err := validateStorage(c); err != nil {
+		return err
+	}
+	if err := validateScraper(c.Scraper); err != nil {
+		return fmt.Errorf("`scraper`: %w", err)
+	}
+	if c.RunAsDaemon && !c.Scraper.Schedule.IsSet() {
+		return fmt.Errorf("`scraper.schedule`: required in daemon mode")
 	}
 	if err := validateRabbitMQ(c.RabbitMQ); err != nil {
 		return fmt.Errorf("rabbitmq: %w", err)
@@ -25,6 +45,43 @@ func (c C) Validate() error {
 	if err := validateCeph(c.Ceph); err != nil {
 		return fmt.Errorf("ceph: %w", err)
 	}
+	if err := validateNotifications(c.Notifications); err != nil {
+		return fmt.Errorf("`notifications`: %w", err)
+	}
+	if err := validateAuth(c.Web.Auth); err != nil {
+		return fmt.Errorf("`web.auth`: %w", err)
+	}
+	if err := validateRetention(c.Retention); err != nil {
+		return fmt.Errorf("`retention`: %w", err)
+	}
+	return c.ValidateAlerts()
+}
+
+// ValidateAlerts validates the alerts of every reporter, type-checking
+// their expressions against the metrics of the reporter.
+func (c C) ValidateAlerts() error {
+	for _, r := range []struct {
+		key     string
+		alerts  []Alert
+		metrics any
+	}{
+		{"rabbitmq", c.RabbitMQ.Alerts, rabbitmq.Metrics{}},
+		{"longRunningJobs", c.LongJobs.Alerts, longjobs.Metrics{}},
+		{"imageTag", c.ImageTag.Alerts, imagetag.Metrics{}},
+		{"deploymentAndStatefulsetStatus", c.DaSS.Alerts, dass.Metrics{}},
+		{"ceph", c.Ceph.Alerts, ceph.Metrics{}},
+		{"pvUtilization", c.PVUtilization.Alerts, pv.Metrics{}},
+		{"resourceUtilization", c.ResourceUtilization.Alerts, resource.Metrics{}},
+		{"connectivity", c.Connectivity.Alerts, connectivity.Metrics{}},
+		{"podStatus", c.PodStatus.Alerts, pod.Metrics{}},
+	} {
+		if err := validateAlerts(r.alerts); err != nil {
+			return fmt.Errorf("`%s.alerts`: %w", r.key, err)
+		}
+		if err := checkAlerts(r.alerts, r.metrics); err != nil {
+			return fmt.Errorf("`%s.alerts`: %w", r.key, err)
+		}
+	}
 	return nil
 }
 
@@ -57,6 +114,32 @@ func validateKubernetesClient(c KubernetesClient) error {
 	return fmt.Errorf("either `inCluster` or `kubeconfig` must be set")
 }
 
+func validateStorage(c C) error {
+	switch c.Storage.Backend {
+	case StorageMongodb:
+		if err := validateMongodb(c.Mongodb); err != nil {
+			return fmt.Errorf("`mongodb`: %w", err)
+		}
+	case StoragePostgres:
+		if c.Postgres.URI == "" {
+			return fmt.Errorf("`postgres`: missing `postgres.uri`")
+		}
+	case StorageBolt:
+		if c.Bolt.Path == "" {
+			return fmt.Errorf("`bolt`: missing `bolt.path`")
+		}
+	default:
+		return fmt.Errorf(
+			"`storage.backend`: must be one of %q, %q or %q, got %q",
+			StorageMongodb,
+			StoragePostgres,
+			StorageBolt,
+			c.Storage.Backend,
+		)
+	}
+	return nil
+}
+
 func validateMongodb(c Mongodb) error {
 	if c.URI == "" {
 		return fmt.Errorf("missing `mongodb.uri`")
@@ -70,6 +153,18 @@ func validateMongodb(c Mongodb) error {
 	return nil
 }
 
+func validateScraper(c Scraper) error {
+	if c.Concurrency < 1 {
+		return fmt.Errorf("`scraper.concurrency` must be at least 1, got %d",
+			c.Concurrency)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("`scraper.timeout` must not be negative, got %s",
+			c.Timeout)
+	}
+	return nil
+}
+
 func validateRabbitMQ(rmq RabbitMQ) error {
 	if !rmq.Enable {
 		return nil
@@ -108,3 +203,179 @@ func validateCeph(c Ceph) error {
 	}
 	return nil
 }
+
+func validateAuth(c Auth) error {
+	if c.Basic.Enable && c.Basic.HtpasswdFile == "" && len(c.Basic.Users) == 0 {
+		return fmt.Errorf("missing `web.auth.basic.htpasswdFile` or `web.auth.basic.users`")
+	}
+	for user, hash := range c.Basic.Users {
+		if !strings.HasPrefix(hash, "$2") {
+			return fmt.Errorf("`web.auth.basic.users.%s`: want a bcrypt hash", user)
+		}
+	}
+	names := make(map[string]bool, len(c.Tokens))
+	for idx, t := range c.Tokens {
+		if t.Name == "" {
+			return fmt.Errorf("missing `web.auth.tokens[%d].name`", idx)
+		}
+		if t.Token == "" {
+			return fmt.Errorf("missing `web.auth.tokens[%d].token`", idx)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("`web.auth.tokens[%d].name`: duplicate name %q", idx, t.Name)
+		}
+		names[t.Name] = true
+	}
+	if !c.OIDC.Enable {
+		return nil
+	}
+	if c.OIDC.IssuerURL == "" {
+		return fmt.Errorf("missing `web.auth.oidc.issuerURL`")
+	}
+	if c.OIDC.ClientID == "" {
+		return fmt.Errorf("missing `web.auth.oidc.clientID`")
+	}
+	if c.OIDC.RedirectURL == "" {
+		return fmt.Errorf("missing `web.auth.oidc.redirectURL`")
+	}
+	if u, err := url.Parse(c.OIDC.RedirectURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("`web.auth.oidc.redirectURL`: want an absolute URL, got %q", c.OIDC.RedirectURL)
+	}
+	if len(c.OIDC.SessionKey) < 32 {
+		return fmt.Errorf("`web.auth.oidc.sessionKey`: must be at least 32 characters long")
+	}
+	return nil
+}
+
+func validateRetention(c Retention) error {
+	if c.Default < 0 {
+		return fmt.Errorf("`default`: must not be negative")
+	}
+	for coll, d := range c.Collections {
+		if !slices.Contains(RetentionCollections, coll) {
+			return fmt.Errorf("`collections`: unknown collection %q, want one of %s", coll, strings.Join(RetentionCollections, ", "))
+		}
+		if d < 0 {
+			return fmt.Errorf("`collections.%s`: must not be negative", coll)
+		}
+	}
+	for idx, rule := range c.Downsample {
+		if rule.After <= 0 {
+			return fmt.Errorf("`downsample[%d].after`: must be positive", idx)
+		}
+		if rule.Every <= 0 {
+			return fmt.Errorf("`downsample[%d].every`: must be positive", idx)
+		}
+		if idx != 0 && rule.After <= c.Downsample[idx-1].After {
+			return fmt.Errorf("`downsample[%d].after`: must be greater than the `after` of the previous rule", idx)
+		}
+	}
+	return nil
+}
+
+func validateNotifications(c Notifications) error {
+	for idx, n := range c.Webhooks {
+		if n.URL == "" {
+			return fmt.Errorf("missing `notifications.webhooks[%d].url`", idx)
+		}
+		if err := validateNotifierFilter(n.NotifierFilter); err != nil {
+			return fmt.Errorf("`notifications.webhooks[%d]`: %w", idx, err)
+		}
+	}
+	for idx, n := range c.Slack {
+		if n.URL == "" {
+			return fmt.Errorf("missing `notifications.slack[%d].url`", idx)
+		}
+		if err := validateNotifierFilter(n.NotifierFilter); err != nil {
+			return fmt.Errorf("`notifications.slack[%d]`: %w", idx, err)
+		}
+	}
+	for idx, n := range c.SMTP {
+		if n.Host == "" {
+			return fmt.Errorf("missing `notifications.smtp[%d].host`", idx)
+		}
+		if n.From == "" {
+			return fmt.Errorf("missing `notifications.smtp[%d].from`", idx)
+		}
+		if len(n.To) == 0 {
+			return fmt.Errorf("missing `notifications.smtp[%d].to`", idx)
+		}
+		if err := validateNotifierFilter(n.NotifierFilter); err != nil {
+			return fmt.Errorf("`notifications.smtp[%d]`: %w", idx, err)
+		}
+	}
+	for idx, n := range c.Alertmanager {
+		if n.URL == "" {
+			return fmt.Errorf("missing `notifications.alertmanager[%d].url`", idx)
+		}
+		if err := validateLabels(n.Labels); err != nil {
+			return fmt.Errorf("`notifications.alertmanager[%d].labels`: %w", idx, err)
+		}
+		if n.TTL < 0 {
+			return fmt.Errorf("`notifications.alertmanager[%d].ttl`: must not be negative", idx)
+		}
+		if err := validateNotifierFilter(n.NotifierFilter); err != nil {
+			return fmt.Errorf("`notifications.alertmanager[%d]`: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+// alertID matches valid alert identifiers.
+var alertID = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+func validateAlerts(alerts []Alert) error {
+	ids := make(map[string]bool, len(alerts))
+	for idx, a := range alerts {
+		if a.ID != "" {
+			if !alertID.MatchString(a.ID) {
+				return fmt.Errorf("`[%d].id`: invalid id %q", idx, a.ID)
+			}
+			if ids[a.ID] {
+				return fmt.Errorf("`[%d].id`: duplicate id %q", idx, a.ID)
+			}
+			ids[a.ID] = true
+		}
+		if err := validateLabels(a.Labels); err != nil {
+			return fmt.Errorf("`[%d].labels`: %w", idx, err)
+		}
+		if a.Resource.Text != "" && a.ForEach.Evaluable == nil {
+			return fmt.Errorf("`[%d].resource`: requires `forEach`", idx)
+		}
+		if a.RunbookURL != "" {
+			u, err := url.Parse(a.RunbookURL)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("`[%d].runbookUrl`: invalid url %q", idx, a.RunbookURL)
+			}
+		}
+	}
+	return nil
+}
+
+// labelName matches valid Prometheus label names.
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func validateLabels(labels map[string]string) error {
+	for name := range labels {
+		if !labelName.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	return nil
+}
+
+func validateNotifierFilter(f NotifierFilter) error {
+	for _, s := range f.Severities {
+		switch s {
+		case SeverityInfo:
+		case SeverityWarning:
+		case SeverityCritical:
+		default:
+			return fmt.Errorf("invalid severity %q", s)
+		}
+	}
+	if err := validateLabels(f.Labels); err != nil {
+		return fmt.Errorf("`labels`: %w", err)
+	}
+	return nil
+}
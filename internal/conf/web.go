@@ -0,0 +1,97 @@
+package conf
+
+import "time"
+
+// Web contains configuration related to the web server.
+type Web struct {
+	// Auth contains the authentication configuration of the web server.
+	// Every method is disabled by default, leaving the reports accessible
+	// to anyone who can reach the web server. When several methods are
+	// enabled, a request authenticated by any of them is accepted.
+	Auth Auth `koanf:"auth"`
+}
+
+// Auth contains the authentication configuration of the web server.
+type Auth struct {
+	// Basic contains configuration related to HTTP basic authentication.
+	Basic BasicAuth `koanf:"basic"`
+	// Tokens is a list of static bearer tokens accepted by the JSON API and
+	// the `/metrics` endpoint, e.g., for scripts and Prometheus.
+	Tokens []BearerToken `koanf:"tokens"`
+	// OIDC contains configuration related to logging in with an OpenID
+	// Connect provider.
+	OIDC OIDCAuth `koanf:"oidc"`
+}
+
+// Enabled reports whether any authentication method is enabled.
+func (a Auth) Enabled() bool {
+	return a.Basic.Enable || len(a.Tokens) != 0 || a.OIDC.Enable
+}
+
+// BasicAuth contains configuration related to HTTP basic authentication.
+type BasicAuth struct {
+	Enable bool `koanf:"enable"`
+	// HtpasswdFile is the path to an htpasswd file, as generated by
+	// `htpasswd -B`. Only bcrypt hashes are supported.
+	HtpasswdFile string `koanf:"htpasswdFile"`
+	// Users maps usernames to bcrypt password hashes, in addition to the
+	// users of HtpasswdFile.
+	Users map[string]string `koanf:"users"`
+	// Realm is the realm sent in the `WWW-Authenticate` header.
+	//
+	// Default: RINC
+	Realm string `koanf:"realm"`
+}
+
+// BearerToken is a static token accepted in the `Authorization: Bearer`
+// header.
+type BearerToken struct {
+	// Name identifies the token in the logs.
+	//
+	// Required.
+	Name string `koanf:"name"`
+	// Token is the secret value of the token.
+	//
+	// Required.
+	Token string `koanf:"token"`
+}
+
+// OIDCAuth contains configuration related to logging in with an OpenID
+// Connect provider, using the authorization code flow. Once logged in, users
+// are identified by a signed session cookie.
+type OIDCAuth struct {
+	Enable bool `koanf:"enable"`
+	// IssuerURL is the URL of the provider, whose discovery document is
+	// served at `<issuerURL>/.well-known/openid-configuration`.
+	//
+	// Required.
+	IssuerURL string `koanf:"issuerURL"`
+	// ClientID is the ID of the client registered with the provider.
+	//
+	// Required.
+	ClientID string `koanf:"clientID"`
+	// ClientSecret is the secret of the client registered with the
+	// provider.
+	ClientSecret string `koanf:"clientSecret"`
+	// RedirectURL is the URL of the `/auth/callback` endpoint of the web
+	// server, as registered with the provider, e.g.,
+	// https://rinc.example.com/auth/callback
+	//
+	// Required.
+	RedirectURL string `koanf:"redirectURL"`
+	// Scopes are the scopes requested from the provider, in addition to
+	// `openid`.
+	//
+	// Default: [email, profile]
+	Scopes []string `koanf:"scopes"`
+	// SessionKey is the secret used to sign session cookies. It must be at
+	// least 32 characters long.
+	//
+	// Required.
+	SessionKey string `koanf:"sessionKey"`
+	// SessionTTL is how long a session lasts before logging in again is
+	// required.
+	//
+	// Default: 12h
+	SessionTTL time.Duration `koanf:"sessionTTL"`
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// migration upgrades the database to the next schema version. Migrations
+// must be idempotent, as several instances may apply the same migration
+// concurrently when they start.
+type migration struct {
+	description string
+	up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// migrations upgrade the database, migrations[i] upgrading it to version
+// i+1. Migrations must never be removed or reordered; a change to the types
+// of the stored documents is handled by appending a migration.
+var migrations = []migration{
+	{
+		description: "create indexes",
+		up:          createIndexes,
+	},
+	{
+		description: "store empty alert lists as arrays",
+		up:          emptyAlertArrays,
+	},
+}
+
+// SchemaVersion is the schema version of the database expected by this
+// version of RINC.
+var SchemaVersion = len(migrations)
+
+// Migrate applies the migrations newer than the schema version recorded in
+// the database, and returns the schema version before and after migrating.
+func Migrate(ctx context.Context, client *mongo.Client) (int, int, error) {
+	db := Database(client)
+	coll := db.Collection(CollectionMigrations)
+	_, err := coll.Indexes().CreateOne(ctx, index(true, "version"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("creating %q index: %w", CollectionMigrations, err)
+	}
+
+	from, err := Version(ctx, client)
+	if err != nil {
+		return 0, 0, err
+	}
+	if from > SchemaVersion {
+		slog.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"database schema is newer than this version of rinc",
+			slog.Int("version", from),
+			slog.Int("expected", SchemaVersion),
+		)
+		return from, from, nil
+	}
+
+	for idx, m := range pending(from) {
+		version := from + idx + 1
+		if err := m.up(ctx, db); err != nil {
+			return from, version - 1, fmt.Errorf("migrating to version %d (%s): %w", version, m.description, err)
+		}
+		_, err := coll.InsertOne(ctx, MigrationDocument{
+			Version:     version,
+			Description: m.description,
+			AppliedAt:   time.Now().UTC(),
+		})
+		// another instance applied the same migration concurrently
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return from, version - 1, fmt.Errorf("recording schema version %d: %w", version, err)
+		}
+		slog.LogAttrs(
+			ctx,
+			slog.LevelInfo,
+			"migrated database",
+			slog.Int("version", version),
+			slog.String("description", m.description),
+		)
+	}
+	return from, max(from, SchemaVersion), nil
+}
+
+// Version returns the schema version recorded in the database, which is 0
+// if no migration was ever applied.
+func Version(ctx context.Context, client *mongo.Client) (int, error) {
+	doc := new(MigrationDocument)
+	err := Database(client).
+		Collection(CollectionMigrations).
+		FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"version": -1})).
+		Decode(doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("finding schema version: %w", err)
+	}
+	return doc.Version, nil
+}
+
+// pending returns the migrations newer than the provided version.
+func pending(version int) []migration {
+	if version >= len(migrations) {
+		return nil
+	}
+	return migrations[max(version, 0):]
+}
+
+// createIndexes creates the indexes used by the lookups of the web server
+// and the scraper.
+func createIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		CollectionRuns:   {index(false, "timestamp")},
+		CollectionAlerts: {index(false, "timestamp", "from")},
+		CollectionAlertStates: {
+			index(false, "from", "state"),
+			index(false, "fingerprint", "state"),
+		},
+	}
+	for _, coll := range Collections {
+		indexes[coll] = []mongo.IndexModel{index(false, "timestamp")}
+	}
+	for coll, models := range indexes {
+		_, err := db.Collection(coll).Indexes().CreateMany(ctx, models)
+		if err != nil {
+			return fmt.Errorf("creating %q indexes: %w", coll, err)
+		}
+	}
+	return nil
+}
+
+// emptyAlertArrays replaces the null alert lists, written when no alert
+// fired, with empty arrays.
+func emptyAlertArrays(ctx context.Context, db *mongo.Database) error {
+	_, err := db.
+		Collection(CollectionAlerts).
+		UpdateMany(
+			ctx,
+			bson.M{"alerts": nil},
+			bson.M{"$set": bson.M{"alerts": bson.A{}}},
+		)
+	if err != nil {
+		return fmt.Errorf("updating %q documents: %w", CollectionAlerts, err)
+	}
+	return nil
+}
+
+// index returns the model of an ascending index on the provided fields.
+func index(unique bool, fields ...string) mongo.IndexModel {
+	keys := make(bson.D, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, bson.E{Key: f, Value: 1})
+	}
+	model := mongo.IndexModel{Keys: keys}
+	if unique {
+		model.Options = options.Index().SetUnique(true)
+	}
+	return model
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPending(t *testing.T) {
+	a := assert.New(t)
+
+	tests := map[int]int{
+		-1:                0,
+		0:                 0,
+		1:                 1,
+		SchemaVersion:     SchemaVersion,
+		SchemaVersion + 1: SchemaVersion,
+	}
+	for version, first := range tests {
+		got := pending(version)
+		a.Len(got, SchemaVersion-first, "INPUT=%d", version)
+		if len(got) != 0 {
+			a.Equal(migrations[first].description, got[0].description, "INPUT=%d", version)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/accuknox/rinc/internal/conf"
@@ -9,20 +11,143 @@ import (
 // AlertDocument defines the schema that should be stored in the
 // `alerts` collection.
 type AlertDocument struct {
-	Timestamp time.Time `bson:"timestamp"`
-	From      string    `bson:"from"`
-	Alerts    []Alert   `bson:"alerts"`
+	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+	From      string    `bson:"from" json:"from"`
+	Alerts    []Alert   `bson:"alerts" json:"alerts"`
 }
 
 // Alert defines the schema that should be stored within the
 // AlertDocument in the `alerts` collection.
 type Alert struct {
-	Message  string        `bson:"message"`
-	Severity conf.Severity `bson:"severity"`
+	// ID is the optional identifier declared on the alert.
+	ID       string        `bson:"id,omitempty" json:"id,omitempty"`
+	Message  string        `bson:"message" json:"message"`
+	Severity conf.Severity `bson:"severity" json:"severity"`
+	// Expr is the text of the `when` expression that fired the alert.
+	Expr string `bson:"expr,omitempty" json:"expr,omitempty"`
+	// Resource identifies the list element the alert fired for, for alerts
+	// evaluated with `forEach`.
+	Resource string `bson:"resource,omitempty" json:"resource,omitempty"`
+	// FirstSeen is the timestamp of the first scrape in which the condition
+	// of the alert held, without resolving in between.
+	FirstSeen time.Time `bson:"firstSeen,omitempty" json:"firstSeen"`
+	// Labels are the custom labels declared on the alert.
+	Labels map[string]string `bson:"labels,omitempty" json:"labels,omitempty"`
+	// Annotations are the custom annotations declared on the alert.
+	Annotations map[string]string `bson:"annotations,omitempty" json:"annotations,omitempty"`
+	// RunbookURL links to the runbook of the alert.
+	RunbookURL string `bson:"runbookUrl,omitempty" json:"runbookUrl,omitempty"`
+	// For is how long the condition of the alert must hold before it fires.
+	// It is only used while tracking the state of the alert and is not
+	// stored.
+	For conf.For `bson:"-" json:"-"`
+}
+
+// Fingerprint returns the identity of an alert fired by the provided
+// source. Two alerts with the same source, expression, resource and rendered
+// message have the same fingerprint. If the alert has an ID, it is used in
+// place of the expression.
+func (a Alert) Fingerprint(from string) string {
+	h := sha256.New()
+	h.Write([]byte(from))
+	h.Write([]byte{0})
+	if a.ID != "" {
+		h.Write([]byte("id:" + a.ID))
+	} else {
+		h.Write([]byte(a.Expr))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(a.Resource))
+	h.Write([]byte{0})
+	h.Write([]byte(a.Message))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// AlertState is the state of an alert.
+type AlertState string
+
+const (
+	AlertStatePending  AlertState = "pending"  // the alert waits for its `for` duration
+	AlertStateFiring   AlertState = "firing"   // the alert is firing
+	AlertStateResolved AlertState = "resolved" // the alert stopped firing
+)
+
+// AlertStateDocument defines the schema that should be stored in the
+// `alert_states` collection. A document tracks a single alert from the
+// moment its condition starts holding, through pending and firing, until it
+// resolves. If the alert fires again after resolving, a new document is
+// created. Pending alerts whose condition stops holding are deleted.
+type AlertStateDocument struct {
+	Fingerprint string            `bson:"fingerprint" json:"fingerprint"`
+	ID          string            `bson:"id,omitempty" json:"id,omitempty"`
+	From        string            `bson:"from" json:"from"`
+	Expr        string            `bson:"expr" json:"expr"`
+	Resource    string            `bson:"resource,omitempty" json:"resource,omitempty"`
+	Message     string            `bson:"message" json:"message"`
+	Severity    conf.Severity     `bson:"severity" json:"severity"`
+	Labels      map[string]string `bson:"labels,omitempty" json:"labels,omitempty"`
+	Annotations map[string]string `bson:"annotations,omitempty" json:"annotations,omitempty"`
+	RunbookURL  string            `bson:"runbookUrl,omitempty" json:"runbookUrl,omitempty"`
+	State       AlertState        `bson:"state" json:"state"`
+	FirstSeen   time.Time         `bson:"firstSeen" json:"firstSeen"`
+	LastSeen    time.Time         `bson:"lastSeen" json:"lastSeen"`
+	FiredAt     time.Time         `bson:"firedAt,omitempty" json:"firedAt"`
+	Evaluations int               `bson:"evaluations" json:"evaluations"`
+	ResolvedAt  time.Time         `bson:"resolvedAt,omitempty" json:"resolvedAt"`
+}
+
+// RunStatus is the outcome of a scrape, or of a single reporter within a
+// scrape.
+type RunStatus string
+
+const (
+	RunStatusSuccess RunStatus = "success" // every reporter succeeded
+	RunStatusPartial RunStatus = "partial" // some of the reporters failed
+	RunStatusFailed  RunStatus = "failed"  // every reporter failed
+	RunStatusSkipped RunStatus = "skipped" // the reporter is disabled
+)
+
+// MigrationDocument defines the schema that should be stored in the
+// `migrations` collection. A migration document is written for every applied
+// migration, the schema version of the database being the greatest version
+// recorded.
+type MigrationDocument struct {
+	Version     int       `bson:"version" json:"version"`
+	Description string    `bson:"description" json:"description"`
+	AppliedAt   time.Time `bson:"appliedAt" json:"appliedAt"`
+}
+
+// RunDocument defines the schema that should be stored in the `runs`
+// collection. A run document is written for every scrape and records the
+// outcome of each reporter.
+type RunDocument struct {
+	Timestamp time.Time     `bson:"timestamp" json:"timestamp"`
+	Start     time.Time     `bson:"start" json:"start"`
+	End       time.Time     `bson:"end" json:"end"`
+	Duration  time.Duration `bson:"duration" json:"duration"`
+	Status    RunStatus     `bson:"status" json:"status"`
+	Reporters []ReporterRun `bson:"reporters" json:"reporters"`
+}
+
+// ReporterRun defines the schema that should be stored within the
+// RunDocument in the `runs` collection.
+type ReporterRun struct {
+	// Name is the name of the collection the reporter writes to.
+	Name     string        `bson:"name" json:"name"`
+	Status   RunStatus     `bson:"status" json:"status"`
+	Start    time.Time     `bson:"start,omitempty" json:"start"`
+	End      time.Time     `bson:"end,omitempty" json:"end"`
+	Duration time.Duration `bson:"duration,omitempty" json:"duration"`
+	Error    string        `bson:"error,omitempty" json:"error,omitempty"`
+	// DocumentID is the ID of the document written by the reporter.
+	DocumentID any `bson:"documentId,omitempty" json:"documentId,omitempty"`
 }
 
 const (
 	CollectionAlerts              = "alerts"
+	CollectionRuns                = "runs"
+	CollectionAlertStates         = "alert_states"
+	CollectionMigrations          = "migrations"
 	CollectionRabbitmq            = "rabbitmq"
 	CollectionCeph                = "ceph"
 	CollectionImageTag            = "imagetag"
@@ -34,8 +159,8 @@ const (
 	CollectionPodStatus           = "podstatus"
 )
 
-// Collections is a list of MongoDB collection names, excluding the alerts
-// collection.
+// Collections is a list of MongoDB collection names, excluding the alerts,
+// alert states and runs collections.
 var Collections = []string{
 	CollectionRabbitmq,
 	CollectionCeph,
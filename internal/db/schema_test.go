@@ -0,0 +1,16 @@
+package db
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionCollections(t *testing.T) {
+	a := assert.New(t)
+
+	want := append(slices.Clone(Collections), CollectionAlerts, CollectionRuns)
+	a.ElementsMatch(want, conf.RetentionCollections)
+}
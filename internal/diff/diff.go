@@ -0,0 +1,352 @@
+// Package diff compares two snapshots of the metrics stored by a reporter.
+//
+// The snapshots are compared in their JSON representation, as served by the
+// REST API. Lists of objects are compared by the identity of their elements,
+// e.g., the namespace and the name of a deployment, so that reordering a list
+// is not reported as a change; lists of scalars are compared as sets.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of a change.
+type Kind string
+
+const (
+	KindAdded   Kind = "added"   // the value exists only in the snapshot after
+	KindRemoved Kind = "removed" // the value exists only in the snapshot before
+	KindChanged Kind = "changed" // the value differs between the snapshots
+)
+
+// Change is a difference between two snapshots.
+type Change struct {
+	// Path locates the value in the snapshots, e.g.,
+	// `Deployments[default/nginx].ReadyReplicas`. The elements of lists are
+	// located by their identity, or by their index if they have none.
+	Path   string `json:"path"`
+	Kind   Kind   `json:"kind"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// Report holds the changes between the snapshots of the reporters at two
+// points in time.
+type Report struct {
+	From      time.Time        `json:"from"`
+	To        time.Time        `json:"to"`
+	Reporters []ReporterReport `json:"reporters"`
+}
+
+// ReporterReport holds the changes between the snapshots of a reporter.
+type ReporterReport struct {
+	Reporter string `json:"reporter"`
+	// From and To are the timestamps of the snapshots compared, i.e., of the
+	// latest reports of the reporter at or before the points in time of the
+	// report. They are nil if the reporter has no such report.
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+	// Missing holds the points in time at which the reporter has no
+	// snapshot, in which case the changes are not computed.
+	Missing []time.Time `json:"missing,omitempty"`
+	Changes []Change    `json:"changes"`
+}
+
+// Ignored holds the names of the fields that are expected to change between
+// any two snapshots, such as ages and uptimes, and are not compared. Names
+// are matched case-insensitively.
+var Ignored = []string{"timestamp", "age", "uptime"}
+
+// identity is a set of fields identifying the elements of a list of objects.
+type identity struct {
+	// required fields must be set on every element.
+	required []string
+	// optional fields tell apart the elements with the same required fields.
+	optional []string
+}
+
+// identities are tried in order, the first identifying every element of a
+// list uniquely is used. Names are matched case-insensitively.
+var identities = []identity{
+	{required: []string{"id"}},
+	{required: []string{"namespace", "podname", "name"}},
+	{required: []string{"namespace", "name"}},
+	{required: []string{"pvcnamespace", "pvc"}},
+	{required: []string{"expr"}, optional: []string{"resource"}},
+	{required: []string{"hostname"}},
+	{required: []string{"name"}},
+	{required: []string{"bucket"}},
+	{required: []string{"devid"}},
+	{required: []string{"consumer_tag"}},
+	{required: []string{"path"}},
+	{required: []string{"message"}, optional: []string{"resource"}},
+}
+
+// Compare returns the changes from before to after, sorted by path. Both values
+// are compared in their JSON representation.
+func Compare(before, after any) ([]Change, error) {
+	o, err := normalize(before)
+	if err != nil {
+		return nil, fmt.Errorf("normalizing the snapshot before: %w", err)
+	}
+	n, err := normalize(after)
+	if err != nil {
+		return nil, fmt.Errorf("normalizing the snapshot after: %w", err)
+	}
+	changes := []Change{}
+	compare(&changes, "", o, n)
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return changes, nil
+}
+
+// normalize converts v to the value decoded from its JSON representation,
+// i.e., maps, slices, strings, float64s, bools and nils.
+func normalize(v any) (any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func compare(changes *[]Change, path string, before, after any) {
+	switch {
+	case isEmpty(before) && isEmpty(after):
+		return
+	case isEmpty(before):
+		*changes = append(*changes, Change{Path: path, Kind: KindAdded, After: after})
+		return
+	case isEmpty(after):
+		*changes = append(*changes, Change{Path: path, Kind: KindRemoved, Before: before})
+		return
+	}
+
+	switch o := before.(type) {
+	case map[string]any:
+		if n, ok := after.(map[string]any); ok {
+			compareObjects(changes, path, o, n)
+			return
+		}
+	case []any:
+		if n, ok := after.([]any); ok {
+			compareLists(changes, path, o, n)
+			return
+		}
+	default:
+		if before == after {
+			return
+		}
+	}
+	*changes = append(*changes, Change{Path: path, Kind: KindChanged, Before: before, After: after})
+}
+
+func compareObjects(changes *[]Change, path string, before, after map[string]any) {
+	keys := slices.Collect(maps.Keys(before))
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if isIgnored(k) {
+			continue
+		}
+		compare(changes, join(path, k), before[k], after[k])
+	}
+}
+
+func compareLists(changes *[]Change, path string, before, after []any) {
+	if isScalars(before) && isScalars(after) {
+		compareSets(changes, path, before, after)
+		return
+	}
+
+	beforeKeys, afterKeys := identify(before, after)
+	if beforeKeys == nil {
+		// compare the elements by index
+		for idx := range max(len(before), len(after)) {
+			var o, n any
+			if idx < len(before) {
+				o = before[idx]
+			}
+			if idx < len(after) {
+				n = after[idx]
+			}
+			compare(changes, fmt.Sprintf("%s[%d]", path, idx), o, n)
+		}
+		return
+	}
+
+	byKey := make(map[string]any, len(after))
+	for idx, key := range afterKeys {
+		byKey[key] = after[idx]
+	}
+	for idx, key := range beforeKeys {
+		compare(changes, element(path, key), before[idx], byKey[key])
+		delete(byKey, key)
+	}
+	for idx, key := range afterKeys {
+		if _, ok := byKey[key]; ok {
+			*changes = append(*changes, Change{Path: element(path, key), Kind: KindAdded, After: after[idx]})
+		}
+	}
+}
+
+// compareSets compares lists of scalars regardless of the order of their
+// elements.
+func compareSets(changes *[]Change, path string, before, after []any) {
+	count := make(map[string]int)
+	for _, v := range after {
+		count[scalar(v)]++
+	}
+	for _, v := range before {
+		s := scalar(v)
+		if count[s] > 0 {
+			count[s]--
+			continue
+		}
+		*changes = append(*changes, Change{Path: element(path, s), Kind: KindRemoved, Before: v})
+	}
+	for _, v := range after {
+		s := scalar(v)
+		if count[s] > 0 {
+			count[s]--
+			*changes = append(*changes, Change{Path: element(path, s), Kind: KindAdded, After: v})
+		}
+	}
+}
+
+// identify returns the keys identifying the elements of both lists, using
+// the first identity that identifies every element uniquely. It returns nil
+// if there is none.
+func identify(before, after []any) ([]string, []string) {
+	for _, id := range identities {
+		beforeKeys, ok := keys(id, before)
+		if !ok {
+			continue
+		}
+		afterKeys, ok := keys(id, after)
+		if !ok {
+			continue
+		}
+		return beforeKeys, afterKeys
+	}
+	return nil, nil
+}
+
+func keys(id identity, list []any) ([]string, bool) {
+	keys := make([]string, 0, len(list))
+	seen := make(map[string]bool, len(list))
+	for _, v := range list {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		parts := make([]string, 0, len(id.required)+len(id.optional))
+		for _, f := range id.required {
+			val, ok := field(obj, f)
+			if !ok || isEmpty(val) || !isScalar(val) {
+				return nil, false
+			}
+			parts = append(parts, scalar(val))
+		}
+		for _, f := range id.optional {
+			if val, ok := field(obj, f); ok && !isEmpty(val) && isScalar(val) {
+				parts = append(parts, scalar(val))
+			}
+		}
+		key := strings.Join(parts, "/")
+		if seen[key] {
+			return nil, false
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, true
+}
+
+// field returns the value of the field of obj, matching its name
+// case-insensitively.
+func field(obj map[string]any, name string) (any, bool) {
+	if v, ok := obj[name]; ok {
+		return v, true
+	}
+	for k, v := range obj {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func isIgnored(name string) bool {
+	return slices.ContainsFunc(Ignored, func(ignored string) bool {
+		return strings.EqualFold(ignored, name)
+	})
+}
+
+// isEmpty reports whether v is null, or an empty object or list. Empty
+// values are omitted from most snapshots, so they are treated as missing.
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+func isScalar(v any) bool {
+	switch v.(type) {
+	case map[string]any, []any:
+		return false
+	default:
+		return true
+	}
+}
+
+func isScalars(list []any) bool {
+	return !slices.ContainsFunc(list, func(v any) bool {
+		return !isScalar(v)
+	})
+}
+
+// scalar formats a scalar value for use in paths.
+func scalar(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func element(path, key string) string {
+	return path + "[" + key + "]"
+}
@@ -0,0 +1,83 @@
+package diff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/types/dass"
+	"github.com/accuknox/rinc/types/imagetag"
+	"github.com/accuknox/rinc/types/rabbitmq"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	a := assert.New(t)
+
+	changes, err := Compare(
+		imagetag.Metrics{
+			Timestamp: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+			Deployments: []imagetag.Resource{
+				{Name: "api", Namespace: "default", Images: []imagetag.Image{{Name: "api:1.0"}}},
+				{Name: "api", Namespace: "staging", Images: []imagetag.Image{{Name: "api:1.1"}}},
+				{Name: "old", Namespace: "default", Images: []imagetag.Image{{Name: "old:1.0"}}},
+			},
+		},
+		imagetag.Metrics{
+			Timestamp: time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC),
+			Deployments: []imagetag.Resource{
+				{Name: "new", Namespace: "default", Images: []imagetag.Image{{Name: "new:1.0"}}},
+				{Name: "api", Namespace: "staging", Images: []imagetag.Image{{Name: "api:1.1"}}},
+				{Name: "api", Namespace: "default", Images: []imagetag.Image{{Name: "api:1.1"}}},
+			},
+		},
+	)
+	a.NoError(err)
+	a.Equal([]Change{
+		{Path: "Deployments[default/api].Images[api:1.0]", Kind: KindRemoved, Before: map[string]any{"Name": "api:1.0", "FromInitContainer": false}},
+		{Path: "Deployments[default/api].Images[api:1.1]", Kind: KindAdded, After: map[string]any{"Name": "api:1.1", "FromInitContainer": false}},
+		{Path: "Deployments[default/new]", Kind: KindAdded, After: map[string]any{"Name": "new", "Namespace": "default", "Images": []any{map[string]any{"Name": "new:1.0", "FromInitContainer": false}}}},
+		{Path: "Deployments[default/old]", Kind: KindRemoved, Before: map[string]any{"Name": "old", "Namespace": "default", "Images": []any{map[string]any{"Name": "old:1.0", "FromInitContainer": false}}}},
+	}, changes)
+
+	// ages change on every scrape and are ignored
+	changes, err = Compare(
+		dass.Metrics{Deployments: []dass.Resource{{Name: "api", Namespace: "default", Age: time.Hour, ReadyReplicas: 3}}},
+		dass.Metrics{Deployments: []dass.Resource{{Name: "api", Namespace: "default", Age: 2 * time.Hour, ReadyReplicas: 1}}},
+	)
+	a.NoError(err)
+	a.Equal([]Change{
+		{Path: "Deployments[default/api].ReadyReplicas", Kind: KindChanged, Before: 3.0, After: 1.0},
+	}, changes)
+
+	// lists of scalars are compared as sets, and empty values as missing ones
+	changes, err = Compare(
+		rabbitmq.Metrics{
+			Nodes:  rabbitmq.Nodes{{Name: "rabbit@a", EnabledPlugins: []string{"management", "shovel"}}},
+			Queues: rabbitmq.Queues{{Name: "jobs", Messages: 4}},
+		},
+		rabbitmq.Metrics{
+			IsClusterUp: true,
+			Nodes:       rabbitmq.Nodes{{Name: "rabbit@a", EnabledPlugins: []string{"federation", "management"}}},
+			Queues:      rabbitmq.Queues{{Name: "jobs", Messages: 4}, {Name: "events"}},
+		},
+	)
+	a.NoError(err)
+	a.Equal([]Change{
+		{Path: "isClusterUp", Kind: KindAdded, After: true},
+		{Path: "nodes[rabbit@a].enabled_plugins[federation]", Kind: KindAdded, After: "federation"},
+		{Path: "nodes[rabbit@a].enabled_plugins[shovel]", Kind: KindRemoved, Before: "shovel"},
+		{Path: "queues[events]", Kind: KindAdded, After: map[string]any{"name": "events"}},
+	}, changes)
+
+	// lists without identity are compared by index
+	changes, err = Compare(
+		map[string]any{"events": []any{map[string]any{"reason": "a"}}},
+		map[string]any{"events": []any{map[string]any{"reason": "b"}, map[string]any{"reason": "c"}}},
+	)
+	a.NoError(err)
+	a.Equal([]Change{
+		{Path: "events[0].reason", Kind: KindChanged, Before: "a", After: "b"},
+		{Path: "events[1]", Kind: KindAdded, After: map[string]any{"reason": "c"}},
+	}, changes)
+}
@@ -0,0 +1,301 @@
+package expr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// Min returns the smallest number in list. If field is provided, list must
+// be a list of structs, and the numbers are the values of the given field.
+// It returns 0 for an empty list.
+func Min(list any, field ...string) (float64, error) {
+	nums, err := numbers(list, field...)
+	if err != nil || len(nums) == 0 {
+		return 0, err
+	}
+	return slices.Min(nums), nil
+}
+
+// Max returns the largest number in list, see Min.
+func Max(list any, field ...string) (float64, error) {
+	nums, err := numbers(list, field...)
+	if err != nil || len(nums) == 0 {
+		return 0, err
+	}
+	return slices.Max(nums), nil
+}
+
+// Avg returns the arithmetic mean of the numbers in list, see Min.
+func Avg(list any, field ...string) (float64, error) {
+	nums, err := numbers(list, field...)
+	if err != nil || len(nums) == 0 {
+		return 0, err
+	}
+	var sum float64
+	for _, n := range nums {
+		sum += n
+	}
+	return sum / float64(len(nums)), nil
+}
+
+// Percentile returns the p-th percentile, between 0 and 100, of the numbers
+// in list, interpolating linearly between the closest ranks. See Min for the
+// field.
+func Percentile(list any, p float64, field ...string) (float64, error) {
+	if p < 0 || p > 100 {
+		return 0, fmt.Errorf("percentile must be between 0 and 100, got %v", p)
+	}
+	nums, err := numbers(list, field...)
+	if err != nil || len(nums) == 0 {
+		return 0, err
+	}
+	slices.Sort(nums)
+	rank := p / 100 * float64(len(nums)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	return nums[lo] + (nums[hi]-nums[lo])*(rank-float64(lo)), nil
+}
+
+// Count returns the number of elements of list for which the boolean
+// expression evaluates to true.
+func Count(ctx context.Context, list any, expr string) (int, error) {
+	var n int
+	err := eachMatch(ctx, list, expr, func(match bool) bool {
+		if match {
+			n++
+		}
+		return true
+	})
+	return n, err
+}
+
+// Any reports whether the boolean expression evaluates to true for at least
+// one element of list.
+func Any(ctx context.Context, list any, expr string) (bool, error) {
+	var found bool
+	err := eachMatch(ctx, list, expr, func(match bool) bool {
+		found = found || match
+		return !found
+	})
+	return found, err
+}
+
+// All reports whether the boolean expression evaluates to true for every
+// element of list. It returns true for an empty list.
+func All(ctx context.Context, list any, expr string) (bool, error) {
+	all := true
+	err := eachMatch(ctx, list, expr, func(match bool) bool {
+		all = all && match
+		return all
+	})
+	return all, err
+}
+
+// SortBy returns a copy of list sorted in ascending order. If field is
+// provided, list must be a list of structs sorted by the given numeric or
+// string field.
+func SortBy(list any, field ...string) ([]any, error) {
+	items, keys, err := sortKeys(list, field...)
+	if err != nil {
+		return nil, err
+	}
+	idx := make([]int, len(items))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return less(keys[idx[i]], keys[idx[j]])
+	})
+	sorted := make([]any, len(items))
+	for i, k := range idx {
+		sorted[i] = items[k]
+	}
+	return sorted, nil
+}
+
+// Top returns the n largest elements of list, largest first. See SortBy for
+// the field.
+func Top(list any, n float64, field ...string) ([]any, error) {
+	if n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("want a non-negative integer count, got %v", n)
+	}
+	sorted, err := SortBy(list, field...)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(sorted)
+	return sorted[:min(int(n), len(sorted))], nil
+}
+
+// Uniq returns the distinct elements of list, in order of first appearance.
+// If field is provided, list must be a list of structs and the distinct
+// values of the given field are returned instead.
+func Uniq(list any, field ...string) ([]any, error) {
+	items, err := values(list, field...)
+	if err != nil {
+		return nil, err
+	}
+	var uniq []any
+	for _, item := range items {
+		if !slices.ContainsFunc(uniq, func(u any) bool { return reflect.DeepEqual(u, item) }) {
+			uniq = append(uniq, item)
+		}
+	}
+	return uniq, nil
+}
+
+// eachMatch evaluates the boolean expression against each element of list,
+// passing the result to fn until it returns false. While type-checking,
+// every element is evaluated.
+func eachMatch(ctx context.Context, list any, expr string, fn func(bool) bool) error {
+	rlist := reflect.ValueOf(list)
+	if rlist.Kind() != reflect.Slice && rlist.Kind() != reflect.Array {
+		return ErrUnexpectedKind[string]{
+			arg:  0,
+			want: fmt.Sprintf("%s|%s", reflect.Array, reflect.Slice),
+			got:  rlist.Kind().String(),
+		}
+	}
+	ev, err := Compile(expr)
+	if err != nil {
+		return fmt.Errorf("parsing expression %q: %w", expr, err)
+	}
+	for idx := 0; idx < rlist.Len(); idx++ {
+		match, err := ev.EvalBool(ctx, rlist.Index(idx).Interface())
+		if err != nil {
+			return fmt.Errorf("evaluating expr %q: %w", expr, err)
+		}
+		if !fn(match) && !Checking(ctx) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// values returns the elements of list or, if field is provided, the values
+// of the given field of each struct in list.
+func values(list any, field ...string) ([]any, error) {
+	if len(field) > 1 {
+		return nil, fmt.Errorf("want at most one field, got %d", len(field))
+	}
+	rlist := reflect.ValueOf(list)
+	if rlist.Kind() != reflect.Slice && rlist.Kind() != reflect.Array {
+		return nil, ErrUnexpectedKind[string]{
+			arg:  0,
+			want: fmt.Sprintf("%s|%s", reflect.Array, reflect.Slice),
+			got:  rlist.Kind().String(),
+		}
+	}
+
+	items := make([]any, 0, rlist.Len())
+	for idx := 0; idx < rlist.Len(); idx++ {
+		item := reflect.ValueOf(rlist.Index(idx).Interface())
+		if item.Kind() == reflect.Ptr {
+			item = item.Elem()
+		}
+		if len(field) == 0 {
+			items = append(items, item.Interface())
+			continue
+		}
+		if item.Kind() != reflect.Struct {
+			return nil, ErrUnexpectedKind[reflect.Kind]{
+				arg:  "list[] -> item",
+				want: reflect.Struct,
+				got:  item.Kind(),
+			}
+		}
+		fval := item.FieldByName(field[0])
+		if !fval.IsValid() {
+			return nil, ErrFieldNotExist{
+				field: field[0],
+				on:    "list(arg 0)",
+			}
+		}
+		items = append(items, fval.Interface())
+	}
+	return items, nil
+}
+
+// numbers returns the elements of list, or the values of the given field, as
+// numbers.
+func numbers(list any, field ...string) ([]float64, error) {
+	items, err := values(list, field...)
+	if err != nil {
+		return nil, err
+	}
+	nums := make([]float64, len(items))
+	for idx, item := range items {
+		n, ok := toFloat(item)
+		if !ok {
+			return nil, ErrUnexpectedKind[string]{
+				arg:  "list[] -> item",
+				want: "number",
+				got:  reflect.ValueOf(item).Kind().String(),
+			}
+		}
+		nums[idx] = n
+	}
+	return nums, nil
+}
+
+// sortKeys returns the elements of list along with the keys to sort them
+// by: the elements themselves, or the values of the given field.
+func sortKeys(list any, field ...string) ([]any, []any, error) {
+	keys, err := values(list, field...)
+	if err != nil {
+		return nil, nil, err
+	}
+	items := slices.Clone(keys)
+	if len(field) != 0 {
+		items, err = values(list)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for idx, key := range keys {
+		if n, ok := toFloat(key); ok {
+			keys[idx] = n
+			continue
+		}
+		if reflect.ValueOf(key).Kind() != reflect.String {
+			return nil, nil, ErrUnexpectedKind[string]{
+				arg:  "list[] -> item",
+				want: "number|string",
+				got:  reflect.ValueOf(key).Kind().String(),
+			}
+		}
+		keys[idx] = reflect.ValueOf(key).String()
+	}
+	return items, keys, nil
+}
+
+// less compares two sort keys, ordering numbers before strings.
+func less(x, y any) bool {
+	switch x := x.(type) {
+	case float64:
+		y, ok := y.(float64)
+		return !ok || x < y
+	case string:
+		y, ok := y.(string)
+		return ok && x < y
+	}
+	return false
+}
+
+// toFloat converts a number of any kind to a float64.
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
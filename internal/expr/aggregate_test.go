@@ -0,0 +1,76 @@
+package expr_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/accuknox/rinc/internal/expr"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregate(t *testing.T) {
+	a := assert.New(t)
+	type container struct {
+		Name           string
+		Namespace      string
+		MemUsedPercent float64
+		Restarts       int32
+	}
+	data := struct {
+		Containers []*container
+		Usage      []float64
+	}{
+		Containers: []*container{
+			{Name: "foo", Namespace: "default", MemUsedPercent: 95, Restarts: 3},
+			{Name: "bar", Namespace: "default", MemUsedPercent: 40, Restarts: 0},
+			{Name: "baz", Namespace: "kube-system", MemUsedPercent: 70, Restarts: 1},
+		},
+		Usage: []float64{10, 20, 30, 40, 50},
+	}
+	inputs := map[string]any{
+		`min(Usage)`:                                     10.0,
+		`max(Containers, "MemUsedPercent")`:              95.0,
+		`avg(Containers, "Restarts")`:                    4.0 / 3,
+		`avg(Containers ~> "MemUsedPercent")`:            205.0 / 3,
+		`percentile(Usage, 50)`:                          30.0,
+		`percentile(Usage, 95)`:                          48.0,
+		`percentile(Containers, 100, "Restarts")`:        3.0,
+		`count(Containers, "MemUsedPercent > 60")`:       2,
+		`any(Containers, "MemUsedPercent > 90")`:         true,
+		`all(Containers, "Restarts > 0")`:                false,
+		`all(Usage, "false")`:                            false,
+		`sortBy(Containers, "Name") ~> "Name"`:           []any{"bar", "baz", "foo"},
+		`top(Containers, 2, "MemUsedPercent") ~> "Name"`: []any{"foo", "baz"},
+		`top(Usage, 1)`:                                  []any{50.0},
+		`uniq(Containers, "Namespace")`:                  []any{"default", "kube-system"},
+		`len(uniq(Containers ~> "Namespace"))`:           2,
+	}
+	for input, want := range inputs {
+		got, err := gval.Full(expr.Full()...).EvaluateWithContext(context.TODO(), input, data)
+		if a.NoErrorf(err, "INPUT=%s", input) {
+			a.Equalf(want, got, "INPUT=%s", input)
+		}
+	}
+
+	for _, input := range []string{
+		`max(Containers, "Name")`,
+		`min(Containers)`,
+		`sortBy(Containers)`,
+		`percentile(Usage, 101)`,
+		`count(Containers, "Foo > 1")`,
+		`top(Usage, -1)`,
+		`top(Usage, 1.5)`,
+	} {
+		_, err := gval.Full(expr.Full()...).EvaluateWithContext(context.TODO(), input, data)
+		a.Errorf(err, "INPUT=%s", input)
+	}
+
+	got, err := expr.Max([]int{1}, "Foo")
+	a.Error(err, "only lists of structs have fields")
+	a.Zero(got)
+	got, err = expr.Max([]int{})
+	a.NoError(err)
+	a.Zero(got, "empty lists")
+}
@@ -0,0 +1,129 @@
+package expr_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/expr"
+	"github.com/accuknox/rinc/types/pod"
+	"github.com/accuknox/rinc/types/resource"
+
+	"github.com/PaesslerAG/gval"
+)
+
+// resourceMetrics returns synthetic resource utilization metrics holding n
+// containers.
+func resourceMetrics(n int) resource.Metrics {
+	m := resource.Metrics{Containers: make([]resource.Container, n)}
+	for idx := range m.Containers {
+		m.Containers[idx] = resource.Container{
+			PodName:        fmt.Sprintf("pod-%d", idx),
+			Namespace:      fmt.Sprintf("ns-%d", idx%10),
+			Name:           fmt.Sprintf("container-%d", idx),
+			MemUsedPercent: float64(idx % 100),
+			CPUUsedPercent: float64(idx*7) / 10,
+		}
+	}
+	return m
+}
+
+// podMetrics returns synthetic pod status metrics holding n deployments of
+// three pods each.
+func podMetrics(n int) pod.Metrics {
+	m := pod.Metrics{Deployments: make([]pod.Resource, n)}
+	for idx := range m.Deployments {
+		pods := make([]pod.Pod, 3)
+		for p := range pods {
+			pods[p] = pod.Pod{
+				Name:   fmt.Sprintf("deploy-%d-%d", idx, p),
+				Status: "Running",
+				Containers: []pod.Container{
+					{Name: "main", Ready: true, RestartCount: int32(idx % 7)},
+				},
+			}
+		}
+		m.Deployments[idx] = pod.Resource{
+			Name:      fmt.Sprintf("deploy-%d", idx),
+			Namespace: fmt.Sprintf("ns-%d", idx%10),
+			Pods:      pods,
+		}
+	}
+	return m
+}
+
+func BenchmarkEvalOnEach(b *testing.B) {
+	m := resourceMetrics(5000)
+	b.ResetTimer()
+	for range b.N {
+		_, err := expr.EvalOnEach(m.Containers, "MemUsedPercent > 90", "Name")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCount(b *testing.B) {
+	m := podMetrics(2000)
+	ctx := context.TODO()
+	b.ResetTimer()
+	for range b.N {
+		_, err := expr.Count(ctx, m.Deployments, `len(Pods) != len(findMany(Pods, "Status", "Running"))`)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCompile compares compiling an expression on every evaluation,
+// as done before compiled expressions were cached, with Compile.
+func BenchmarkCompile(b *testing.B) {
+	c := resourceMetrics(1).Containers[0]
+	ctx := context.TODO()
+	const e = `MemUsedPercent > 90 && Namespace == "ns-0"`
+	compilers := map[string]func(string) (gval.Evaluable, error){
+		"uncached": expr.Language().NewEvaluable,
+		"cached":   expr.Compile,
+	}
+	for name, compile := range compilers {
+		b.Run(name, func(b *testing.B) {
+			for range b.N {
+				ev, err := compile(e)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := ev(ctx, c); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMessage compares rendering a message template parsed once, as
+// done for the templates of the configuration, with parsing it on every
+// rendering.
+func BenchmarkMessage(b *testing.B) {
+	c := resourceMetrics(1).Containers[0]
+	ctx := context.TODO()
+	const text = "container `Name` of pod `PodName` uses {{ .MemUsedPercent }}% of its memory limit"
+	var precompiled conf.StringExpr
+	if err := precompiled.UnmarshalText([]byte(text)); err != nil {
+		b.Fatal(err)
+	}
+	messages := map[string]conf.StringExpr{
+		"precompiled": precompiled,
+		// a StringExpr that was not unmarshalled is parsed when rendered.
+		"per-call": {Text: text},
+	}
+	for name, msg := range messages {
+		b.Run(name, func(b *testing.B) {
+			for range b.N {
+				if _, err := msg.Evaluate(ctx, c); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
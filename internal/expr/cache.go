@@ -0,0 +1,115 @@
+package expr
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/PaesslerAG/gval"
+)
+
+// CacheSize is the maximum number of compiled expressions kept by Compile.
+const CacheSize = 1024
+
+var (
+	languageOnce sync.Once
+	language     gval.Language
+
+	cache = newExprCache(CacheSize)
+)
+
+// Language returns the expression language: gval's full language extended
+// with the functions and operators returned by Full, and with a ternary
+// operator evaluating both branches while type-checking. It is built once.
+func Language() gval.Language {
+	languageOnce.Do(func() {
+		language = gval.NewLanguage(
+			// gval keeps the first definition of a postfix operator, so
+			// the ternary operator precedes the one of gval.Full.
+			gval.PostfixOperator("?", ternary),
+			gval.Full(Full()...),
+		)
+	})
+	return language
+}
+
+// Compile parses an expression of the expression language. Compiled
+// expressions are cached, so compiling the same expression again, such as
+// the inner expression of `evalOnEach` for each element of a list, is
+// cheap. The cache holds up to CacheSize expressions, evicting the least
+// recently used one.
+func Compile(expr string) (gval.Evaluable, error) {
+	if ev, ok := cache.get(expr); ok {
+		return ev, nil
+	}
+	ev, err := Language().NewEvaluable(expr)
+	if err != nil {
+		return nil, err
+	}
+	cache.add(expr, ev)
+	return ev, nil
+}
+
+// Evaluate compiles the expression using Compile and evaluates it against
+// data.
+func Evaluate(ctx context.Context, expr string, data any) (any, error) {
+	ev, err := Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing expression %q: %w", expr, err)
+	}
+	return ev(ctx, data)
+}
+
+// exprCache is a least recently used cache of compiled expressions.
+type exprCache struct {
+	mu      sync.Mutex
+	max     int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	expr string
+	ev   gval.Evaluable
+}
+
+func newExprCache(max int) *exprCache {
+	return &exprCache{
+		max:     max,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, max),
+	}
+}
+
+func (c *exprCache) get(expr string) (gval.Evaluable, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[expr]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(cacheEntry).ev, true
+}
+
+func (c *exprCache) add(expr string, ev gval.Evaluable) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[expr]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[expr] = c.order.PushFront(cacheEntry{expr: expr, ev: ev})
+	if c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(cacheEntry).expr)
+	}
+}
+
+func (c *exprCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
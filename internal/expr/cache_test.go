@@ -0,0 +1,38 @@
+package expr
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	a := assert.New(t)
+	c := newExprCache(2)
+	for idx := range 3 {
+		ev, err := Language().NewEvaluable(fmt.Sprintf("X > %d", idx))
+		a.NoError(err)
+		c.add(fmt.Sprintf("X > %d", idx), ev)
+	}
+	a.Equal(2, c.len(), "the cache is bounded")
+	_, ok := c.get("X > 0")
+	a.False(ok, "the least recently used expression is evicted")
+	_, ok = c.get("X > 2")
+	a.True(ok)
+
+	ev, err := Compile("X * 2")
+	if a.NoError(err) {
+		got, err := ev(context.TODO(), map[string]int{"X": 21})
+		a.NoError(err)
+		a.Equal(42.0, got)
+	}
+	_, ok = cache.get("X * 2")
+	a.True(ok, "compiled expressions are cached")
+
+	_, err = Compile("X >")
+	a.Error(err)
+	_, ok = cache.get("X >")
+	a.False(ok, "invalid expressions are not cached")
+}
@@ -0,0 +1,140 @@
+package expr
+
+import (
+	"context"
+	"reflect"
+	"text/scanner"
+
+	"github.com/PaesslerAG/gval"
+)
+
+type checkKey struct{}
+
+// WithCheck returns a copy of ctx in which expressions are type-checked
+// rather than evaluated: `findOne`, `findMany` and their regex variants
+// match every element of the list, and `evalOnEach` selects every element,
+// so that the rest of the expression is evaluated against them. Both
+// operands of `&&`, `||` and `??`, and both branches of `?:`, are evaluated
+// regardless of the values they short-circuit on. Use it with the value
+// returned by Sample.
+func WithCheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, checkKey{}, true)
+}
+
+// Checking reports whether expressions are type-checked with ctx, see
+// WithCheck.
+func Checking(ctx context.Context) bool {
+	check, _ := ctx.Value(checkKey{}).(bool)
+	return check
+}
+
+// shortCircuit returns the operator name of gval.Full, which skips the
+// right operand when short reports so for the left one. While
+// type-checking, the right operand is evaluated regardless.
+func shortCircuit(name string, short func(a any) (any, bool)) gval.Language {
+	op, err := gval.Full().NewEvaluable("a " + name + " b")
+	if err != nil {
+		panic(err)
+	}
+	return gval.InfixEvalOperator(name, func(a, b gval.Evaluable) (gval.Evaluable, error) {
+		return func(c context.Context, v any) (any, error) {
+			x, err := a(c, v)
+			if err != nil {
+				return nil, err
+			}
+			r, ok := short(x)
+			if ok && !Checking(c) {
+				return r, nil
+			}
+			y, err := b(c, v)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return r, nil
+			}
+			return op(c, map[string]any{"a": x, "b": y})
+		}, nil
+	})
+}
+
+// ternary parses the `?:` operator of gval.Full. While type-checking, both
+// branches are evaluated regardless of the condition.
+func ternary(c context.Context, p *gval.Parser, cond gval.Evaluable) (gval.Evaluable, error) {
+	a, err := p.ParseExpression(c)
+	if err != nil {
+		return nil, err
+	}
+	b := p.Const(nil)
+	switch p.Scan() {
+	case ':':
+		b, err = p.ParseExpression(c)
+		if err != nil {
+			return nil, err
+		}
+	case scanner.EOF:
+	default:
+		return nil, p.Expected("<> ? <> : <>", ':', scanner.EOF)
+	}
+	return func(c context.Context, v any) (any, error) {
+		x, err := cond(c, v)
+		if err != nil {
+			return nil, err
+		}
+		branch, other := a, b
+		if x == nil || reflect.ValueOf(x).IsZero() {
+			branch, other = b, a
+		}
+		if Checking(c) {
+			if _, err := other(c, v); err != nil {
+				return nil, err
+			}
+		}
+		return branch(c, v)
+	}, nil
+}
+
+// Sample returns a value of the same type as v in which every slice holds
+// one element, every map holds one entry and every pointer is set, all of
+// them populated recursively. Evaluating an expression against it reaches
+// every field of the type, which allows type-checking the expression.
+func Sample(v any) any {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil
+	}
+	return sample(t, make(map[reflect.Type]bool)).Interface()
+}
+
+// sample returns a populated value of type t. seen holds the types being
+// populated, so that recursive types are left empty the second time.
+func sample(t reflect.Type, seen map[reflect.Type]bool) reflect.Value {
+	v := reflect.New(t).Elem()
+	if seen[t] {
+		return v
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		v.Set(sample(t.Elem(), seen).Addr())
+	case reflect.Struct:
+		for idx := 0; idx < t.NumField(); idx++ {
+			if !t.Field(idx).IsExported() {
+				continue
+			}
+			v.Field(idx).Set(sample(t.Field(idx).Type, seen))
+		}
+	case reflect.Slice:
+		v.Set(reflect.Append(v, sample(t.Elem(), seen)))
+	case reflect.Array:
+		for idx := 0; idx < t.Len(); idx++ {
+			v.Index(idx).Set(sample(t.Elem(), seen))
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		v.SetMapIndex(sample(t.Key(), seen), sample(t.Elem(), seen))
+	}
+	return v
+}
@@ -3,6 +3,7 @@ package expr
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 type ErrUnexpectedKind[T string | reflect.Kind] struct {
@@ -23,3 +24,17 @@ type ErrFieldNotExist struct {
 func (e ErrFieldNotExist) Error() string {
 	return fmt.Sprintf("field %q does not exist on %q", e.field, e.on)
 }
+
+// ErrUnknownParameter is returned when a key of a variable, such as the
+// index of `Pods[1]` or the field of `Spec.Foo`, cannot be selected.
+type ErrUnknownParameter struct {
+	// Path holds the keys of the variable up to the one that could not be
+	// selected.
+	Path []string
+	// On is the kind of the value the last key was selected on.
+	On reflect.Kind
+}
+
+func (e ErrUnknownParameter) Error() string {
+	return fmt.Sprintf("unknown parameter %s", strings.Join(e.Path, "."))
+}
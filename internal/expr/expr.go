@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/accuknox/rinc/types"
@@ -13,30 +14,70 @@ import (
 )
 
 func Full() []gval.Language {
-	return []gval.Language{
-		gval.Function("has", Has),
-		gval.Function("len", Len),
-		gval.Function("fieldsEq", FieldsEq),
-		gval.Function("sumInt", Sum[int]),
-		gval.Function("sumInt8", Sum[int8]),
-		gval.Function("sumInt16", Sum[int16]),
-		gval.Function("sumInt32", Sum[int32]),
-		gval.Function("sumInt64", Sum[int64]),
-		gval.Function("sumUint", Sum[uint]),
-		gval.Function("sumUint8", Sum[uint8]),
-		gval.Function("sumUint16", Sum[uint16]),
-		gval.Function("sumUint32", Sum[uint32]),
-		gval.Function("sumUint64", Sum[uint64]),
-		gval.Function("sumFloat32", Sum[float32]),
-		gval.Function("sumFloat64", Sum[float64]),
-		gval.Function("findOne", FindOne),
-		gval.Function("findMany", FindMany),
-		gval.Function("findOneRegex", FindOneRegex),
-		gval.Function("findManyRegex", FindManyRegex),
-		gval.Function("evalOnEach", EvalOnEach),
+	var langs []gval.Language
+	for name, fn := range Functions() {
+		langs = append(langs, gval.Function(name, fn))
+	}
+	return append(langs,
+		gval.VariableSelector(variable),
+		shortCircuit("&&", func(a any) (any, bool) { return false, a == false }),
+		shortCircuit("||", func(a any) (any, bool) { return true, a == true }),
+		shortCircuit("??", func(a any) (any, bool) {
+			return a, a != nil && !reflect.ValueOf(a).IsZero()
+		}),
 		gval.InfixOperator("->", AccessOp),
 		gval.InfixOperator("~>", AccessSpreadOp),
 		gval.PostfixOperator("|", pipeOp),
+	)
+}
+
+// Functions returns the functions of the expression language by name.
+// Functions whose first parameter is a context.Context receive the context
+// the expression is evaluated with.
+func Functions() map[string]any {
+	return map[string]any{
+		"has":           Has,
+		"len":           Len,
+		"fieldsEq":      FieldsEq,
+		"sumInt":        Sum[int],
+		"sumInt8":       Sum[int8],
+		"sumInt16":      Sum[int16],
+		"sumInt32":      Sum[int32],
+		"sumInt64":      Sum[int64],
+		"sumUint":       Sum[uint],
+		"sumUint8":      Sum[uint8],
+		"sumUint16":     Sum[uint16],
+		"sumUint32":     Sum[uint32],
+		"sumUint64":     Sum[uint64],
+		"sumFloat32":    Sum[float32],
+		"sumFloat64":    Sum[float64],
+		"findOne":       findFunc(FindOpts{One: true}),
+		"findMany":      findFunc(FindOpts{One: false}),
+		"findOneRegex":  findFunc(FindOpts{One: true, MatchAsStr: true}),
+		"findManyRegex": findFunc(FindOpts{One: false, MatchAsStr: true}),
+		"evalOnEach":    evalOnEach,
+		"prev":          Prev,
+		"delta":         Delta,
+		"rate":          Rate,
+		"min":           Min,
+		"max":           Max,
+		"avg":           Avg,
+		"percentile":    Percentile,
+		"count":         Count,
+		"any":           Any,
+		"all":           All,
+		"sortBy":        SortBy,
+		"top":           Top,
+		"uniq":          Uniq,
+		"duration":      Duration,
+		"now":           Now,
+		"since":         Since,
+		"days":          Days,
+		"hours":         Hours,
+		"minutes":       Minutes,
+		"seconds":       Seconds,
+		"humanDuration": HumanDuration,
+		"humanBytes":    HumanBytes,
 	}
 }
 
@@ -150,6 +191,19 @@ func FindManyRegex(list any, field string, value any) (any, error) {
 type FindOpts struct {
 	One        bool
 	MatchAsStr bool
+	// MatchAll matches every element of the list, once the field has been
+	// checked. It is used to type-check expressions, see WithCheck.
+	MatchAll bool
+}
+
+// findFunc returns the function registered in the expression language for
+// the provided options, matching every element while type-checking.
+func findFunc(opts FindOpts) func(context.Context, any, string, any) (any, error) {
+	return func(ctx context.Context, list any, field string, value any) (any, error) {
+		opts := opts
+		opts.MatchAll = Checking(ctx)
+		return Find(list, field, value, &opts)
+	}
 }
 
 func Find(list any, field string, value any, opts *FindOpts) (any, error) {
@@ -210,13 +264,13 @@ func Find(list any, field string, value any, opts *FindOpts) (any, error) {
 			if err != nil {
 				return nil, fmt.Errorf("compiling regex %q: %w", vval.String(), err)
 			}
-			if !regex.MatchString(s) {
+			if !regex.MatchString(s) && !opts.MatchAll {
 				continue
 			}
 			matches = append(matches, item.Interface())
 			continue
 		}
-		if reflect.DeepEqual(fval.Interface(), value) {
+		if reflect.DeepEqual(fval.Interface(), value) || opts.MatchAll {
 			matches = append(matches, item.Interface())
 		}
 	}
@@ -232,6 +286,12 @@ func Find(list any, field string, value any, opts *FindOpts) (any, error) {
 }
 
 func EvalOnEach(list any, expr, ret string) (any, error) {
+	return evalOnEach(context.TODO(), list, expr, ret)
+}
+
+// evalOnEach is EvalOnEach, evaluating expr with the provided context. While
+// type-checking, every element is selected.
+func evalOnEach(ctx context.Context, list any, expr, ret string) (any, error) {
 	rlist := reflect.ValueOf(list)
 	if rlist.Kind() != reflect.Slice && rlist.Kind() != reflect.Array {
 		return nil, ErrUnexpectedKind[string]{
@@ -241,6 +301,11 @@ func EvalOnEach(list any, expr, ret string) (any, error) {
 		}
 	}
 
+	ev, err := Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing expression %q: %w", expr, err)
+	}
+
 	var postivies []any
 
 	for idx := 0; idx < rlist.Len(); idx++ {
@@ -255,15 +320,11 @@ func EvalOnEach(list any, expr, ret string) (any, error) {
 				got:  item.Kind(),
 			}
 		}
-		ev, err := gval.Full(Full()...).NewEvaluable(expr)
-		if err != nil {
-			return nil, fmt.Errorf("parsing expression %q: %w", expr, err)
-		}
-		isTrue, err := ev.EvalBool(context.TODO(), item.Interface())
+		isTrue, err := ev.EvalBool(ctx, item.Interface())
 		if err != nil {
 			return nil, fmt.Errorf("evaluating expr %q: %w", expr, err)
 		}
-		if !isTrue {
+		if !isTrue && !Checking(ctx) {
 			continue
 		}
 		fval := item.FieldByName(ret)
@@ -469,6 +530,90 @@ func AccessSpreadOp(x, y any) (any, error) {
 	}
 }
 
+// variable selects the value at path, such as `Pods[0].Name`, like the
+// default selector of gval does. A key that cannot be selected fails with an
+// ErrUnknownParameter.
+func variable(path gval.Evaluables) gval.Evaluable {
+	return func(c context.Context, v any) (any, error) {
+		keys, err := path.EvalStrings(c, v)
+		if err != nil {
+			return nil, err
+		}
+		for idx, key := range keys {
+			switch o := v.(type) {
+			case gval.Selector:
+				v, err = o.SelectGVal(c, key)
+				if err != nil {
+					return nil, fmt.Errorf("failed to select '%s' on %T: %w", key, o, err)
+				}
+				continue
+			case map[any]any:
+				v = o[key]
+				continue
+			case map[string]any:
+				v = o[key]
+				continue
+			}
+			val, ok := selectKey(v, key)
+			if !ok {
+				rv := reflect.ValueOf(v)
+				if rv.Kind() == reflect.Ptr {
+					rv = rv.Elem()
+				}
+				return nil, ErrUnknownParameter{
+					Path: keys[:idx+1],
+					On:   rv.Kind(),
+				}
+			}
+			v = val
+		}
+		return v, nil
+	}
+}
+
+// selectKey selects the field, map entry, list element or method named key
+// on v, reporting whether there is one.
+func selectKey(v any, key string) (any, bool) {
+	val := reflect.ValueOf(v)
+	elem := val
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+
+	var sel reflect.Value
+	switch elem.Kind() {
+	case reflect.Map:
+		var k reflect.Value
+		switch kt := elem.Type().Key(); kt.Kind() {
+		case reflect.String:
+			k = reflect.ValueOf(key).Convert(kt)
+		case reflect.Int:
+			if i, err := strconv.Atoi(key); err == nil {
+				k = reflect.ValueOf(i).Convert(kt)
+			}
+		}
+		if k.IsValid() {
+			sel = elem.MapIndex(k)
+		}
+	case reflect.Slice, reflect.Array:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < elem.Len() {
+			sel = elem.Index(i)
+		}
+	case reflect.Struct:
+		sel = elem.FieldByName(key)
+	}
+	if sel.Kind() == reflect.Ptr && !sel.IsNil() {
+		sel = sel.Elem()
+	}
+	if !sel.IsValid() && val.IsValid() {
+		sel = val.MethodByName(key)
+	}
+	if !sel.IsValid() || !sel.CanInterface() {
+		return nil, false
+	}
+	return sel.Interface(), true
+}
+
 func pipeOp(c context.Context, p *gval.Parser, pre gval.Evaluable) (gval.Evaluable, error) {
 	post, err := p.ParseExpression(c)
 	if err != nil {
@@ -1,11 +1,13 @@
 package expr_test
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	"github.com/accuknox/rinc/internal/expr"
 
+	"github.com/PaesslerAG/gval"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -523,3 +525,57 @@ func TestSum(t *testing.T) {
 		a.Equal(i.wantInt, got, msg...)
 	}
 }
+
+func TestSample(t *testing.T) {
+	a := assert.New(t)
+	type item struct {
+		Name  string
+		Items []*item
+	}
+	type metrics struct {
+		Items  []item
+		Labels map[string]int
+	}
+	s, ok := expr.Sample(metrics{}).(metrics)
+	if !a.True(ok) || !a.Len(s.Items, 1) {
+		return
+	}
+	a.Len(s.Labels, 1)
+	// recursive types are only populated once.
+	a.Len(s.Items[0].Items, 1)
+	a.Empty(s.Items[0].Items[0].Items)
+
+	ctx := expr.WithCheck(context.TODO())
+	ev, err := gval.Full(expr.Full()...).NewEvaluable(`findOne(Items, "Name", "foo") -> "Name"`)
+	if a.NoError(err) {
+		_, err = ev(context.TODO(), s)
+		a.Error(err, "no item matches outside of type-checking")
+		_, err = ev(ctx, s)
+		a.NoError(err)
+	}
+}
+
+func TestCheckShortCircuit(t *testing.T) {
+	a := assert.New(t)
+	data := map[string]any{"X": 1}
+	inputs := map[string]any{
+		`X > 5 && Y.Z == 1`:   false,
+		`X < 5 || Y.Z == 1`:   true,
+		`X ?? Y.Z`:            1,
+		`X > 5 ? Y.Z : "foo"`: "foo",
+		`X < 5 ? "foo" : Y.Z`: "foo",
+	}
+	for input, want := range inputs {
+		ev, err := expr.Language().NewEvaluable(input)
+		if !a.NoError(err, "INPUT=%s", input) {
+			continue
+		}
+		got, err := ev(context.TODO(), data)
+		if a.NoError(err, "INPUT=%s", input) {
+			a.Equal(want, got, "INPUT=%s", input)
+		}
+		// the skipped operand is evaluated while type-checking.
+		_, err = ev(expr.WithCheck(context.TODO()), data)
+		a.Error(err, "INPUT=%s", input)
+	}
+}
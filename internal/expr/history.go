@@ -0,0 +1,193 @@
+package expr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// History provides access to the earlier snapshots of the metrics being
+// evaluated, for use by the history functions: `prev`, `delta` and `rate`.
+type History interface {
+	// Current returns the snapshot being evaluated.
+	Current() any
+	// Timestamp returns the timestamp of the snapshot being evaluated.
+	Timestamp() time.Time
+	// Previous returns the latest snapshot taken before the current one,
+	// along with its timestamp. It returns a nil snapshot if there is none.
+	Previous(ctx context.Context) (any, time.Time, error)
+	// At returns the latest snapshot taken at or before t, along with its
+	// timestamp. It returns a nil snapshot if there is none.
+	At(ctx context.Context, t time.Time) (any, time.Time, error)
+}
+
+// Snapshot is a snapshot of metrics taken at a point in time.
+type Snapshot struct {
+	Timestamp time.Time
+	Data      any
+}
+
+// Snapshots is an in-memory History. The snapshots must be sorted by their
+// timestamp; the last one is the current snapshot.
+type Snapshots []Snapshot
+
+var _ History = Snapshots(nil)
+
+// Current satisfies the History interface.
+func (s Snapshots) Current() any {
+	if len(s) == 0 {
+		return nil
+	}
+	return s[len(s)-1].Data
+}
+
+// Timestamp satisfies the History interface.
+func (s Snapshots) Timestamp() time.Time {
+	if len(s) == 0 {
+		return time.Time{}
+	}
+	return s[len(s)-1].Timestamp
+}
+
+// Previous satisfies the History interface.
+func (s Snapshots) Previous(ctx context.Context) (any, time.Time, error) {
+	if len(s) < 2 {
+		return nil, time.Time{}, nil
+	}
+	prev := s[len(s)-2]
+	return prev.Data, prev.Timestamp, nil
+}
+
+// At satisfies the History interface.
+func (s Snapshots) At(ctx context.Context, t time.Time) (any, time.Time, error) {
+	for idx := len(s) - 1; idx >= 0; idx-- {
+		if !s[idx].Timestamp.After(t) {
+			return s[idx].Data, s[idx].Timestamp, nil
+		}
+	}
+	return nil, time.Time{}, nil
+}
+
+// ErrNoHistory is returned by the history functions when the expression is
+// evaluated without a History.
+var ErrNoHistory = errors.New("history is not available")
+
+type historyKey struct{}
+
+// WithHistory returns a copy of ctx carrying the provided history. Use the
+// returned context to evaluate expressions using the history functions.
+func WithHistory(ctx context.Context, h History) context.Context {
+	return context.WithValue(ctx, historyKey{}, h)
+}
+
+func historyFrom(ctx context.Context) (History, error) {
+	h, ok := ctx.Value(historyKey{}).(History)
+	if !ok || h == nil {
+		return nil, ErrNoHistory
+	}
+	return h, nil
+}
+
+// Prev evaluates path against the previous snapshot. It returns nil if there
+// is no previous snapshot.
+func Prev(ctx context.Context, path string) (any, error) {
+	h, err := historyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, _, err := h.Previous(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading previous snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return nil, nil
+	}
+	return evalPath(ctx, path, snapshot)
+}
+
+// Delta returns the difference between the value of path in the current
+// snapshot and its value in the previous snapshot. If a window, such as
+// "24h", is provided, the value is instead compared with the latest snapshot
+// taken at least that long ago. It returns 0 if there is no such snapshot.
+func Delta(ctx context.Context, path string, window ...string) (float64, error) {
+	d, _, err := delta(ctx, path, window...)
+	return d, err
+}
+
+// Rate returns the per-second rate of change of the value of path between
+// the latest snapshot taken at least window ago, such as "1h", and the
+// current snapshot. It returns 0 if there is no such snapshot.
+func Rate(ctx context.Context, path string, window string) (float64, error) {
+	d, elapsed, err := delta(ctx, path, window)
+	if err != nil || elapsed <= 0 {
+		return 0, err
+	}
+	return d / elapsed.Seconds(), nil
+}
+
+// delta returns the difference between the value of path in the current
+// snapshot and in the earlier snapshot selected by the optional window, along
+// with the time elapsed between both snapshots.
+func delta(ctx context.Context, path string, window ...string) (float64, time.Duration, error) {
+	if len(window) > 1 {
+		return 0, 0, fmt.Errorf("want at most one window, got %d", len(window))
+	}
+	h, err := historyFrom(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var snapshot any
+	var at time.Time
+	if len(window) == 0 {
+		snapshot, at, err = h.Previous(ctx)
+	} else {
+		d, perr := Duration(window[0])
+		if perr != nil {
+			return 0, 0, fmt.Errorf("parsing window: %w", perr)
+		}
+		snapshot, at, err = h.At(ctx, h.Timestamp().Add(-d))
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("loading earlier snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return 0, 0, nil
+	}
+
+	was, err := evalNumber(ctx, path, snapshot)
+	if err != nil {
+		return 0, 0, err
+	}
+	is, err := evalNumber(ctx, path, h.Current())
+	if err != nil {
+		return 0, 0, err
+	}
+	return is - was, h.Timestamp().Sub(at), nil
+}
+
+func evalPath(ctx context.Context, path string, snapshot any) (any, error) {
+	v, err := Evaluate(ctx, path, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating path %q: %w", path, err)
+	}
+	return v, nil
+}
+
+func evalNumber(ctx context.Context, path string, snapshot any) (float64, error) {
+	v, err := evalPath(ctx, path, snapshot)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := toFloat(v)
+	if !ok {
+		return 0, ErrUnexpectedKind[string]{
+			arg:  fmt.Sprintf("%s(path)", path),
+			want: "number",
+			got:  reflect.ValueOf(v).Kind().String(),
+		}
+	}
+	return n, nil
+}
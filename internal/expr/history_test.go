@@ -0,0 +1,57 @@
+package expr_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/expr"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryFunctions(t *testing.T) {
+	a := assert.New(t)
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := expr.Snapshots{
+		{Timestamp: t0, Data: testStruct{X: 10}},
+		{Timestamp: t0.Add(time.Hour), Data: testStruct{X: 40}},
+		{Timestamp: t0.Add(2 * time.Hour), Data: testStruct{X: 100}},
+	}
+	ctx := expr.WithHistory(context.TODO(), h)
+
+	inputs := map[string]any{
+		`prev("X")`:              40,
+		`delta("X")`:             60.0,
+		`delta("X", "2h")`:       90.0,
+		`delta("X", "90m")`:      90.0,
+		`delta("X", "24h")`:      0.0,
+		`delta("X", "0.05d")`:    90.0,
+		`rate("X", "7d")`:        0.0,
+		`rate("X", "1h") * 3600`: 60.0,
+		`rate("X", "24h")`:       0.0,
+		`prev("X") < X`:          true,
+	}
+	for input, want := range inputs {
+		got, err := gval.Full(expr.Full()...).EvaluateWithContext(ctx, input, h.Current())
+		if a.NoErrorf(err, "INPUT=%s", input) {
+			a.Equalf(want, got, "INPUT=%s", input)
+		}
+	}
+
+	// without a previous snapshot.
+	ctx = expr.WithHistory(context.TODO(), h[:1])
+	got, err := gval.Full(expr.Full()...).EvaluateWithContext(ctx, `prev("X")`, h[0].Data)
+	if a.NoError(err) {
+		a.Nil(got)
+	}
+
+	// without history.
+	_, err = gval.Full(expr.Full()...).EvaluateWithContext(context.TODO(), `delta("X")`, h[0].Data)
+	a.ErrorIs(err, expr.ErrNoHistory)
+
+	// non-numeric path.
+	_, err = gval.Full(expr.Full()...).EvaluateWithContext(ctx, `delta("Foo", "0s")`, h[0].Data)
+	a.Error(err)
+}
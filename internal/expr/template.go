@@ -0,0 +1,131 @@
+package expr
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// templateBuiltins are the functions predefined by text/template. The
+// functions of the expression language sharing their names, such as `len`,
+// are left out of TemplateFuncs so as not to change their behavior.
+var templateBuiltins = []string{
+	"and", "call", "html", "index", "js", "len", "not", "or", "print",
+	"printf", "println", "slice", "urlquery", "eq", "ge", "gt", "le", "lt",
+	"ne",
+}
+
+// TemplateFuncs returns the functions available to message templates: the
+// functions of the expression language, except those overriding the
+// builtins of text/template, along with the functions below. Functions
+// taking a context receive ctx.
+//
+//   - eval(expr, data), which evaluates an expression against data, e.g.,
+//     `{{ eval "len(Pods)" . }}`.
+//   - format(v), which formats v like an expression embedded between
+//     backticks: lists are joined with commas.
+func TemplateFuncs(ctx context.Context) template.FuncMap {
+	funcs := template.FuncMap{}
+	for name := range templateFunctions() {
+		funcs[name], _ = TemplateFunc(ctx, name)
+	}
+	return funcs
+}
+
+// TemplateFunc returns the function of TemplateFuncs with the given name,
+// bound to ctx. It reports whether there is such a function.
+func TemplateFunc(ctx context.Context, name string) (any, bool) {
+	fn, ok := templateFunctions()[name]
+	if !ok {
+		return nil, false
+	}
+	if fn.bound == nil {
+		return fn.fn.Interface(), true
+	}
+	c := reflect.ValueOf(&ctx).Elem()
+	return reflect.MakeFunc(fn.bound, func(args []reflect.Value) []reflect.Value {
+		args = append([]reflect.Value{c}, args...)
+		if fn.bound.IsVariadic() {
+			return fn.fn.CallSlice(args)
+		}
+		return fn.fn.Call(args)
+	}).Interface(), true
+}
+
+// Format formats v for a message: the elements of lists are joined with
+// commas, other values are formatted with the default format.
+func Format(v any) string {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return fmt.Sprintf("%v", v)
+	}
+	var b strings.Builder
+	for idx := 0; idx < val.Len(); idx++ {
+		if idx != 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%v", val.Index(idx).Interface())
+	}
+	return b.String()
+}
+
+// templateFunc is a function of TemplateFuncs.
+type templateFunc struct {
+	fn reflect.Value
+	// bound is the type of the function without its first parameter when
+	// it is a context.Context, and nil otherwise.
+	bound reflect.Type
+}
+
+var (
+	templateFunctionsOnce sync.Once
+	templateFunctionsMap  map[string]templateFunc
+)
+
+// templateFunctions returns the functions of TemplateFuncs by name. They
+// are looked up once.
+func templateFunctions() map[string]templateFunc {
+	templateFunctionsOnce.Do(func() {
+		fns := Functions()
+		fns["eval"] = func(ctx context.Context, expr string, data any) (any, error) {
+			v, err := Evaluate(ctx, expr, data)
+			if err != nil {
+				return nil, fmt.Errorf("evaluating expr %q: %w", expr, err)
+			}
+			return v, nil
+		}
+		fns["format"] = Format
+
+		templateFunctionsMap = make(map[string]templateFunc, len(fns))
+		for name, fn := range fns {
+			if slices.Contains(templateBuiltins, name) {
+				continue
+			}
+			v := reflect.ValueOf(fn)
+			t := v.Type()
+			if t.NumIn() == 0 || t.In(0) != contextType {
+				templateFunctionsMap[name] = templateFunc{fn: v}
+				continue
+			}
+			in := make([]reflect.Type, t.NumIn()-1)
+			for idx := range in {
+				in[idx] = t.In(idx + 1)
+			}
+			out := make([]reflect.Type, t.NumOut())
+			for idx := range out {
+				out[idx] = t.Out(idx)
+			}
+			templateFunctionsMap[name] = templateFunc{
+				fn:    v,
+				bound: reflect.FuncOf(in, out, t.IsVariadic()),
+			}
+		}
+	})
+	return templateFunctionsMap
+}
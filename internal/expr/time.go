@@ -0,0 +1,144 @@
+package expr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration parses a duration string, such as "90m" or "2h45m". Besides the
+// units accepted by time.ParseDuration, it accepts days, e.g., "7d".
+func Duration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing duration %q: %w", s, err)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("parsing duration %q: %w", s, err)
+	}
+	return d, nil
+}
+
+// Now returns the time the metrics being evaluated were scraped at, if they
+// are evaluated with a History, and the current time otherwise. Using the
+// scrape time keeps alerts reproducible when evaluating earlier snapshots.
+func Now(ctx context.Context) time.Time {
+	if h, err := historyFrom(ctx); err == nil && !h.Timestamp().IsZero() {
+		return h.Timestamp()
+	}
+	return time.Now()
+}
+
+// Since returns the time elapsed between t and Now.
+func Since(ctx context.Context, t time.Time) time.Duration {
+	return Now(ctx).Sub(t)
+}
+
+// Days returns the duration d as a floating point number of days. d is
+// either a time.Duration or a number of nanoseconds.
+func Days(d any) (float64, error) {
+	return inUnit(d, 24*time.Hour)
+}
+
+// Hours returns the duration d as a floating point number of hours, see Days.
+func Hours(d any) (float64, error) {
+	return inUnit(d, time.Hour)
+}
+
+// Minutes returns the duration d as a floating point number of minutes, see
+// Days.
+func Minutes(d any) (float64, error) {
+	return inUnit(d, time.Minute)
+}
+
+// Seconds returns the duration d as a floating point number of seconds, see
+// Days.
+func Seconds(d any) (float64, error) {
+	return inUnit(d, time.Second)
+}
+
+// HumanDuration formats the duration d, see Days, rounded to its two most
+// significant units, e.g., "3d 4h", "2h 5m", "5m 30s" or "42s".
+func HumanDuration(d any) (string, error) {
+	dur, err := toDuration(d)
+	if err != nil {
+		return "", err
+	}
+	sign := ""
+	if dur < 0 {
+		sign, dur = "-", -dur
+	}
+	dur = dur.Round(time.Second)
+	days := dur / (24 * time.Hour)
+	hours := (dur % (24 * time.Hour)) / time.Hour
+	minutes := (dur % time.Hour) / time.Minute
+	seconds := (dur % time.Minute) / time.Second
+	switch {
+	case days != 0:
+		return fmt.Sprintf("%s%dd %dh", sign, days, hours), nil
+	case hours != 0:
+		return fmt.Sprintf("%s%dh %dm", sign, hours, minutes), nil
+	case minutes != 0:
+		return fmt.Sprintf("%s%dm %ds", sign, minutes, seconds), nil
+	default:
+		return fmt.Sprintf("%s%ds", sign, seconds), nil
+	}
+}
+
+// HumanBytes formats a number of bytes using binary units, e.g., "512 B",
+// "1.5 KiB" or "20.0 GiB".
+func HumanBytes(n any) (string, error) {
+	b, ok := toFloat(n)
+	if !ok {
+		return "", ErrUnexpectedKind[string]{
+			arg:  0,
+			want: "number",
+			got:  reflect.ValueOf(n).Kind().String(),
+		}
+	}
+	if math.Abs(b) < 1024 {
+		return fmt.Sprintf("%.0f B", b), nil
+	}
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB"}
+	var unit string
+	for _, unit = range units {
+		b /= 1024
+		if math.Abs(b) < 1024 {
+			break
+		}
+	}
+	return fmt.Sprintf("%.1f %s", b, unit), nil
+}
+
+func inUnit(d any, unit time.Duration) (float64, error) {
+	dur, err := toDuration(d)
+	if err != nil {
+		return 0, err
+	}
+	return float64(dur) / float64(unit), nil
+}
+
+// toDuration converts d to a time.Duration. Numbers, such as the result of
+// arithmetic on durations, are taken as nanoseconds.
+func toDuration(d any) (time.Duration, error) {
+	if dur, ok := d.(time.Duration); ok {
+		return dur, nil
+	}
+	n, ok := toFloat(d)
+	if !ok {
+		return 0, ErrUnexpectedKind[string]{
+			arg:  0,
+			want: "duration|number",
+			got:  reflect.ValueOf(d).Kind().String(),
+		}
+	}
+	return time.Duration(n), nil
+}
@@ -0,0 +1,62 @@
+package expr_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/expr"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTime(t *testing.T) {
+	a := assert.New(t)
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	data := struct {
+		Age       time.Duration
+		StartTime time.Time
+		Used      float64
+	}{
+		Age:       26 * time.Hour,
+		StartTime: t0.Add(-90 * time.Minute),
+		Used:      1.5 * 1024 * 1024 * 1024,
+	}
+	ctx := expr.WithHistory(context.TODO(), expr.Snapshots{{Timestamp: t0, Data: data}})
+	inputs := map[string]any{
+		`Age > duration("1d")`:              true,
+		`Age > duration("2d")`:              false,
+		`Age < duration("30h")`:             true,
+		`hours(Age)`:                        26.0,
+		`days(Age * 2)`:                     26.0 / 12,
+		`minutes(since(StartTime))`:         90.0,
+		`since(StartTime) > duration("1h")`: true,
+		`now() == StartTime`:                false,
+		`seconds(duration("1m30s"))`:        90.0,
+		`humanDuration(Age)`:                "1d 2h",
+		`humanDuration(since(StartTime))`:   "1h 30m",
+		`humanDuration(duration("42s"))`:    "42s",
+		`humanBytes(Used)`:                  "1.5 GiB",
+		`humanBytes(512)`:                   "512 B",
+	}
+	for input, want := range inputs {
+		got, err := gval.Full(expr.Full()...).EvaluateWithContext(ctx, input, data)
+		if a.NoErrorf(err, "INPUT=%s", input) {
+			a.Equalf(want, got, "INPUT=%s", input)
+		}
+	}
+
+	for _, input := range []string{
+		`duration("2 hours")`,
+		`hours(StartTime)`,
+		`humanBytes("1GB")`,
+	} {
+		_, err := gval.Full(expr.Full()...).EvaluateWithContext(ctx, input, data)
+		a.Errorf(err, "INPUT=%s", input)
+	}
+
+	// without a history, now is the current time.
+	a.WithinDuration(time.Now(), expr.Now(context.TODO()), time.Minute)
+	a.Equal(t0, expr.Now(ctx))
+}
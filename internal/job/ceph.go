@@ -11,7 +11,7 @@ import (
 
 // GenerateCEPHReport generates ceph status report.
 func (j Job) GenerateCEPHReport(ctx context.Context, now time.Time) error {
-	r := ceph.NewReporter(j.conf.Ceph, j.kubeClient, j.mongo)
+	r := ceph.NewReporter(j.conf.Ceph, j.kubeClient, j.store)
 	err := r.Report(ctx, now)
 	if err != nil {
 		slog.LogAttrs(
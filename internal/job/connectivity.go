@@ -11,7 +11,7 @@ import (
 
 // GenerateConnectivityReport generates connectivity status report.
 func (j Job) GenerateConnectivityReport(ctx context.Context, now time.Time) error {
-	r := connectivity.NewReporter(j.conf.Connectivity, j.kubeClient, j.mongo)
+	r := connectivity.NewReporter(j.conf.Connectivity, j.kubeClient, j.store)
 	err := r.Report(ctx, now)
 	if err != nil {
 		slog.LogAttrs(
@@ -0,0 +1,167 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RunDaemon keeps running and generates the report of each enabled reporter
+// on its own schedule, until an interrupt or SIGTERM is received. Reporters
+// that are due at the same time share the same report timestamp.
+//
+// On termination, the reporters that are still running are given
+// `terminationGracePeriod` to finish before they are cancelled.
+func (j Job) RunDaemon(ctx context.Context) error {
+	var tasks []task
+	for _, t := range j.tasks() {
+		if t.skip || t.schedule == nil {
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+	if len(tasks) == 0 {
+		return fmt.Errorf("no reporter is enabled")
+	}
+
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// in-flight reporters must not be cancelled as soon as an interrupt is
+	// received.
+	runCtx, cancelRuns := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRuns()
+
+	s := newScheduler(tasks, time.Now())
+	for _, t := range tasks {
+		slog.LogAttrs(
+			ctx,
+			slog.LevelInfo,
+			"scheduled reporter",
+			slog.String("reporter", t.name),
+			slog.Time("next", s.next[t.name]),
+		)
+	}
+
+	var wg sync.WaitGroup
+Loop:
+	for {
+		at := s.upcoming()
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-sigCtx.Done():
+			timer.Stop()
+			break Loop
+		case <-timer.C:
+		}
+
+		due := s.due(at)
+		if len(due) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.done(due)
+			// errors are logged and recorded in the run manifest.
+			_, _ = j.generate(runCtx, at, due)
+		}()
+	}
+
+	slog.Log(ctx, slog.LevelInfo, "shutting down")
+
+	// graceful termination
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	if j.conf.TerminationGracePeriod == 0 {
+		<-finished
+		return nil
+	}
+	select {
+	case <-finished:
+	case <-time.After(j.conf.TerminationGracePeriod):
+		slog.Log(ctx, slog.LevelError, "forcefully shutting down")
+		cancelRuns()
+		<-finished
+	}
+	return nil
+}
+
+// scheduler keeps track of the next activation time of each task, and of
+// the tasks that are currently running.
+type scheduler struct {
+	mu      sync.Mutex
+	tasks   []task
+	next    map[string]time.Time
+	running map[string]bool
+}
+
+func newScheduler(tasks []task, now time.Time) *scheduler {
+	s := &scheduler{
+		tasks:   tasks,
+		next:    make(map[string]time.Time, len(tasks)),
+		running: make(map[string]bool, len(tasks)),
+	}
+	for _, t := range tasks {
+		s.next[t.name] = t.schedule.Next(now)
+	}
+	return s
+}
+
+// upcoming returns the earliest activation time among all the tasks.
+func (s *scheduler) upcoming() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var earliest time.Time
+	for _, t := range s.tasks {
+		next := s.next[t.name]
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+	return earliest
+}
+
+// due returns the tasks that are due at `at`, and marks them as running.
+// The next activation time of every due task is advanced, even if it is
+// skipped because its previous run has not finished yet.
+func (s *scheduler) due(at time.Time) []task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []task
+	for _, t := range s.tasks {
+		if s.next[t.name].After(at) {
+			continue
+		}
+		s.next[t.name] = t.schedule.Next(at)
+		if s.running[t.name] {
+			slog.LogAttrs(
+				context.Background(),
+				slog.LevelWarn,
+				"skipping reporter, previous run is still in progress",
+				slog.String("reporter", t.name),
+			)
+			continue
+		}
+		s.running[t.name] = true
+		due = append(due, t)
+	}
+	return due
+}
+
+// done marks the tasks as no longer running.
+func (s *scheduler) done(tasks []task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range tasks {
+		s.running[t.name] = false
+	}
+}
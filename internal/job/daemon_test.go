@@ -0,0 +1,42 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	a := assert.New(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tasks := []task{
+		{name: "foo", schedule: cron.Every(time.Minute * 5)},
+		{name: "bar", schedule: cron.Every(time.Minute * 10)},
+	}
+	s := newScheduler(tasks, now)
+
+	at := s.upcoming()
+	a.Equal(now.Add(time.Minute*5), at)
+	due := s.due(at)
+	if a.Len(due, 1) {
+		a.Equal("foo", due[0].name)
+	}
+
+	// foo is still running when both are due.
+	at = s.upcoming()
+	a.Equal(now.Add(time.Minute*10), at)
+	due = s.due(at)
+	if a.Len(due, 1) {
+		a.Equal("bar", due[0].name)
+	}
+
+	s.done(tasks)
+	at = s.upcoming()
+	a.Equal(now.Add(time.Minute*15), at)
+	due = s.due(at)
+	if a.Len(due, 1) {
+		a.Equal("foo", due[0].name)
+	}
+}
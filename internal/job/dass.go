@@ -12,7 +12,7 @@ import (
 // GenerateDaSSReport generates a status report for deployments and
 // statefulsets.
 func (j Job) GenerateDaSSReport(ctx context.Context, now time.Time) error {
-	r := dass.NewReporter(j.conf.DaSS, j.kubeClient, j.mongo)
+	r := dass.NewReporter(j.conf.DaSS, j.kubeClient, j.store)
 	err := r.Report(ctx, now)
 	if err != nil {
 		slog.LogAttrs(
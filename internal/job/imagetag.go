@@ -12,7 +12,7 @@ import (
 // GenerateImageTagReport generates an image tag report for deployments and
 // statefulsets.
 func (j Job) GenerateImageTagReport(ctx context.Context, now time.Time) error {
-	r := imagetag.NewReporter(j.conf.ImageTag, j.kubeClient, j.mongo)
+	r := imagetag.NewReporter(j.conf.ImageTag, j.kubeClient, j.store)
 	err := r.Report(ctx, now)
 	if err != nil {
 		slog.LogAttrs(
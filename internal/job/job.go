@@ -2,14 +2,19 @@ package job
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/notify"
+	"github.com/accuknox/rinc/internal/store"
 	"github.com/accuknox/rinc/internal/util"
 
-	"go.mongodb.org/mongo-driver/v2/mongo"
+	"github.com/robfig/cron/v3"
 	"k8s.io/client-go/kubernetes"
 	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
 )
@@ -20,138 +25,271 @@ type Job struct {
 	conf          conf.C
 	kubeClient    *kubernetes.Clientset
 	metricsClient *metrics.Clientset
-	mongo         *mongo.Client
+	store         store.Store
+	notifier      *notify.Dispatcher
 }
 
 // New returns a new reporting Job object.
-func New(c conf.C, k *kubernetes.Clientset, m *metrics.Clientset, mongo *mongo.Client) Job {
+func New(c conf.C, k *kubernetes.Clientset, m *metrics.Clientset, s store.Store) Job {
 	slog.SetDefault(util.NewLogger(c.Log))
 	return Job{
 		conf:          c,
 		kubeClient:    k,
 		metricsClient: m,
-		mongo:         mongo,
+		store:         s,
+		notifier:      notify.New(c.Notifications),
 	}
 }
 
-// GenerateAll generates reports for all the configured tasks.
-func (j Job) GenerateAll(ctx context.Context) error {
-	now := time.Now().UTC().Round(time.Second)
+// Result contains the outcome of a single reporter run.
+type Result struct {
+	// Reporter is the name of the reporter, which is the same as the name
+	// of the collection it writes to.
+	Reporter string
+	Start    time.Time
+	End      time.Time
+	// Err is the error returned by the reporter, if any.
+	Err error
+	// Skipped is set when the reporter is disabled and did not run.
+	Skipped bool
+}
 
-	if j.conf.RabbitMQ.Enable {
-		err := j.GenerateRMQReport(ctx, now)
-		if err != nil {
-			slog.LogAttrs(
-				ctx,
-				slog.LevelError,
-				"generating RMQ report",
-				slog.String("error", err.Error()),
-			)
-			return fmt.Errorf("generating RMQ report: %w", err)
-		}
-	}
+// Duration returns the time taken by the reporter to run.
+func (r Result) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
 
-	if j.conf.LongJobs.Enable {
-		err := j.GenerateLongRunningJobsReport(ctx, now)
-		if err != nil {
-			slog.LogAttrs(
-				ctx,
-				slog.LevelError,
-				"generating long running jobs report",
-				slog.String("error", err.Error()),
-			)
-			return fmt.Errorf("generating long running jobs report: %w", err)
+// Summary contains the outcome of all the reporters that ran as part of a
+// single GenerateAll call.
+type Summary struct {
+	Timestamp time.Time
+	Results   []Result
+}
+
+// Ran returns the results of the reporters that were not skipped.
+func (s Summary) Ran() []Result {
+	var ran []Result
+	for _, r := range s.Results {
+		if !r.Skipped {
+			ran = append(ran, r)
 		}
 	}
+	return ran
+}
 
-	if j.conf.ImageTag.Enable {
-		err := j.GenerateImageTagReport(ctx, now)
-		if err != nil {
-			slog.LogAttrs(
-				ctx,
-				slog.LevelError,
-				"generating image tag report",
-				slog.String("error", err.Error()),
-			)
-			return fmt.Errorf("generating image tag report: %w", err)
+// Failed returns the results of the reporters that returned an error.
+func (s Summary) Failed() []Result {
+	var failed []Result
+	for _, r := range s.Results {
+		if r.Err != nil {
+			failed = append(failed, r)
 		}
 	}
+	return failed
+}
 
-	if j.conf.DaSS.Enable {
-		err := j.GenerateDaSSReport(ctx, now)
-		if err != nil {
-			slog.LogAttrs(
-				ctx,
-				slog.LevelError,
-				"generating DaSS report",
-				slog.String("error", err.Error()),
-			)
-			return fmt.Errorf("generating DaSS report: %w", err)
-		}
+// Err joins the errors returned by the failed reporters. It returns nil if
+// every reporter succeeded.
+func (s Summary) Err() error {
+	var errs []error
+	for _, r := range s.Failed() {
+		errs = append(errs, fmt.Errorf("%s: %w", r.Reporter, r.Err))
 	}
+	return errors.Join(errs...)
+}
 
-	if j.conf.Ceph.Enable {
-		err := j.GenerateCEPHReport(ctx, now)
-		if err != nil {
-			slog.LogAttrs(
-				ctx,
-				slog.LevelError,
-				"generating ceph status report",
-				slog.String("error", err.Error()),
-			)
-			return fmt.Errorf("generating ceph status report: %w", err)
-		}
+type task struct {
+	name string
+	run  func(ctx context.Context, now time.Time) error
+	// skip is set when the reporter is disabled.
+	skip bool
+	// schedule specifies when the reporter runs in daemon mode.
+	schedule cron.Schedule
+}
+
+// tasks returns the list of all the reporters.
+func (j Job) tasks() []task {
+	return []task{
+		{
+			name:     db.CollectionRabbitmq,
+			run:      j.GenerateRMQReport,
+			skip:     !j.conf.RabbitMQ.Enable,
+			schedule: j.schedule(j.conf.RabbitMQ.Schedule),
+		},
+		{
+			name:     db.CollectionLongJobs,
+			run:      j.GenerateLongRunningJobsReport,
+			skip:     !j.conf.LongJobs.Enable,
+			schedule: j.schedule(j.conf.LongJobs.Schedule),
+		},
+		{
+			name:     db.CollectionImageTag,
+			run:      j.GenerateImageTagReport,
+			skip:     !j.conf.ImageTag.Enable,
+			schedule: j.schedule(j.conf.ImageTag.Schedule),
+		},
+		{
+			name:     db.CollectionDass,
+			run:      j.GenerateDaSSReport,
+			skip:     !j.conf.DaSS.Enable,
+			schedule: j.schedule(j.conf.DaSS.Schedule),
+		},
+		{
+			name:     db.CollectionCeph,
+			run:      j.GenerateCEPHReport,
+			skip:     !j.conf.Ceph.Enable,
+			schedule: j.schedule(j.conf.Ceph.Schedule),
+		},
+		{
+			name:     db.CollectionPVUtilizaton,
+			run:      j.GeneratePVUtilizationReport,
+			skip:     !j.conf.PVUtilization.Enable,
+			schedule: j.schedule(j.conf.PVUtilization.Schedule),
+		},
+		{
+			name:     db.CollectionResourceUtilization,
+			run:      j.GenerateResourceUtilizationReport,
+			skip:     !j.conf.ResourceUtilization.Enable,
+			schedule: j.schedule(j.conf.ResourceUtilization.Schedule),
+		},
+		{
+			name:     db.CollectionConnectivity,
+			run:      j.GenerateConnectivityReport,
+			schedule: j.schedule(j.conf.Connectivity.Schedule),
+		},
+		{
+			name:     db.CollectionPodStatus,
+			run:      j.GeneratePodStatusReport,
+			skip:     !j.conf.PodStatus.Enable,
+			schedule: j.schedule(j.conf.PodStatus.Schedule),
+		},
 	}
+}
 
-	if j.conf.PVUtilization.Enable {
-		err := j.GeneratePVUtilizationReport(ctx, now)
-		if err != nil {
-			slog.LogAttrs(
-				ctx,
-				slog.LevelError,
-				"generating PV utilization report",
-				slog.String("error", err.Error()),
-			)
-			return fmt.Errorf("generating PV utilization report: %w", err)
-		}
+// schedule returns the provided reporter schedule if set, or the default
+// scraper schedule otherwise.
+func (j Job) schedule(s conf.Schedule) cron.Schedule {
+	if s.IsSet() {
+		return s.Schedule
 	}
+	return j.conf.Scraper.Schedule.Schedule
+}
 
-	if j.conf.ResourceUtilization.Enable {
-		err := j.GenerateResourceUtilizationReport(ctx, now)
-		if err != nil {
-			slog.LogAttrs(
-				ctx,
-				slog.LevelError,
-				"generating resource utilization report",
-				slog.String("error", err.Error()),
-			)
-			return fmt.Errorf("generating resource utilization report: %w", err)
-		}
+// GenerateAll generates reports for all the configured tasks. The reporters
+// run concurrently, and a failing reporter does not prevent the others from
+// writing their reports. The returned error joins the errors of all the
+// failed reporters.
+func (j Job) GenerateAll(ctx context.Context) (Summary, error) {
+	return j.generate(ctx, time.Now(), j.tasks())
+}
+
+// generate runs the provided tasks, and records their outcome in a run
+// manifest.
+func (j Job) generate(ctx context.Context, now time.Time, tasks []task) (Summary, error) {
+	now = now.UTC().Round(time.Second)
+	start := time.Now().UTC()
+	summary := run(ctx, now, tasks, j.conf.Scraper.Concurrency, j.conf.Scraper.Timeout)
+	end := time.Now().UTC()
+
+	j.notify(ctx, summary)
+	j.prune(ctx, now)
+
+	if err := j.writeManifest(ctx, summary, start, end); err != nil {
+		return summary, errors.Join(summary.Err(), err)
 	}
 
-	err := j.GenerateConnectivityReport(ctx, now)
+	err := summary.Err()
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"generating connectivity status report",
-			slog.String("error", err.Error()),
+			"one or more reporters failed",
+			slog.Int("failed", len(summary.Failed())),
+			slog.Int("total", len(summary.Ran())),
 		)
-		return fmt.Errorf("generating connectivity status report: %w", err)
+		return summary, err
 	}
+	slog.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"generated all reports",
+		slog.Int("total", len(summary.Ran())),
+	)
+	return summary, nil
+}
+
+// run executes the tasks with at most `concurrency` tasks running at a
+// time. Each task gets its own context that is cancelled after `timeout`,
+// unless the timeout is 0.
+func run(ctx context.Context, now time.Time, tasks []task, concurrency int, timeout time.Duration) Summary {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]Result, len(tasks))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for idx, t := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if t.skip {
+				results[idx] = Result{
+					Reporter: t.name,
+					Skipped:  true,
+				}
+				return
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ctx := ctx
+			if timeout != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			res := Result{
+				Reporter: t.name,
+				Start:    time.Now().UTC(),
+			}
+			res.Err = runTask(ctx, now, t)
+			res.End = time.Now().UTC()
+			results[idx] = res
 
-	if j.conf.PodStatus.Enable {
-		err := j.GeneratePodStatusReport(ctx, now)
-		if err != nil {
 			slog.LogAttrs(
 				ctx,
-				slog.LevelError,
-				"generating pod status report",
-				slog.String("error", err.Error()),
+				slog.LevelDebug,
+				"reporter finished",
+				slog.String("reporter", t.name),
+				slog.Duration("duration", res.Duration()),
+				slog.Bool("success", res.Err == nil),
 			)
-			return fmt.Errorf("generating pod status report: %w", err)
-		}
+		}()
 	}
+	wg.Wait()
 
-	return nil
+	return Summary{
+		Timestamp: now,
+		Results:   results,
+	}
+}
+
+// runTask runs the task and converts a panic into an error, so that a
+// misbehaving reporter cannot take the other reporters down with it.
+func runTask(ctx context.Context, now time.Time, t task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"reporter panicked",
+				slog.String("reporter", t.name),
+				slog.Any("panic", r),
+			)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return t.run(ctx, now)
 }
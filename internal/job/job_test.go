@@ -0,0 +1,118 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunIsolatesFailures(t *testing.T) {
+	a := assert.New(t)
+	ok := func(context.Context, time.Time) error { return nil }
+	tasks := []task{
+		{name: "foo", run: ok},
+		{name: "bar", run: func(context.Context, time.Time) error {
+			return errors.New("unreachable")
+		}},
+		{name: "blah", run: func(context.Context, time.Time) error {
+			panic("oops")
+		}},
+		{name: "baz", run: ok},
+	}
+	summary := run(context.TODO(), time.Now(), tasks, 2, 0)
+	if a.Len(summary.Results, 4) {
+		a.Equal("foo", summary.Results[0].Reporter)
+		a.NoError(summary.Results[0].Err)
+		a.Error(summary.Results[1].Err)
+		a.Error(summary.Results[2].Err)
+		a.NoError(summary.Results[3].Err)
+	}
+	a.Len(summary.Failed(), 2)
+	a.ErrorContains(summary.Err(), "bar: unreachable")
+}
+
+func TestRunConcurrencyLimit(t *testing.T) {
+	a := assert.New(t)
+	var running, peak atomic.Int32
+	fn := func(context.Context, time.Time) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 20)
+		return nil
+	}
+	var tasks []task
+	for range 8 {
+		tasks = append(tasks, task{name: "foo", run: fn})
+	}
+	summary := run(context.TODO(), time.Now(), tasks, 3, 0)
+	a.NoError(summary.Err())
+	a.LessOrEqual(peak.Load(), int32(3))
+}
+
+func TestRunTimeout(t *testing.T) {
+	a := assert.New(t)
+	tasks := []task{
+		{name: "slow", run: func(ctx context.Context, _ time.Time) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	}
+	summary := run(context.TODO(), time.Now(), tasks, 1, time.Millisecond*10)
+	a.ErrorIs(summary.Err(), context.DeadlineExceeded)
+}
+
+func TestRunSkipsDisabled(t *testing.T) {
+	a := assert.New(t)
+	tasks := []task{
+		{name: "foo", skip: true, run: func(context.Context, time.Time) error {
+			panic("must not run")
+		}},
+	}
+	summary := run(context.TODO(), time.Now(), tasks, 1, 0)
+	if a.Len(summary.Results, 1) {
+		a.True(summary.Results[0].Skipped)
+	}
+	a.Empty(summary.Ran())
+	a.NoError(summary.Err())
+}
+
+func TestManifest(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	summary := Summary{
+		Timestamp: now,
+		Results: []Result{
+			{Reporter: "foo", Start: now, End: now.Add(time.Second)},
+			{Reporter: "bar", Start: now, End: now, Err: errors.New("oops")},
+			{Reporter: "blah", Skipped: true},
+		},
+	}
+	doc := manifest(summary, now, now.Add(time.Second), map[string]any{"foo": 1})
+	a.Equal(db.RunStatusPartial, doc.Status)
+	a.Equal(time.Second, doc.Duration)
+	if a.Len(doc.Reporters, 3) {
+		a.Equal(db.RunStatusSuccess, doc.Reporters[0].Status)
+		a.Equal(1, doc.Reporters[0].DocumentID)
+		a.Equal(time.Second, doc.Reporters[0].Duration)
+		a.Equal(db.RunStatusFailed, doc.Reporters[1].Status)
+		a.Equal("oops", doc.Reporters[1].Error)
+		a.Nil(doc.Reporters[1].DocumentID)
+		a.Equal(db.RunStatusSkipped, doc.Reporters[2].Status)
+	}
+
+	summary.Results = summary.Results[1:]
+	doc = manifest(summary, now, now, nil)
+	a.Equal(db.RunStatusFailed, doc.Status)
+}
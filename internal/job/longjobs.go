@@ -12,7 +12,7 @@ import (
 // GenerateLongRunningJobsReport generates a report for Kubernetes jobs running
 // older than the given provided threshold.
 func (j Job) GenerateLongRunningJobsReport(ctx context.Context, now time.Time) error {
-	r := longjobs.NewReporter(j.conf.LongJobs, j.kubeClient, j.mongo)
+	r := longjobs.NewReporter(j.conf.LongJobs, j.kubeClient, j.store)
 	err := r.Report(ctx, now)
 	if err != nil {
 		slog.LogAttrs(
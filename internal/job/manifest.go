@@ -0,0 +1,114 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/store"
+)
+
+// writeManifest writes the run manifest document for the provided summary
+// to the `runs` collection.
+func (j Job) writeManifest(ctx context.Context, s Summary, start, end time.Time) error {
+	ids := make(map[string]any)
+	for _, r := range s.Ran() {
+		if r.Err != nil {
+			continue
+		}
+		id, err := j.documentID(ctx, r.Reporter, s.Timestamp)
+		if err != nil {
+			slog.LogAttrs(
+				ctx,
+				slog.LevelWarn,
+				"looking up report document id",
+				slog.String("reporter", r.Reporter),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		ids[r.Reporter] = id
+	}
+
+	doc := manifest(s, start, end, ids)
+	id, err := j.store.Insert(ctx, db.CollectionRuns, doc)
+	if err != nil {
+		slog.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"inserting run manifest into database",
+			slog.Time("timestamp", s.Timestamp),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("inserting run manifest into database: %w", err)
+	}
+	slog.LogAttrs(
+		ctx,
+		slog.LevelDebug,
+		"runs: inserted document into database",
+		slog.Any("insertedId", id),
+		slog.String("status", string(doc.Status)),
+	)
+	return nil
+}
+
+// documentID returns the ID of the document written to the collection at the
+// given timestamp.
+func (j Job) documentID(ctx context.Context, coll string, at time.Time) (any, error) {
+	id, err := j.store.DocumentID(ctx, coll, at)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("finding document in %q at %v: %w", coll, at, err)
+	}
+	return id, nil
+}
+
+// manifest builds the run manifest document from the summary. The ids map
+// holds the ID of the document written by each successful reporter.
+func manifest(s Summary, start, end time.Time, ids map[string]any) db.RunDocument {
+	doc := db.RunDocument{
+		Timestamp: s.Timestamp,
+		Start:     start,
+		End:       end,
+		Duration:  end.Sub(start),
+		Reporters: make([]db.ReporterRun, len(s.Results)),
+	}
+
+	var ran, failed int
+	for idx, r := range s.Results {
+		run := db.ReporterRun{Name: r.Reporter}
+		if r.Skipped {
+			run.Status = db.RunStatusSkipped
+			doc.Reporters[idx] = run
+			continue
+		}
+		ran++
+		run.Start = r.Start
+		run.End = r.End
+		run.Duration = r.Duration()
+		run.Status = db.RunStatusSuccess
+		if r.Err != nil {
+			failed++
+			run.Status = db.RunStatusFailed
+			run.Error = r.Err.Error()
+		} else {
+			run.DocumentID = ids[r.Reporter]
+		}
+		doc.Reporters[idx] = run
+	}
+
+	switch {
+	case failed == 0:
+		doc.Status = db.RunStatusSuccess
+	case failed == ran:
+		doc.Status = db.RunStatusFailed
+	default:
+		doc.Status = db.RunStatusPartial
+	}
+	return doc
+}
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/notify"
+)
+
+// notify delivers the alert states of every successful reporter in the
+// summary to the configured notifiers. Notifiers only receive the alerts that
+// started firing or got resolved in this run, unless they expect alerts that
+// keep firing to be delivered again. Delivery failures are logged and do not
+// fail the run.
+func (j Job) notify(ctx context.Context, s Summary) {
+	if !j.notifier.Enabled() {
+		return
+	}
+	for _, r := range s.Ran() {
+		if r.Err != nil {
+			continue
+		}
+		alerts, err := j.alertStates(ctx, r.Reporter, s.Timestamp)
+		if err != nil {
+			slog.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"fetching alerts for notification",
+				slog.String("reporter", r.Reporter),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		if len(alerts) == 0 {
+			continue
+		}
+		// errors are logged by the dispatcher.
+		_ = j.notifier.Dispatch(ctx, notify.Notification{
+			Timestamp: s.Timestamp,
+			From:      r.Reporter,
+			Alerts:    alerts,
+			Interval:  j.interval(r.Reporter, s.Timestamp),
+		})
+	}
+}
+
+// interval returns the time from the provided timestamp until the next run
+// of the reporter according to its schedule, which is also the schedule of
+// the CronJob when not running as a daemon. It returns 0 if the reporter has
+// no schedule.
+func (j Job) interval(reporter string, at time.Time) time.Duration {
+	for _, t := range j.tasks() {
+		if t.name == reporter && t.schedule != nil {
+			return t.schedule.Next(at).Sub(at)
+		}
+	}
+	return 0
+}
+
+// alertStates returns the states of the reporter's alerts that were firing or
+// got resolved at the given timestamp.
+func (j Job) alertStates(ctx context.Context, from string, at time.Time) ([]db.AlertStateDocument, error) {
+	states, err := j.store.AlertStates(ctx, from, at)
+	if err != nil {
+		return nil, fmt.Errorf("finding alert states from %q at %v: %w", from, at, err)
+	}
+	return states, nil
+}
@@ -11,7 +11,7 @@ import (
 
 // GeneratePodStatusReport generates pod status report.
 func (j Job) GeneratePodStatusReport(ctx context.Context, now time.Time) error {
-	r := pod.NewReporter(j.conf.PodStatus, j.kubeClient, j.mongo)
+	r := pod.NewReporter(j.conf.PodStatus, j.kubeClient, j.store)
 	err := r.Report(ctx, now)
 	if err != nil {
 		slog.LogAttrs(
@@ -11,7 +11,7 @@ import (
 
 // GeneratePVUtilizationReport generates a PV utilization status report.
 func (j Job) GeneratePVUtilizationReport(ctx context.Context, now time.Time) error {
-	r := pv.NewReporter(j.conf.PVUtilization, j.kubeClient, j.mongo)
+	r := pv.NewReporter(j.conf.PVUtilization, j.kubeClient, j.store)
 	err := r.Report(ctx, now)
 	if err != nil {
 		slog.LogAttrs(
@@ -11,7 +11,7 @@ import (
 
 // GenerateRMQReport generates a RabbitMQ status and metrics report.
 func (j Job) GenerateRMQReport(ctx context.Context, now time.Time) error {
-	r := rabbitmq.NewReporter(j.conf.RabbitMQ, j.kubeClient, j.mongo)
+	r := rabbitmq.NewReporter(j.conf.RabbitMQ, j.kubeClient, j.store)
 	err := r.Report(ctx, now)
 	if err != nil {
 		slog.LogAttrs(
@@ -15,7 +15,7 @@ func (j Job) GenerateResourceUtilizationReport(ctx context.Context, now time.Tim
 		ResourceUtilizationConfig: j.conf.ResourceUtilization,
 		KubeClient:                j.kubeClient,
 		MetricsClient:             j.metricsClient,
-		MongoClient:               j.mongo,
+		Store:                     j.store,
 	})
 	err := r.Report(ctx, now)
 	if err != nil {
@@ -0,0 +1,224 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+)
+
+// deleteBatchSize is the maximum number of timestamps matched by a single
+// delete when downsampling.
+const deleteBatchSize = 1000
+
+// prunedCollections are the collections pruned according to the retention
+// configuration. The alert states are not pruned with them, as they track
+// alerts across runs; only the resolved ones expire, along with the alerts.
+var prunedCollections = append(
+	slices.Clone(db.Collections),
+	db.CollectionAlerts,
+	db.CollectionRuns,
+)
+
+// prune deletes the documents older than the retention of their collection,
+// and downsamples the remaining runs. Errors are logged rather than returned,
+// since the reports of the current run have already been written.
+func (j Job) prune(ctx context.Context, now time.Time) {
+	if err := j.expire(ctx, now); err != nil {
+		slog.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"deleting expired reports",
+			slog.String("error", err.Error()),
+		)
+	}
+	if err := j.downsample(ctx, now); err != nil {
+		slog.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"downsampling reports",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// expire deletes the documents older than the retention of their
+// collection, and the alert states resolved before the retention of the
+// alerts.
+func (j Job) expire(ctx context.Context, now time.Time) error {
+	var errs []error
+	for _, coll := range prunedCollections {
+		retention := j.conf.Retention.RetentionFor(coll)
+		if retention == 0 {
+			continue
+		}
+		deleted, err := j.store.DeleteBefore(ctx, coll, now.Add(-retention))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if deleted != 0 {
+			slog.LogAttrs(
+				ctx,
+				slog.LevelInfo,
+				"deleted expired reports",
+				slog.String("collection", coll),
+				slog.Int64("deleted", deleted),
+			)
+		}
+	}
+
+	if retention := j.conf.Retention.RetentionFor(db.CollectionAlerts); retention != 0 {
+		deleted, err := j.store.DeleteResolvedAlertStates(ctx, now.Add(-retention))
+		if err != nil {
+			errs = append(errs, err)
+		} else if deleted != 0 {
+			slog.LogAttrs(
+				ctx,
+				slog.LevelInfo,
+				"deleted resolved alert states",
+				slog.Int64("deleted", deleted),
+			)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// downsample deletes the documents dropped by the downsampling rules. Each
+// reporter collection is downsampled on its own, as reporters may run on
+// different schedules, while the alerts and the run manifests follow the
+// reports they were written with.
+func (j Job) downsample(ctx context.Context, now time.Time) error {
+	rules := j.conf.Retention.Downsample
+	if len(rules) == 0 {
+		return nil
+	}
+
+	stamps := make(map[string][]time.Time, len(prunedCollections))
+	for _, coll := range prunedCollections {
+		found, err := j.store.Timestamps(ctx, coll, time.Time{}, now.Add(-rules[0].After))
+		if err != nil {
+			return err
+		}
+		stamps[coll] = found
+	}
+
+	var errs []error
+	for coll, drop := range downsampledCollections(stamps, now, rules) {
+		var deleted int64
+		for batch := range slices.Chunk(drop, deleteBatchSize) {
+			n, err := j.store.DeleteAt(ctx, coll, batch)
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+			deleted += n
+		}
+		if deleted != 0 {
+			slog.LogAttrs(
+				ctx,
+				slog.LevelInfo,
+				"downsampled reports",
+				slog.String("collection", coll),
+				slog.Int64("deleted", deleted),
+			)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// downsampledCollections returns the timestamps dropped from each
+// collection by the downsampling rules, given the timestamps of the
+// documents of each collection.
+//
+// The timestamps of every reporter collection are downsampled on their own.
+// A timestamp of the alerts and of the run manifests is kept if a reporter
+// collection kept it, and dropped if a reporter collection dropped it. The
+// remaining ones, which have no report, e.g., runs where every reporter
+// failed, are downsampled on their own.
+func downsampledCollections(
+	stamps map[string][]time.Time,
+	now time.Time,
+	rules []conf.Downsample,
+) map[string][]time.Time {
+	drops := make(map[string][]time.Time, len(stamps))
+	kept, dropped := make(map[int64]bool), make(map[int64]bool)
+	for _, coll := range db.Collections {
+		drop := downsampled(stamps[coll], now, rules)
+		collDropped := make(map[int64]bool, len(drop))
+		for _, t := range drop {
+			collDropped[t.UnixNano()] = true
+			dropped[t.UnixNano()] = true
+		}
+		for _, t := range stamps[coll] {
+			if !collDropped[t.UnixNano()] {
+				kept[t.UnixNano()] = true
+			}
+		}
+		if len(drop) != 0 {
+			drops[coll] = drop
+		}
+	}
+
+	for _, coll := range []string{db.CollectionAlerts, db.CollectionRuns} {
+		var drop, orphans []time.Time
+		for _, t := range stamps[coll] {
+			switch {
+			case kept[t.UnixNano()]:
+			case dropped[t.UnixNano()]:
+				drop = append(drop, t)
+			default:
+				orphans = append(orphans, t)
+			}
+		}
+		drop = append(drop, downsampled(orphans, now, rules)...)
+		if len(drop) != 0 {
+			drops[coll] = drop
+		}
+	}
+	return drops
+}
+
+// downsampled returns the timestamps of the runs dropped by the downsampling
+// rules. Each run is subject to the rule with the greatest `after` it is
+// older than, and only the earliest run of each interval of `every` is kept.
+//
+// Keeping the earliest run makes the outcome stable as runs age: a kept run
+// is the earliest of its interval, so it is never dropped by a later pass,
+// and it remains the earliest of the wider interval of the next rule as long
+// as the intervals are aligned, e.g., days and weeks.
+func downsampled(stamps []time.Time, now time.Time, rules []conf.Downsample) []time.Time {
+	type bucket struct {
+		rule  int
+		start int64
+	}
+	sorted := slices.Clone(stamps)
+	slices.SortFunc(sorted, func(a, b time.Time) int {
+		return a.Compare(b)
+	})
+
+	kept := make(map[bucket]bool)
+	var drop []time.Time
+	for _, t := range sorted {
+		rule := -1
+		for idx, r := range rules {
+			if now.Sub(t) > r.After {
+				rule = idx
+			}
+		}
+		if rule == -1 {
+			continue
+		}
+		b := bucket{rule: rule, start: t.Truncate(rules[rule].Every).UnixNano()}
+		if kept[b] {
+			drop = append(drop, t)
+			continue
+		}
+		kept[b] = true
+	}
+	return drop
+}
@@ -0,0 +1,119 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownsampled(t *testing.T) {
+	a := assert.New(t)
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	rules := []conf.Downsample{
+		{After: 7 * 24 * time.Hour, Every: 24 * time.Hour},
+		{After: 90 * 24 * time.Hour, Every: 7 * 24 * time.Hour},
+	}
+
+	// a run every 8 hours for 120 days
+	var stamps []time.Time
+	for at := now.Add(-120 * 24 * time.Hour); at.Before(now); at = at.Add(8 * time.Hour) {
+		stamps = append(stamps, at)
+	}
+	drop := downsampled(stamps, now, rules)
+	dropped := make(map[time.Time]bool, len(drop))
+	for _, t := range drop {
+		dropped[t] = true
+	}
+
+	days, weeks := make(map[time.Time]int), make(map[time.Time]int)
+	var recent int
+	for _, t := range stamps {
+		if dropped[t] {
+			continue
+		}
+		switch age := now.Sub(t); {
+		case age > rules[1].After:
+			weeks[t.Truncate(rules[1].Every)]++
+		case age > rules[0].After:
+			days[t.Truncate(rules[0].Every)]++
+		default:
+			recent++
+		}
+	}
+	a.Equal(7*3, recent, "runs younger than 7 days are kept")
+	for day, n := range days {
+		a.Equal(1, n, "DAY=%v", day)
+	}
+	for week, n := range weeks {
+		a.Equal(1, n, "WEEK=%v", week)
+	}
+	a.Len(days, 83)
+
+	// pruning again drops nothing
+	var kept []time.Time
+	for _, t := range stamps {
+		if !dropped[t] {
+			kept = append(kept, t)
+		}
+	}
+	a.Empty(downsampled(kept, now, rules))
+	a.Empty(downsampled(stamps, now, nil))
+}
+
+func TestDownsampledCollections(t *testing.T) {
+	a := assert.New(t)
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	rules := []conf.Downsample{{After: 7 * 24 * time.Hour, Every: 24 * time.Hour}}
+
+	// ceph runs hourly at :30 and pods every 5 minutes from :01, so that
+	// their timestamps never match.
+	stamps := make(map[string][]time.Time)
+	start := now.Add(-10 * 24 * time.Hour)
+	for at := start.Add(30 * time.Minute); at.Before(now); at = at.Add(time.Hour) {
+		stamps[db.CollectionCeph] = append(stamps[db.CollectionCeph], at)
+	}
+	for at := start.Add(time.Minute); at.Before(now); at = at.Add(5 * time.Minute) {
+		stamps[db.CollectionPodStatus] = append(stamps[db.CollectionPodStatus], at)
+	}
+	for _, coll := range []string{db.CollectionCeph, db.CollectionPodStatus} {
+		stamps[db.CollectionAlerts] = append(stamps[db.CollectionAlerts], stamps[coll]...)
+		stamps[db.CollectionRuns] = append(stamps[db.CollectionRuns], stamps[coll]...)
+	}
+	// runs where every reporter failed
+	failed := []time.Time{start.Add(2 * time.Minute), start.Add(3 * time.Minute)}
+	stamps[db.CollectionRuns] = append(stamps[db.CollectionRuns], failed...)
+
+	drops := downsampledCollections(stamps, now, rules)
+	kept := func(coll string) map[time.Time]int {
+		dropped := make(map[time.Time]bool)
+		for _, t := range drops[coll] {
+			dropped[t] = true
+		}
+		days := make(map[time.Time]int)
+		for _, t := range stamps[coll] {
+			if !dropped[t] && now.Sub(t) > rules[0].After {
+				days[t.Truncate(rules[0].Every)]++
+			}
+		}
+		return days
+	}
+
+	for _, coll := range []string{db.CollectionCeph, db.CollectionPodStatus} {
+		days := kept(coll)
+		a.Len(days, 3, "COLL=%s", coll)
+		for day, n := range days {
+			a.Equal(1, n, "COLL=%s DAY=%v", coll, day)
+		}
+	}
+	for day, n := range kept(db.CollectionAlerts) {
+		a.Equal(2, n, "an alert document per reporter is kept, DAY=%v", day)
+	}
+	a.NotContains(drops[db.CollectionRuns], start.Add(time.Minute), "run of the kept pod report")
+	a.NotContains(drops[db.CollectionRuns], failed[0], "earliest run without reports")
+	a.Contains(drops[db.CollectionRuns], failed[1], "later run without reports")
+	a.Empty(drops[db.CollectionDass])
+}
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+)
+
+// Alertmanager pushes alerts to the Prometheus Alertmanager
+// `/api/v2/alerts` endpoint.
+//
+// Alertmanager considers an alert resolved once its `endsAt` has passed, so
+// firing alerts are pushed on every notification with an `endsAt` past the
+// next run of their reporter. Resolved alerts are pushed once with their
+// `endsAt` set to the time they were resolved.
+type Alertmanager struct {
+	conf   conf.AlertmanagerNotifier
+	client *http.Client
+}
+
+// defaultTTLFactor is the number of intervals between the runs of a
+// reporter after which its firing alerts are resolved by Alertmanager, if
+// they are not pushed again. Tolerates a couple of delayed or failed runs.
+const defaultTTLFactor = 3
+
+// alertmanagerAlert is the postable alert schema of the Alertmanager v2 API.
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    string            `json:"startsAt,omitempty"`
+	EndsAt      string            `json:"endsAt,omitempty"`
+}
+
+// NewAlertmanager creates a new Alertmanager notifier.
+func NewAlertmanager(c conf.AlertmanagerNotifier) Alertmanager {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return Alertmanager{
+		conf:   c,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify satisfies the Notifier interface.
+func (a Alertmanager) Notify(ctx context.Context, n Notification) error {
+	url := strings.TrimSuffix(a.conf.URL, "/") + "/api/v2/alerts"
+	return postJSON(ctx, a.client, url, a.conf.Headers, a.alerts(n))
+}
+
+// alerts converts the alert states in the notification into Alertmanager
+// alerts. Labels are merged in the following order, later ones taking
+// precedence: the notifier's static labels, the alert's custom labels, and
+// the labels set by RINC, i.e., `reporter`, `severity`, `fingerprint` and,
+// for alerts evaluated with `forEach`, `resource`.
+// `alertname` defaults to the alert's ID, or the reporter name if the alert
+// has no ID, unless set by a custom label. Annotations hold the alert's
+// custom annotations along with `summary`, `expr` and `runbook_url`.
+func (a Alertmanager) alerts(n Notification) []alertmanagerAlert {
+	ttl := a.conf.TTL
+	if ttl == 0 {
+		ttl = defaultTTLFactor * n.Interval
+	}
+	alerts := make([]alertmanagerAlert, 0, len(n.Alerts))
+	for _, s := range n.Alerts {
+		labels := make(map[string]string, len(a.conf.Labels)+len(s.Labels)+4)
+		for k, v := range a.conf.Labels {
+			labels[k] = v
+		}
+		for k, v := range s.Labels {
+			labels[k] = v
+		}
+		if labels["alertname"] == "" {
+			labels["alertname"] = s.ID
+		}
+		if labels["alertname"] == "" {
+			labels["alertname"] = n.From
+		}
+		labels["reporter"] = n.From
+		labels["severity"] = string(s.Severity)
+		labels["fingerprint"] = s.Fingerprint
+		if s.Resource != "" {
+			labels["resource"] = s.Resource
+		}
+
+		annotations := make(map[string]string, len(s.Annotations)+3)
+		for k, v := range s.Annotations {
+			annotations[k] = v
+		}
+		annotations["summary"] = s.Message
+		annotations["expr"] = s.Expr
+		if s.RunbookURL != "" {
+			annotations["runbook_url"] = s.RunbookURL
+		}
+
+		alert := alertmanagerAlert{
+			Labels:      labels,
+			Annotations: annotations,
+			StartsAt:    s.FirstSeen.UTC().Format(time.RFC3339),
+		}
+		switch {
+		case s.State == db.AlertStateResolved:
+			alert.EndsAt = s.ResolvedAt.UTC().Format(time.RFC3339)
+		case ttl != 0:
+			alert.EndsAt = n.Timestamp.Add(ttl).UTC().Format(time.RFC3339)
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+)
+
+// Notification contains the states of the alerts of a single reporter at a
+// point in time: alerts that are firing, including the ones that just started
+// firing, and alerts that just got resolved.
+type Notification struct {
+	Timestamp time.Time               `json:"timestamp"`
+	From      string                  `json:"from"`
+	Alerts    []db.AlertStateDocument `json:"alerts"`
+	// Interval is the time until the next run of the reporter, or 0 if it
+	// is unknown.
+	Interval time.Duration `json:"-"`
+}
+
+// Notifier delivers notifications to an external channel.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+type route struct {
+	name     string
+	filter   conf.NotifierFilter
+	notifier Notifier
+	// repeat delivers alerts that keep firing on every notification,
+	// instead of only delivering state changes.
+	repeat bool
+}
+
+// Dispatcher delivers notifications to all the configured notifiers whose
+// filters match.
+type Dispatcher struct {
+	routes []route
+}
+
+// New creates a new dispatcher from the provided configuration.
+func New(c conf.Notifications) *Dispatcher {
+	d := new(Dispatcher)
+	for idx, n := range c.Webhooks {
+		d.routes = append(d.routes, route{
+			name:     fmt.Sprintf("webhooks[%d]", idx),
+			filter:   n.NotifierFilter,
+			notifier: NewWebhook(n),
+		})
+	}
+	for idx, n := range c.Slack {
+		d.routes = append(d.routes, route{
+			name:     fmt.Sprintf("slack[%d]", idx),
+			filter:   n.NotifierFilter,
+			notifier: NewSlack(n),
+		})
+	}
+	for idx, n := range c.SMTP {
+		d.routes = append(d.routes, route{
+			name:     fmt.Sprintf("smtp[%d]", idx),
+			filter:   n.NotifierFilter,
+			notifier: NewSMTP(n),
+		})
+	}
+	for idx, n := range c.Alertmanager {
+		d.routes = append(d.routes, route{
+			name:     fmt.Sprintf("alertmanager[%d]", idx),
+			filter:   n.NotifierFilter,
+			notifier: NewAlertmanager(n),
+			repeat:   true,
+		})
+	}
+	return d
+}
+
+// Enabled reports whether at least one notifier is configured.
+func (d *Dispatcher) Enabled() bool {
+	return d != nil && len(d.routes) != 0
+}
+
+// Dispatch delivers the notification to every notifier whose filter matches
+// at least one of its alerts. Only the matching alerts are delivered, and
+// alerts that keep firing are only delivered to notifiers that expect them,
+// such as Alertmanager. A failing notifier does not prevent delivery to the
+// others; the returned error joins the errors of all the failed notifiers.
+func (d *Dispatcher) Dispatch(ctx context.Context, n Notification) error {
+	if !d.Enabled() {
+		return nil
+	}
+	var errs []error
+	for _, r := range d.routes {
+		filtered := Filter(r.filter, n)
+		if !r.repeat {
+			filtered = Changed(filtered)
+		}
+		if len(filtered.Alerts) == 0 {
+			continue
+		}
+		err := r.notifier.Notify(ctx, filtered)
+		if err != nil {
+			slog.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"delivering notification",
+				slog.String("notifier", r.name),
+				slog.String("from", n.From),
+				slog.String("error", err.Error()),
+			)
+			errs = append(errs, fmt.Errorf("%s: %w", r.name, err))
+			continue
+		}
+		slog.LogAttrs(
+			ctx,
+			slog.LevelDebug,
+			"delivered notification",
+			slog.String("notifier", r.name),
+			slog.String("from", n.From),
+			slog.Int("alerts", len(filtered.Alerts)),
+		)
+	}
+	return errors.Join(errs...)
+}
+
+// Filter returns a copy of the notification holding only the alerts that
+// match the filter, i.e., alerts from one of the filter's sources, with one
+// of its severities and having all of its labels.
+func Filter(f conf.NotifierFilter, n Notification) Notification {
+	out := Notification{
+		Timestamp: n.Timestamp,
+		From:      n.From,
+		Interval:  n.Interval,
+	}
+	if len(f.Sources) != 0 && !slices.Contains(f.Sources, n.From) {
+		return out
+	}
+	for _, a := range n.Alerts {
+		if len(f.Severities) != 0 && !slices.Contains(f.Severities, a.Severity) {
+			continue
+		}
+		if !hasLabels(a.Labels, f.Labels) {
+			continue
+		}
+		out.Alerts = append(out.Alerts, a)
+	}
+	return out
+}
+
+// hasLabels reports whether labels contain all the wanted labels with the
+// same values.
+func hasLabels(labels, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Changed returns a copy of the notification holding only the alerts that
+// changed state at the notification's timestamp, i.e., alerts that started
+// firing and alerts that got resolved.
+func Changed(n Notification) Notification {
+	out := Notification{
+		Timestamp: n.Timestamp,
+		From:      n.From,
+		Interval:  n.Interval,
+	}
+	for _, a := range n.Alerts {
+		if a.State == db.AlertStateFiring && !a.FiredAt.Equal(n.Timestamp) {
+			continue
+		}
+		out.Alerts = append(out.Alerts, a)
+	}
+	return out
+}
+
+// subject returns a one-line summary of the notification.
+func subject(n Notification) string {
+	noun := "alerts"
+	if len(n.Alerts) == 1 {
+		noun = "alert"
+	}
+	var resolved int
+	for _, a := range n.Alerts {
+		if a.State == db.AlertStateResolved {
+			resolved++
+		}
+	}
+	if resolved != 0 {
+		return fmt.Sprintf("RINC: %d %s from %s (%d resolved)", len(n.Alerts), noun, n.From, resolved)
+	}
+	return fmt.Sprintf("RINC: %d %s from %s", len(n.Alerts), noun, n.From)
+}
+
+// text renders the notification as plain text, one alert per line.
+func text(n Notification, bullet string) string {
+	var b strings.Builder
+	for _, a := range n.Alerts {
+		fmt.Fprintf(
+			&b,
+			"%s[%s] [%s] %s",
+			bullet,
+			strings.ToUpper(string(a.State)),
+			strings.ToUpper(string(a.Severity)),
+			a.Message,
+		)
+		if a.State == db.AlertStateResolved {
+			fmt.Fprintf(&b, " (active for %s)", a.ResolvedAt.Sub(a.FirstSeen))
+		}
+		if a.RunbookURL != "" {
+			fmt.Fprintf(&b, " - runbook: %s", a.RunbookURL)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\nGenerated: %s UTC\n", n.Timestamp.UTC().Format("2006-01-02 15:04:05"))
+	return b.String()
+}
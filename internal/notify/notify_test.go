@@ -0,0 +1,370 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/notify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var notification = notify.Notification{
+	Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	From:      db.CollectionRabbitmq,
+	Alerts: []db.AlertStateDocument{
+		{
+			Message:   "foo",
+			Severity:  conf.SeverityWarning,
+			State:     db.AlertStateFiring,
+			FirstSeen: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			FiredAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Message:   "bar",
+			Severity:  conf.SeverityCritical,
+			State:     db.AlertStateFiring,
+			FirstSeen: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			FiredAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	},
+}
+
+func TestFilter(t *testing.T) {
+	a := assert.New(t)
+
+	got := notify.Filter(conf.NotifierFilter{}, notification)
+	a.Len(got.Alerts, 2)
+
+	got = notify.Filter(conf.NotifierFilter{
+		Severities: []conf.Severity{conf.SeverityCritical},
+	}, notification)
+	if a.Len(got.Alerts, 1) {
+		a.Equal("bar", got.Alerts[0].Message)
+	}
+
+	got = notify.Filter(conf.NotifierFilter{
+		Sources: []string{db.CollectionCeph},
+	}, notification)
+	a.Empty(got.Alerts)
+
+	labelled := notification
+	labelled.Alerts = slices.Clone(notification.Alerts)
+	labelled.Alerts[0].Labels = map[string]string{"team": "sre", "tier": "1"}
+	labelled.Alerts[1].Labels = map[string]string{"team": "dev"}
+	got = notify.Filter(conf.NotifierFilter{
+		Labels: map[string]string{"team": "sre"},
+	}, labelled)
+	if a.Len(got.Alerts, 1) {
+		a.Equal("foo", got.Alerts[0].Message)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	a := assert.New(t)
+	var got notify.Notification
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		a.NoError(json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	n := notify.NewWebhook(conf.WebhookNotifier{
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer foo"},
+	})
+	if a.NoError(n.Notify(context.TODO(), notification)) {
+		a.Equal("Bearer foo", auth)
+		a.Equal(notification, got)
+	}
+}
+
+func TestWebhookNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	n := notify.NewWebhook(conf.WebhookNotifier{URL: srv.URL})
+	assert.Error(t, n.Notify(context.TODO(), notification))
+}
+
+func TestSlack(t *testing.T) {
+	a := assert.New(t)
+	var got struct {
+		Text string `json:"text"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.NoError(json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	n := notify.NewSlack(conf.SlackNotifier{URL: srv.URL})
+	if a.NoError(n.Notify(context.TODO(), notification)) {
+		a.Contains(got.Text, "2 alerts from rabbitmq")
+		a.Contains(got.Text, "[FIRING] [WARNING] foo")
+		a.Contains(got.Text, "[FIRING] [CRITICAL] bar")
+	}
+
+	resolved := notification
+	resolved.Alerts = []db.AlertStateDocument{{
+		Message:    "foo",
+		Severity:   conf.SeverityWarning,
+		State:      db.AlertStateResolved,
+		FirstSeen:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ResolvedAt: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC),
+	}}
+	if a.NoError(n.Notify(context.TODO(), resolved)) {
+		a.Contains(got.Text, "1 alert from rabbitmq (1 resolved)")
+		a.Contains(got.Text, "[RESOLVED] [WARNING] foo (active for 2h0m0s)")
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	a := assert.New(t)
+	var warnings, criticals int
+	handler := func(counter *int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var n notify.Notification
+			a.NoError(json.NewDecoder(r.Body).Decode(&n))
+			*counter += len(n.Alerts)
+		}
+	}
+	warnSrv := httptest.NewServer(handler(&warnings))
+	defer warnSrv.Close()
+	critSrv := httptest.NewServer(handler(&criticals))
+	defer critSrv.Close()
+	failSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failSrv.Close()
+
+	d := notify.New(conf.Notifications{
+		Webhooks: []conf.WebhookNotifier{
+			{
+				NotifierFilter: conf.NotifierFilter{
+					Severities: []conf.Severity{conf.SeverityWarning},
+				},
+				URL: warnSrv.URL,
+			},
+			{
+				NotifierFilter: conf.NotifierFilter{
+					Severities: []conf.Severity{conf.SeverityCritical},
+				},
+				URL: critSrv.URL,
+			},
+			{
+				NotifierFilter: conf.NotifierFilter{
+					Sources: []string{db.CollectionCeph},
+				},
+				URL: failSrv.URL,
+			},
+		},
+	})
+	a.NoError(d.Dispatch(context.TODO(), notification))
+	a.Equal(1, warnings)
+	a.Equal(1, criticals)
+
+	notification := notification
+	notification.From = db.CollectionCeph
+	a.Error(d.Dispatch(context.TODO(), notification))
+}
+
+func TestAlertmanager(t *testing.T) {
+	a := assert.New(t)
+	var path string
+	var got []struct {
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+		StartsAt    time.Time         `json:"startsAt"`
+		EndsAt      time.Time         `json:"endsAt"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		a.NoError(json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	n := notify.NewAlertmanager(conf.AlertmanagerNotifier{
+		URL:    srv.URL + "/",
+		Labels: map[string]string{"cluster": "prod", "team": "infra"},
+	})
+	resolvedAt := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	notification := notification
+	notification.Alerts = []db.AlertStateDocument{
+		{
+			Fingerprint: "abc",
+			Message:     "foo",
+			Severity:    conf.SeverityWarning,
+			Labels:      map[string]string{"team": "sre", "severity": "none"},
+			State:       db.AlertStateFiring,
+			FirstSeen:   notification.Timestamp,
+		},
+		{
+			Fingerprint: "def",
+			ID:          "ceph-down",
+			Message:     "bar",
+			Severity:    conf.SeverityCritical,
+			Labels:      map[string]string{"alertname": "CephDown"},
+			Annotations: map[string]string{"description": "baz"},
+			RunbookURL:  "https://example.com/runbooks/ceph",
+			State:       db.AlertStateResolved,
+			FirstSeen:   notification.Timestamp,
+			ResolvedAt:  resolvedAt,
+		},
+	}
+	if !a.NoError(n.Notify(context.TODO(), notification)) {
+		return
+	}
+	a.Equal("/api/v2/alerts", path)
+	if !a.Len(got, 2) {
+		return
+	}
+	a.Equal(map[string]string{
+		"alertname":   "rabbitmq",
+		"reporter":    "rabbitmq",
+		"severity":    "warning",
+		"fingerprint": "abc",
+		"cluster":     "prod",
+		"team":        "sre",
+	}, got[0].Labels)
+	a.Equal("foo", got[0].Annotations["summary"])
+	a.True(got[0].StartsAt.Equal(notification.Timestamp))
+	a.True(got[0].EndsAt.IsZero(), "unknown interval")
+
+	a.Equal("CephDown", got[1].Labels["alertname"])
+	a.True(got[1].EndsAt.Equal(resolvedAt))
+	a.Equal(map[string]string{
+		"summary":     "bar",
+		"expr":        "",
+		"description": "baz",
+		"runbook_url": "https://example.com/runbooks/ceph",
+	}, got[1].Annotations)
+
+	// without a custom alertname, the ID is used.
+	notification.Alerts[1].Labels = nil
+	if a.NoError(n.Notify(context.TODO(), notification)) && a.Len(got, 2) {
+		a.Equal("ceph-down", got[1].Labels["alertname"])
+	}
+
+	// firing alerts end after 3 intervals, unless they are pushed again.
+	notification.Interval = 8 * time.Hour
+	if a.NoError(n.Notify(context.TODO(), notification)) && a.Len(got, 2) {
+		a.True(got[0].EndsAt.Equal(notification.Timestamp.Add(24 * time.Hour)))
+		a.True(got[1].EndsAt.Equal(resolvedAt))
+	}
+	n = notify.NewAlertmanager(conf.AlertmanagerNotifier{URL: srv.URL, TTL: time.Hour})
+	if a.NoError(n.Notify(context.TODO(), notification)) && a.Len(got, 2) {
+		a.True(got[0].EndsAt.Equal(notification.Timestamp.Add(time.Hour)))
+	}
+}
+
+func TestDispatchRepeat(t *testing.T) {
+	a := assert.New(t)
+	var webhook, alertmanager int
+	webhookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n notify.Notification
+		a.NoError(json.NewDecoder(r.Body).Decode(&n))
+		webhook += len(n.Alerts)
+	}))
+	defer webhookSrv.Close()
+	amSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alerts []any
+		a.NoError(json.NewDecoder(r.Body).Decode(&alerts))
+		alertmanager += len(alerts)
+	}))
+	defer amSrv.Close()
+
+	d := notify.New(conf.Notifications{
+		Webhooks:     []conf.WebhookNotifier{{URL: webhookSrv.URL}},
+		Alertmanager: []conf.AlertmanagerNotifier{{URL: amSrv.URL}},
+	})
+
+	// an hour later, both alerts are still firing.
+	notification := notification
+	notification.Timestamp = notification.Timestamp.Add(time.Hour)
+	a.NoError(d.Dispatch(context.TODO(), notification))
+	a.Equal(0, webhook)
+	a.Equal(2, alertmanager)
+}
+
+func TestSMTP(t *testing.T) {
+	a := assert.New(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !a.NoError(err) {
+		return
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go serveSMTP(l, received)
+
+	n := notify.NewSMTP(conf.SMTPNotifier{
+		Host: "127.0.0.1",
+		Port: uint16(l.Addr().(*net.TCPAddr).Port),
+		From: "rinc@example.com",
+		To:   []string{"sre@example.com"},
+	})
+	if a.NoError(n.Notify(context.TODO(), notification)) {
+		msg := <-received
+		a.Contains(msg, "MAIL FROM:<rinc@example.com>")
+		a.Contains(msg, "RCPT TO:<sre@example.com>")
+		a.Contains(msg, "Subject: RINC: 2 alerts from rabbitmq")
+		a.Contains(msg, "- [FIRING] [CRITICAL] bar")
+	}
+}
+
+// serveSMTP is a minimal SMTP stand-in that accepts a single message and
+// sends the whole conversation to the received channel.
+func serveSMTP(l net.Listener, received chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var conversation strings.Builder
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	inData := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		conversation.WriteString(line)
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				reply("250 OK")
+			}
+			continue
+		}
+		switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "DATA":
+			inData = true
+			reply("354 go ahead")
+		case "QUIT":
+			reply("221 bye")
+			received <- conversation.String()
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/accuknox/rinc/internal/conf"
+)
+
+// Slack delivers notifications to a Slack-compatible incoming webhook. The
+// payload only uses the `text` field, which is also understood by Microsoft
+// Teams and Mattermost incoming webhooks.
+type Slack struct {
+	conf   conf.SlackNotifier
+	client *http.Client
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// NewSlack creates a new Slack-compatible incoming webhook notifier.
+func NewSlack(c conf.SlackNotifier) Slack {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return Slack{
+		conf:   c,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify satisfies the Notifier interface.
+func (s Slack) Notify(ctx context.Context, n Notification) error {
+	payload := slackPayload{
+		Text: "*" + subject(n) + "*\n" + text(n, "• "),
+	}
+	return postJSON(ctx, s.client, s.conf.URL, nil, payload)
+}
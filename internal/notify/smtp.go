@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+)
+
+const (
+	defaultSMTPPort    = 587
+	defaultSMTPTimeout = time.Second * 30
+)
+
+// SMTP delivers notifications as plain text emails.
+type SMTP struct {
+	conf conf.SMTPNotifier
+}
+
+// NewSMTP creates a new email notifier.
+func NewSMTP(c conf.SMTPNotifier) SMTP {
+	if c.Port == 0 {
+		c.Port = defaultSMTPPort
+	}
+	return SMTP{conf: c}
+}
+
+// Notify satisfies the Notifier interface. STARTTLS is used whenever the
+// server supports it.
+func (s SMTP) Notify(ctx context.Context, n Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultSMTPTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.conf.Host, strconv.Itoa(int(s.conf.Port)))
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("dialing %q: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.conf.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("creating smtp client: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err := c.StartTLS(&tls.Config{ServerName: s.conf.Host})
+		if err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.conf.Username != "" {
+		auth := smtp.PlainAuth("", s.conf.Username, s.conf.Password, s.conf.Host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.conf.From); err != nil {
+		return fmt.Errorf("MAIL FROM %q: %w", s.conf.From, err)
+	}
+	for _, to := range s.conf.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("RCPT TO %q: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("DATA: %w", err)
+	}
+	if _, err := w.Write(s.message(n)); err != nil {
+		w.Close()
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("closing message: %w", err)
+	}
+	return c.Quit()
+}
+
+func (s SMTP) message(n Notification) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.conf.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.conf.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject(n))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(text(n, "- "), "\n", "\r\n"))
+	return b.Bytes()
+}
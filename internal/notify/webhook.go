@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+)
+
+const defaultHTTPTimeout = time.Second * 10
+
+// Webhook POSTs notifications as JSON documents to a generic webhook
+// receiver.
+type Webhook struct {
+	conf   conf.WebhookNotifier
+	client *http.Client
+}
+
+// NewWebhook creates a new generic webhook notifier.
+func NewWebhook(c conf.WebhookNotifier) Webhook {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return Webhook{
+		conf:   c,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify satisfies the Notifier interface.
+func (w Webhook) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, w.client, w.conf.URL, w.conf.Headers, n)
+}
+
+// postJSON marshals the body into JSON and POSTs it to the provided url.
+// Any non-2xx response is considered an error.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshalling request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("creating new http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("non-2xx response. Status: %s", resp.Status)
+	}
+	return nil
+}
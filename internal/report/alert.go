@@ -2,50 +2,311 @@ package report
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"reflect"
+	"time"
 
 	"github.com/accuknox/rinc/internal/conf"
 	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/expr"
+	"github.com/accuknox/rinc/internal/store"
 )
 
-// SoftEvaluateAlerts evaluates the provided alerts using the given data and
-// returns a list of triggered alerts. Any errors encountered during the
+// SoftEvaluateAlerts evaluates the provided alerts using the current
+// snapshot of the given history and returns a list of triggered alerts. The
+// history is also made available to the history functions of the expression
+// language, such as `prev` and `delta`. Any errors encountered during the
 // process will be logged. In case of an error during the evaluation of an
 // alert, only that specific alert will be skipped.
-func SoftEvaluateAlerts(ctx context.Context, alerts []conf.Alert, data any) []db.Alert {
+//
+// Alerts with a `forEach` expression are evaluated once for each element of
+// the list it selects, firing one alert per matching element.
+func SoftEvaluateAlerts(ctx context.Context, alerts []conf.Alert, h expr.History) []db.Alert {
+	firing, errs := EvaluateAlerts(ctx, alerts, h)
+	for _, err := range errs {
+		slog.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"evaluating alert",
+			slog.String("error", err.Error()),
+		)
+	}
+	return firing
+}
+
+// EvaluateAlerts is like SoftEvaluateAlerts, except that the errors
+// encountered while evaluating the alerts are returned instead of logged.
+func EvaluateAlerts(ctx context.Context, alerts []conf.Alert, h expr.History) ([]db.Alert, []error) {
+	ctx = expr.WithHistory(ctx, h)
+	data := h.Current()
 	var firing []db.Alert
+	var errs []error
 
 	for _, alert := range alerts {
-		fire, err := alert.When.Evaluable.EvalBool(ctx, data)
-		if err != nil {
-			slog.LogAttrs(
-				ctx,
-				slog.LevelError,
-				"evaluating boolean expressions",
-				slog.String("error", err.Error()),
-				slog.String("expr", alert.When.Text),
-			)
+		if alert.ForEach.Evaluable != nil {
+			fired, ferrs := evaluateForEach(ctx, alert, data)
+			firing = append(firing, fired...)
+			errs = append(errs, ferrs...)
 			continue
 		}
-		if !fire {
+		a, ok, err := evaluate(ctx, alert, data)
+		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
-		msg, err := alert.Message.Evaluate(ctx, data)
+		if ok {
+			firing = append(firing, a)
+		}
+	}
+
+	return firing, errs
+}
+
+// evaluateForEach evaluates the alert against each element of the list
+// selected by its `forEach` expression. An element whose evaluation fails is
+// skipped and its error returned along with the alerts that fired.
+func evaluateForEach(ctx context.Context, alert conf.Alert, data any) ([]db.Alert, []error) {
+	list, err := alert.ForEach.Evaluable(ctx, data)
+	if err != nil {
+		return nil, []error{
+			fmt.Errorf("evaluating forEach expression %q: %w", alert.ForEach.Text, err),
+		}
+	}
+	rlist := reflect.ValueOf(list)
+	if rlist.Kind() != reflect.Slice && rlist.Kind() != reflect.Array {
+		if list != nil {
+			return nil, []error{
+				fmt.Errorf("forEach expression %q evaluated to %s, want a list",
+					alert.ForEach.Text, rlist.Kind()),
+			}
+		}
+		return nil, nil
+	}
+
+	var firing []db.Alert
+	var errs []error
+	for idx := 0; idx < rlist.Len(); idx++ {
+		item := rlist.Index(idx).Interface()
+		a, ok, err := evaluate(ctx, alert, item)
 		if err != nil {
-			slog.LogAttrs(
-				ctx,
-				slog.LevelError,
-				"evaluating message expression",
-				slog.String("error", err.Error()),
-				slog.String("message", alert.Message.Text),
-			)
+			errs = append(errs, fmt.Errorf("%s: %w", resource(item, idx), err))
 			continue
 		}
-		firing = append(firing, db.Alert{
-			Message:  msg,
-			Severity: alert.Severity,
-		})
+		if !ok {
+			continue
+		}
+		a.Resource = resource(item, idx)
+		if alert.Resource.Text != "" {
+			res, err := alert.Resource.Evaluate(ctx, item)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: evaluating resource expression %q: %w",
+					a.Resource, alert.Resource.Text, err))
+				continue
+			}
+			a.Resource = res
+		}
+		firing = append(firing, a)
 	}
+	return firing, errs
+}
 
-	return firing
+// evaluate evaluates a single alert using the given data. It returns false
+// if the alert does not fire or if its evaluation fails.
+func evaluate(ctx context.Context, alert conf.Alert, data any) (db.Alert, bool, error) {
+	fire, err := alert.When.Evaluable.EvalBool(ctx, data)
+	if err != nil {
+		return db.Alert{}, false, fmt.Errorf("evaluating boolean expression %q: %w", alert.When.Text, err)
+	}
+	if !fire {
+		return db.Alert{}, false, nil
+	}
+	msg, err := alert.Message.Evaluate(ctx, data)
+	if err != nil {
+		return db.Alert{}, false, fmt.Errorf("evaluating message expression %q: %w", alert.Message.Text, err)
+	}
+	return db.Alert{
+		ID:          alert.ID,
+		Message:     msg,
+		Severity:    alert.Severity,
+		Expr:        alert.When.Text,
+		Labels:      alert.Labels,
+		Annotations: alert.Annotations,
+		RunbookURL:  alert.RunbookURL,
+		For:         alert.For,
+	}, true, nil
+}
+
+// resource returns the identity of a list element: "<Namespace>/<Name>" if
+// the element is a struct with both fields, "<Name>" if it only has a name,
+// and its index in the list otherwise.
+func resource(item any, idx int) string {
+	v := reflect.ValueOf(item)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		name := v.FieldByName("Name")
+		if name.IsValid() && name.Kind() == reflect.String {
+			ns := v.FieldByName("Namespace")
+			if ns.IsValid() && ns.Kind() == reflect.String && ns.String() != "" {
+				return ns.String() + "/" + name.String()
+			}
+			return name.String()
+		}
+	}
+	return fmt.Sprintf("[%d]", idx)
+}
+
+// StoreAlerts records the alerts fired by the provided source at the given
+// timestamp. Duplicate alerts, i.e., alerts with the same fingerprint, are
+// dropped. The state of each alert is tracked in the alert states
+// collection: alerts that start firing get a new firing state, alerts that
+// keep firing carry over the time they were first seen, and firing alerts
+// that are no longer present are marked as resolved.
+//
+// Alerts with a `for` clause are kept pending until their condition has held
+// long enough; only then do they fire and get stored with the other firing
+// alerts. Pending alerts whose condition stops holding are dropped.
+func StoreAlerts(ctx context.Context, s store.Store, from string, now time.Time, alerts []db.Alert) error {
+	prev, err := s.ActiveAlertStates(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	t := trackAlerts(from, now, alerts, prev)
+
+	for _, state := range t.states {
+		if err := s.SaveAlertState(ctx, state); err != nil {
+			return err
+		}
+	}
+	if len(t.resolved) != 0 {
+		if err := s.ResolveAlertStates(ctx, fingerprints(t.resolved), now); err != nil {
+			return err
+		}
+	}
+	if len(t.dropped) != 0 {
+		if err := s.DropAlertStates(ctx, fingerprints(t.dropped)); err != nil {
+			return err
+		}
+	}
+
+	firing := t.firing
+	if firing == nil {
+		// stored as an empty array rather than null
+		firing = []db.Alert{}
+	}
+	id, err := s.Insert(ctx, db.CollectionAlerts, db.AlertDocument{
+		Timestamp: now,
+		From:      from,
+		Alerts:    firing,
+	})
+	if err != nil {
+		return fmt.Errorf("inserting alerts: %w", err)
+	}
+	slog.LogAttrs(
+		ctx,
+		slog.LevelDebug,
+		"inserted alerts into database",
+		slog.String("from", from),
+		slog.Int("firing", len(t.firing)),
+		slog.Int("pending", len(t.states)-len(t.firing)),
+		slog.Int("resolved", len(t.resolved)),
+		slog.Any("insertedId", id),
+	)
+	return nil
+}
+
+// tracked holds the outcome of tracking the alerts of a source at a point in
+// time.
+type tracked struct {
+	// firing is the list of deduplicated firing alerts, with their
+	// FirstSeen field populated.
+	firing []db.Alert
+	// states is the list of states of the pending and firing alerts.
+	states []db.AlertStateDocument
+	// resolved is the list of previously firing states that are now
+	// resolved.
+	resolved []db.AlertStateDocument
+	// dropped is the list of previously pending states whose condition no
+	// longer holds.
+	dropped []db.AlertStateDocument
+}
+
+// trackAlerts computes the alert state transitions caused by the alerts
+// evaluated for the provided source at the given timestamp, given the states
+// that were pending or firing before.
+func trackAlerts(from string, now time.Time, alerts []db.Alert, active []db.AlertStateDocument) tracked {
+	prev := make(map[string]db.AlertStateDocument, len(active))
+	for _, s := range active {
+		prev[s.Fingerprint] = s
+	}
+
+	var t tracked
+	seen := make(map[string]bool, len(alerts))
+	for _, a := range alerts {
+		fp := a.Fingerprint(from)
+		if seen[fp] {
+			continue
+		}
+		seen[fp] = true
+
+		s := db.AlertStateDocument{
+			Fingerprint: fp,
+			ID:          a.ID,
+			From:        from,
+			Resource:    a.Resource,
+			Expr:        a.Expr,
+			Message:     a.Message,
+			Severity:    a.Severity,
+			Labels:      a.Labels,
+			Annotations: a.Annotations,
+			RunbookURL:  a.RunbookURL,
+			State:       db.AlertStatePending,
+			FirstSeen:   now,
+			LastSeen:    now,
+			Evaluations: 1,
+		}
+		if p, ok := prev[fp]; ok {
+			s.State = p.State
+			s.FirstSeen = p.FirstSeen
+			s.FiredAt = p.FiredAt
+			s.Evaluations = p.Evaluations + 1
+		}
+		if s.State == db.AlertStatePending && (!a.For.IsSet() || a.For.Satisfied(s.FirstSeen, now, s.Evaluations)) {
+			s.State = db.AlertStateFiring
+			s.FiredAt = now
+		}
+		t.states = append(t.states, s)
+
+		if s.State == db.AlertStateFiring {
+			a.FirstSeen = s.FirstSeen
+			t.firing = append(t.firing, a)
+		}
+	}
+
+	for _, s := range active {
+		if seen[s.Fingerprint] {
+			continue
+		}
+		if s.State == db.AlertStatePending {
+			t.dropped = append(t.dropped, s)
+			continue
+		}
+		s.State = db.AlertStateResolved
+		s.ResolvedAt = now
+		t.resolved = append(t.resolved, s)
+	}
+
+	return t
+}
+
+// fingerprints returns the fingerprints of the provided states.
+func fingerprints(states []db.AlertStateDocument) []string {
+	out := make([]string, 0, len(states))
+	for _, s := range states {
+		out = append(out, s.Fingerprint)
+	}
+	return out
 }
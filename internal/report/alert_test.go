@@ -0,0 +1,176 @@
+package report
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/expr"
+	"github.com/accuknox/rinc/types/dass"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackAlerts(t *testing.T) {
+	a := assert.New(t)
+	from := db.CollectionRabbitmq
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	foo := db.Alert{Message: "foo", Severity: conf.SeverityWarning, Expr: "x > 1"}
+	bar := db.Alert{Message: "bar", Severity: conf.SeverityCritical, Expr: "y > 1"}
+
+	// first evaluation: both alerts start firing, duplicates are dropped.
+	first := trackAlerts(from, t0, []db.Alert{foo, bar, foo}, nil)
+	a.Len(first.firing, 2)
+	a.Len(first.states, 2)
+	a.Empty(first.resolved)
+	for _, s := range first.states {
+		a.Equal(db.AlertStateFiring, s.State)
+		a.Equal(t0, s.FirstSeen)
+		a.Equal(t0, s.LastSeen)
+		a.Equal(t0, s.FiredAt)
+	}
+
+	// second evaluation: foo keeps firing, bar resolves.
+	second := trackAlerts(from, t1, []db.Alert{foo}, first.states)
+	if a.Len(second.firing, 1) && a.Len(second.states, 1) {
+		a.Equal(t0, second.firing[0].FirstSeen)
+		a.Equal(foo.Fingerprint(from), second.states[0].Fingerprint)
+		a.Equal(t0, second.states[0].FirstSeen)
+		a.Equal(t1, second.states[0].LastSeen)
+		a.Equal(t0, second.states[0].FiredAt)
+		a.Equal(2, second.states[0].Evaluations)
+	}
+	if a.Len(second.resolved, 1) {
+		a.Equal(bar.Fingerprint(from), second.resolved[0].Fingerprint)
+		a.Equal(db.AlertStateResolved, second.resolved[0].State)
+		a.Equal(t1, second.resolved[0].ResolvedAt)
+	}
+
+	// the same alert fired by another source is tracked separately.
+	a.NotEqual(foo.Fingerprint(from), foo.Fingerprint(db.CollectionCeph))
+
+	// alerts with an ID keep their identity when the expression changes.
+	withID := foo
+	withID.ID = "foo"
+	edited := withID
+	edited.Expr = "x > 2"
+	a.Equal(withID.Fingerprint(from), edited.Fingerprint(from))
+	a.NotEqual(foo.Fingerprint(from), withID.Fingerprint(from))
+}
+
+func TestTrackAlertsFor(t *testing.T) {
+	a := assert.New(t)
+	from := db.CollectionRabbitmq
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var evaluations conf.For
+	a.NoError(evaluations.UnmarshalText([]byte("3")))
+	var duration conf.For
+	a.NoError(duration.UnmarshalText([]byte("15m")))
+	foo := db.Alert{Message: "foo", Expr: "x > 1", For: evaluations}
+	bar := db.Alert{Message: "bar", Expr: "y > 1", For: duration}
+
+	var states []db.AlertStateDocument
+	for idx, want := range []struct {
+		foo, bar db.AlertState
+	}{
+		{db.AlertStatePending, db.AlertStatePending}, // t0
+		{db.AlertStatePending, db.AlertStatePending}, // t0 + 10m
+		{db.AlertStateFiring, db.AlertStateFiring},   // t0 + 20m
+	} {
+		now := t0.Add(time.Duration(idx) * 10 * time.Minute)
+		tr := trackAlerts(from, now, []db.Alert{foo, bar}, states)
+		if !a.Len(tr.states, 2) {
+			return
+		}
+		a.Equalf(want.foo, tr.states[0].State, "foo at evaluation %d", idx)
+		a.Equalf(want.bar, tr.states[1].State, "bar at evaluation %d", idx)
+		if want.foo == db.AlertStateFiring {
+			a.Len(tr.firing, 2)
+			a.Equal(now, tr.states[0].FiredAt)
+			a.Equal(t0, tr.firing[0].FirstSeen)
+		} else {
+			a.Empty(tr.firing)
+		}
+		states = tr.states
+	}
+
+	// pending alerts whose condition stops holding are dropped, not resolved.
+	tr := trackAlerts(from, t0, []db.Alert{foo}, nil)
+	tr = trackAlerts(from, t0.Add(time.Minute), nil, tr.states)
+	a.Len(tr.dropped, 1)
+	a.Empty(tr.resolved)
+}
+
+func TestSoftEvaluateAlertsForEach(t *testing.T) {
+	a := assert.New(t)
+	metrics := dass.Metrics{
+		Deployments: []dass.Resource{
+			{Name: "foo", Namespace: "default", IsAvailable: false},
+			{Name: "bar", Namespace: "default", IsAvailable: true},
+			{Name: "baz", Namespace: "kube-system", IsAvailable: false},
+		},
+	}
+	alert := conf.Alert{Severity: conf.SeverityCritical}
+	a.NoError(alert.When.UnmarshalText([]byte("IsAvailable == false")))
+	a.NoError(alert.Message.UnmarshalText([]byte("deployment `Name` is unavailable")))
+	a.NoError(alert.ForEach.UnmarshalText([]byte("Deployments")))
+
+	firing := SoftEvaluateAlerts(context.TODO(), []conf.Alert{alert}, history(metrics))
+	if a.Len(firing, 2) {
+		a.Equal("deployment foo is unavailable", firing[0].Message)
+		a.Equal("default/foo", firing[0].Resource)
+		a.Equal("deployment baz is unavailable", firing[1].Message)
+		a.Equal("kube-system/baz", firing[1].Resource)
+	}
+
+	a.NoError(alert.Resource.UnmarshalText([]byte("deploy/`Name`")))
+	firing = SoftEvaluateAlerts(context.TODO(), []conf.Alert{alert}, history(metrics))
+	if a.Len(firing, 2) {
+		a.Equal("deploy/foo", firing[0].Resource)
+	}
+
+	// elements without a name are identified by their index.
+	a.NoError(alert.ForEach.UnmarshalText([]byte("Events")))
+	a.NoError(alert.When.UnmarshalText([]byte(`Type == "Warning"`)))
+	a.NoError(alert.Message.UnmarshalText([]byte("`Reason`")))
+	alert.Resource = conf.StringExpr{}
+	firing = SoftEvaluateAlerts(context.TODO(), []conf.Alert{alert}, history(dass.Resource{
+		Events: []dass.Event{
+			{Type: "Normal", Reason: "Scaled"},
+			{Type: "Warning", Reason: "FailedCreate"},
+		},
+	}))
+	if a.Len(firing, 1) {
+		a.Equal("FailedCreate", firing[0].Message)
+		a.Equal("[1]", firing[0].Resource)
+	}
+}
+
+func TestSoftEvaluateAlertsHistory(t *testing.T) {
+	a := assert.New(t)
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := expr.Snapshots{
+		{Timestamp: t0, Data: dass.Metrics{Deployments: make([]dass.Resource, 1)}},
+		{Timestamp: t0.Add(time.Hour), Data: dass.Metrics{Deployments: make([]dass.Resource, 4)}},
+	}
+	alert := conf.Alert{Severity: conf.SeverityWarning}
+	a.NoError(alert.When.UnmarshalText([]byte(`delta("len(Deployments)") > 2`)))
+	a.NoError(alert.Message.UnmarshalText([]byte("`delta(\"len(Deployments)\")` new deployments")))
+
+	firing := SoftEvaluateAlerts(context.TODO(), []conf.Alert{alert}, h)
+	if a.Len(firing, 1) {
+		a.Equal("3 new deployments", firing[0].Message)
+	}
+	// without an earlier snapshot, the delta is zero.
+	a.Empty(SoftEvaluateAlerts(context.TODO(), []conf.Alert{alert}, h[1:]))
+}
+
+// history returns a history holding only the provided data.
+func history(data any) expr.History {
+	return expr.Snapshots{{Data: data}}
+}
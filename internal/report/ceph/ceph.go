@@ -10,10 +10,9 @@ import (
 	"github.com/accuknox/rinc/internal/conf"
 	"github.com/accuknox/rinc/internal/db"
 	"github.com/accuknox/rinc/internal/report"
+	"github.com/accuknox/rinc/internal/store"
 	types "github.com/accuknox/rinc/types/ceph"
 
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -21,16 +20,16 @@ import (
 type Reporter struct {
 	kubeClient *kubernetes.Clientset
 	conf       conf.Ceph
-	mongo      *mongo.Client
+	store      store.Store
 	token      *token
 }
 
 // NewReporter creates a new ceph status reporter.
-func NewReporter(c conf.Ceph, k *kubernetes.Clientset, mongo *mongo.Client) Reporter {
+func NewReporter(c conf.Ceph, k *kubernetes.Clientset, s store.Store) Reporter {
 	return Reporter{
 		conf:       c,
 		kubeClient: k,
-		mongo:      mongo,
+		store:      s,
 		token:      nil,
 	}
 }
@@ -139,10 +138,7 @@ func (r Reporter) Report(ctx context.Context, now time.Time) error {
 		Inventories: inventories,
 	}
 
-	result, err := db.
-		Database(r.mongo).
-		Collection(db.CollectionCeph).
-		InsertOne(ctx, metrics)
+	id, err := r.store.Insert(ctx, db.CollectionCeph, metrics)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
@@ -157,34 +153,22 @@ func (r Reporter) Report(ctx context.Context, now time.Time) error {
 		ctx,
 		slog.LevelDebug,
 		"ceph: inserted metrics into mongodb",
-		slog.Any("insertedId", result.InsertedID),
+		slog.Any("insertedId", id),
 	)
 
-	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, metrics)
-	result, err = db.
-		Database(r.mongo).
-		Collection(db.CollectionAlerts).
-		InsertOne(ctx, bson.M{
-			"timestamp": now,
-			"from":      db.CollectionCeph,
-			"alerts":    alerts,
-		})
+	history := report.NewHistory(r.store, db.CollectionCeph, now, metrics)
+	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, history)
+	err = report.StoreAlerts(ctx, r.store, db.CollectionCeph, now, alerts)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"ceph: inserting alerts into mongodb",
+			"ceph: storing alerts",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting alerts into mongodb: %w", err)
+		return fmt.Errorf("storing alerts: %w", err)
 	}
-	slog.LogAttrs(
-		ctx,
-		slog.LevelDebug,
-		"ceph: inserted alerts into mongodb",
-		slog.Any("insertedId", result.InsertedID),
-	)
 
 	return nil
 }
@@ -9,10 +9,9 @@ import (
 	"github.com/accuknox/rinc/internal/conf"
 	"github.com/accuknox/rinc/internal/db"
 	"github.com/accuknox/rinc/internal/report"
+	"github.com/accuknox/rinc/internal/store"
 	types "github.com/accuknox/rinc/types/connectivity"
 
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -20,15 +19,15 @@ import (
 type Reporter struct {
 	kubeClient *kubernetes.Clientset
 	conf       conf.Connectivity
-	mongo      *mongo.Client
+	store      store.Store
 }
 
 // NewReporter creates a new connectivity status reporter.
-func NewReporter(c conf.Connectivity, k *kubernetes.Clientset, mongo *mongo.Client) Reporter {
+func NewReporter(c conf.Connectivity, k *kubernetes.Clientset, s store.Store) Reporter {
 	return Reporter{
 		conf:       c,
 		kubeClient: k,
-		mongo:      mongo,
+		store:      s,
 	}
 }
 
@@ -127,10 +126,7 @@ func (r Reporter) Report(ctx context.Context, now time.Time) error {
 		}
 	}
 
-	result, err := db.
-		Database(r.mongo).
-		Collection(db.CollectionConnectivity).
-		InsertOne(ctx, metrics)
+	id, err := r.store.Insert(ctx, db.CollectionConnectivity, metrics)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
@@ -145,34 +141,22 @@ func (r Reporter) Report(ctx context.Context, now time.Time) error {
 		ctx,
 		slog.LevelDebug,
 		"connectivity: inserted metrics into mongodb",
-		slog.Any("insertedId", result.InsertedID),
+		slog.Any("insertedId", id),
 	)
 
-	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, metrics)
-	result, err = db.
-		Database(r.mongo).
-		Collection(db.CollectionAlerts).
-		InsertOne(ctx, bson.M{
-			"timestamp": now,
-			"from":      db.CollectionConnectivity,
-			"alerts":    alerts,
-		})
+	history := report.NewHistory(r.store, db.CollectionConnectivity, now, metrics)
+	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, history)
+	err = report.StoreAlerts(ctx, r.store, db.CollectionConnectivity, now, alerts)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"connectivity: inserting alerts into mongodb",
+			"connectivity: storing alerts",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting alerts into mongodb: %w", err)
+		return fmt.Errorf("storing alerts: %w", err)
 	}
-	slog.LogAttrs(
-		ctx,
-		slog.LevelDebug,
-		"connectivity: inserted alerts into mongodb",
-		slog.Any("insertedId", result.InsertedID),
-	)
 
 	return nil
 }
@@ -9,10 +9,9 @@ import (
 	"github.com/accuknox/rinc/internal/conf"
 	"github.com/accuknox/rinc/internal/db"
 	"github.com/accuknox/rinc/internal/report"
+	"github.com/accuknox/rinc/internal/store"
 	types "github.com/accuknox/rinc/types/dass"
 
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -21,15 +20,15 @@ import (
 type Reporter struct {
 	kubeClient *kubernetes.Clientset
 	conf       conf.DaSS
-	mongo      *mongo.Client
+	store      store.Store
 }
 
 // NewReporter creates a new deployment and statefulset status (DaSS) reporter.
-func NewReporter(c conf.DaSS, k *kubernetes.Clientset, mongo *mongo.Client) Reporter {
+func NewReporter(c conf.DaSS, k *kubernetes.Clientset, s store.Store) Reporter {
 	return Reporter{
 		conf:       c,
 		kubeClient: k,
-		mongo:      mongo,
+		store:      s,
 	}
 }
 
@@ -65,51 +64,37 @@ func (r Reporter) Report(ctx context.Context, now time.Time) error {
 		Statefulsets: ss,
 	}
 
-	result, err := db.Database(r.mongo).
-		Collection(db.CollectionDass).
-		InsertOne(ctx, metrics)
+	id, err := r.store.Insert(ctx, db.CollectionDass, metrics)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"inserting into mongodb",
+			"inserting into database",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting into mongodb: %w", err)
+		return fmt.Errorf("inserting into database: %w", err)
 	}
 	slog.LogAttrs(
 		ctx,
 		slog.LevelDebug,
-		"dass: inserted document into mongodb",
-		slog.Any("insertedId", result.InsertedID),
+		"dass: inserted document into database",
+		slog.Any("insertedId", id),
 	)
 
-	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, metrics)
-	result, err = db.
-		Database(r.mongo).
-		Collection(db.CollectionAlerts).
-		InsertOne(ctx, bson.M{
-			"timestamp": now,
-			"from":      db.CollectionDass,
-			"alerts":    alerts,
-		})
+	history := report.NewHistory(r.store, db.CollectionDass, now, metrics)
+	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, history)
+	err = report.StoreAlerts(ctx, r.store, db.CollectionDass, now, alerts)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"dass: inserting alerts into mongodb",
+			"dass: storing alerts",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting alerts into mongodb: %w", err)
+		return fmt.Errorf("storing alerts: %w", err)
 	}
-	slog.LogAttrs(
-		ctx,
-		slog.LevelDebug,
-		"dass: inserted alerts into mongodb",
-		slog.Any("insertedId", result.InsertedID),
-	)
 
 	return nil
 }
@@ -0,0 +1,103 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/accuknox/rinc/internal/expr"
+	"github.com/accuknox/rinc/internal/store"
+)
+
+// History loads the earlier documents of a reporter's collection, decoded
+// into the same type as the current metrics. It implements expr.History.
+// Loaded documents are cached, so an earlier document is fetched at most
+// once per evaluation.
+type History struct {
+	store   store.Store
+	coll    string
+	now     time.Time
+	current any
+
+	mu    sync.Mutex
+	cache map[string]snapshot
+}
+
+var _ expr.History = (*History)(nil)
+
+type snapshot struct {
+	doc any
+	at  time.Time
+}
+
+// NewHistory creates a new history of the provided collection, where
+// current is the document of the metrics taken at now.
+func NewHistory(s store.Store, coll string, now time.Time, current any) *History {
+	return &History{
+		store:   s,
+		coll:    coll,
+		now:     now,
+		current: current,
+	}
+}
+
+// Current satisfies the expr.History interface.
+func (h *History) Current() any {
+	return h.current
+}
+
+// Timestamp satisfies the expr.History interface.
+func (h *History) Timestamp() time.Time {
+	return h.now
+}
+
+// Previous satisfies the expr.History interface.
+func (h *History) Previous(ctx context.Context) (any, time.Time, error) {
+	return h.find(ctx, "before", h.now, h.store.Before)
+}
+
+// At satisfies the expr.History interface.
+func (h *History) At(ctx context.Context, t time.Time) (any, time.Time, error) {
+	return h.find(ctx, "at", t, h.store.AtOrBefore)
+}
+
+// find returns the latest document returned by the provided lookup, which
+// is cached under the operator and timestamp of the lookup.
+func (h *History) find(
+	ctx context.Context,
+	op string,
+	t time.Time,
+	lookup func(ctx context.Context, coll string, t time.Time, v any) (time.Time, error),
+) (any, time.Time, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := fmt.Sprintf("%s %d", op, t.UnixNano())
+	if s, ok := h.cache[key]; ok {
+		return s.doc, s.at, nil
+	}
+	if h.cache == nil {
+		h.cache = make(map[string]snapshot)
+	}
+
+	typ := reflect.TypeOf(h.current)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	doc := reflect.New(typ)
+	at, err := lookup(ctx, h.coll, t, doc.Interface())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			h.cache[key] = snapshot{}
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, fmt.Errorf("finding document in %q: %w", h.coll, err)
+	}
+
+	s := snapshot{doc: doc.Elem().Interface(), at: at}
+	h.cache[key] = s
+	return s.doc, s.at, nil
+}
@@ -9,10 +9,9 @@ import (
 	"github.com/accuknox/rinc/internal/conf"
 	"github.com/accuknox/rinc/internal/db"
 	"github.com/accuknox/rinc/internal/report"
+	"github.com/accuknox/rinc/internal/store"
 	types "github.com/accuknox/rinc/types/pod"
 
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -23,15 +22,15 @@ import (
 type Reporter struct {
 	kubeClient *kubernetes.Clientset
 	conf       conf.PodStatus
-	mongo      *mongo.Client
+	store      store.Store
 }
 
 // NewReporter creates a new pod status reporter.
-func NewReporter(c conf.PodStatus, k *kubernetes.Clientset, mongo *mongo.Client) Reporter {
+func NewReporter(c conf.PodStatus, k *kubernetes.Clientset, s store.Store) Reporter {
 	return Reporter{
 		conf:       c,
 		kubeClient: k,
-		mongo:      mongo,
+		store:      s,
 	}
 }
 
@@ -66,51 +65,37 @@ func (r Reporter) Report(ctx context.Context, now time.Time) error {
 		Statefulsets: ss,
 	}
 
-	result, err := db.Database(r.mongo).
-		Collection(db.CollectionPodStatus).
-		InsertOne(ctx, metrics)
+	id, err := r.store.Insert(ctx, db.CollectionPodStatus, metrics)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"inserting into mongodb",
+			"inserting into database",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting into mongodb: %w", err)
+		return fmt.Errorf("inserting into database: %w", err)
 	}
 	slog.LogAttrs(
 		ctx,
 		slog.LevelDebug,
-		"podStatus: inserted document into mongodb",
-		slog.Any("insertedId", result.InsertedID),
+		"podStatus: inserted document into database",
+		slog.Any("insertedId", id),
 	)
 
-	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, metrics)
-	result, err = db.
-		Database(r.mongo).
-		Collection(db.CollectionAlerts).
-		InsertOne(ctx, bson.M{
-			"timestamp": now,
-			"from":      db.CollectionPodStatus,
-			"alerts":    alerts,
-		})
+	history := report.NewHistory(r.store, db.CollectionPodStatus, now, metrics)
+	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, history)
+	err = report.StoreAlerts(ctx, r.store, db.CollectionPodStatus, now, alerts)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"podStatus: inserting alerts into mongodb",
+			"podStatus: storing alerts",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting alerts into mongodb: %w", err)
+		return fmt.Errorf("storing alerts: %w", err)
 	}
-	slog.LogAttrs(
-		ctx,
-		slog.LevelDebug,
-		"podStatus: inserted alerts into mongodb",
-		slog.Any("insertedId", result.InsertedID),
-	)
 
 	return nil
 }
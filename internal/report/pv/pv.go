@@ -9,12 +9,11 @@ import (
 	"github.com/accuknox/rinc/internal/conf"
 	"github.com/accuknox/rinc/internal/db"
 	"github.com/accuknox/rinc/internal/report"
+	"github.com/accuknox/rinc/internal/store"
 	types "github.com/accuknox/rinc/types/pv"
 
 	"github.com/prometheus/client_golang/api"
 	promV1 "github.com/prometheus/client_golang/api/prometheus/v1"
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -22,15 +21,15 @@ import (
 type Reporter struct {
 	kubeClient *kubernetes.Clientset
 	conf       conf.PVUtilization
-	mongo      *mongo.Client
+	store      store.Store
 }
 
 // NewReporter creates a new PV utilization reporter.
-func NewReporter(c conf.PVUtilization, k *kubernetes.Clientset, mongo *mongo.Client) Reporter {
+func NewReporter(c conf.PVUtilization, k *kubernetes.Clientset, s store.Store) Reporter {
 	return Reporter{
 		conf:       c,
 		kubeClient: k,
-		mongo:      mongo,
+		store:      s,
 	}
 }
 
@@ -87,52 +86,37 @@ func (r Reporter) Report(ctx context.Context, now time.Time) error {
 		PVs:       pvs,
 	}
 
-	result, err := db.
-		Database(r.mongo).
-		Collection(db.CollectionPVUtilizaton).
-		InsertOne(ctx, metrics)
+	id, err := r.store.Insert(ctx, db.CollectionPVUtilizaton, metrics)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"inserting into mongodb",
+			"inserting into database",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting into mongodb: %w", err)
+		return fmt.Errorf("inserting into database: %w", err)
 	}
 	slog.LogAttrs(
 		ctx,
 		slog.LevelDebug,
-		"pv: inserted document into mongodb",
-		slog.Any("insertedId", result.InsertedID),
+		"pv: inserted document into database",
+		slog.Any("insertedId", id),
 	)
 
-	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, metrics)
-	result, err = db.
-		Database(r.mongo).
-		Collection(db.CollectionAlerts).
-		InsertOne(ctx, bson.M{
-			"timestamp": now,
-			"from":      db.CollectionPVUtilizaton,
-			"alerts":    alerts,
-		})
+	history := report.NewHistory(r.store, db.CollectionPVUtilizaton, now, metrics)
+	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, history)
+	err = report.StoreAlerts(ctx, r.store, db.CollectionPVUtilizaton, now, alerts)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"pv: inserting alerts into mongodb",
+			"pv: storing alerts",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting alerts into mongodb: %w", err)
+		return fmt.Errorf("storing alerts: %w", err)
 	}
-	slog.LogAttrs(
-		ctx,
-		slog.LevelDebug,
-		"pv: inserted alerts into mongodb",
-		slog.Any("insertedId", result.InsertedID),
-	)
 
 	return nil
 }
@@ -9,10 +9,9 @@ import (
 	"github.com/accuknox/rinc/internal/conf"
 	"github.com/accuknox/rinc/internal/db"
 	"github.com/accuknox/rinc/internal/report"
+	"github.com/accuknox/rinc/internal/store"
 	types "github.com/accuknox/rinc/types/rabbitmq"
 
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -20,15 +19,15 @@ import (
 type Reporter struct {
 	kubeClient *kubernetes.Clientset
 	conf       conf.RabbitMQ
-	mongo      *mongo.Client
+	store      store.Store
 }
 
 // NewReporter creates a new of the rabbitmq reporter.
-func NewReporter(c conf.RabbitMQ, k *kubernetes.Clientset, mongo *mongo.Client) Reporter {
+func NewReporter(c conf.RabbitMQ, k *kubernetes.Clientset, s store.Store) Reporter {
 	return Reporter{
 		conf:       c,
 		kubeClient: k,
-		mongo:      mongo,
+		store:      s,
 	}
 }
 
@@ -45,95 +44,60 @@ func (r Reporter) Report(ctx context.Context, now time.Time) error {
 		)
 		return fmt.Errorf("fetching rabbitmq health status: %w", err)
 	}
-	if !up {
-		slog.LogAttrs(
-			ctx,
-			slog.LevelInfo,
-			"rabbitmq cluster is down",
-		)
-		result, err := db.
-			Database(r.mongo).
-			Collection(db.CollectionRabbitmq).
-			InsertOne(ctx, types.Metrics{
-				Timestamp:   now,
-				IsClusterUp: false,
-			})
+	// the alerts are evaluated while the cluster is down too, so that those
+	// that no longer hold are resolved and those on `IsClusterUp` can fire.
+	metrics := &types.Metrics{IsClusterUp: false}
+	if up {
+		metrics, err = r.GetMetrics(ctx)
 		if err != nil {
 			slog.LogAttrs(
 				ctx,
 				slog.LevelError,
-				"inserting into mongodb",
-				slog.Time("timestamp", now),
+				"failed to fetch rabbitmq metrics",
 				slog.String("error", err.Error()),
 			)
-			return fmt.Errorf("inserting into mongodb: %w", err)
+			return fmt.Errorf("failed to fetch rabbitmq metrics: %w", err)
 		}
+	} else {
 		slog.LogAttrs(
 			ctx,
-			slog.LevelDebug,
-			"rabbitmq: inserted document into mongodb",
-			slog.Any("insertedId", result.InsertedID),
-		)
-		return nil
-	}
-
-	metrics, err := r.GetMetrics(ctx)
-	if err != nil {
-		slog.LogAttrs(
-			ctx,
-			slog.LevelError,
-			"failed to fetch rabbitmq metrics",
-			slog.String("error", err.Error()),
+			slog.LevelInfo,
+			"rabbitmq cluster is down",
 		)
-		return fmt.Errorf("failed to fetch rabbitmq metrics: %w", err)
 	}
 	metrics.Timestamp = now
 
-	result, err := db.Database(r.mongo).
-		Collection(db.CollectionRabbitmq).
-		InsertOne(ctx, metrics)
+	id, err := r.store.Insert(ctx, db.CollectionRabbitmq, metrics)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"inserting into mongodb",
+			"inserting into database",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting into mongodb: %w", err)
+		return fmt.Errorf("inserting into database: %w", err)
 	}
 	slog.LogAttrs(
 		ctx,
 		slog.LevelDebug,
-		"rabbitmq: inserted document into mongodb",
-		slog.Any("insertedId", result.InsertedID),
+		"rabbitmq: inserted document into database",
+		slog.Any("insertedId", id),
 	)
 
-	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, metrics)
-	result, err = db.
-		Database(r.mongo).
-		Collection(db.CollectionAlerts).
-		InsertOne(ctx, bson.M{
-			"timestamp": now,
-			"from":      db.CollectionRabbitmq,
-			"alerts":    alerts,
-		})
+	history := report.NewHistory(r.store, db.CollectionRabbitmq, now, metrics)
+	alerts := report.SoftEvaluateAlerts(ctx, r.conf.Alerts, history)
+	err = report.StoreAlerts(ctx, r.store, db.CollectionRabbitmq, now, alerts)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"rabbitmq: inserting alerts into mongodb",
+			"rabbitmq: storing alerts",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting alerts into mongodb: %w", err)
+		return fmt.Errorf("storing alerts: %w", err)
 	}
-	slog.LogAttrs(
-		ctx,
-		slog.LevelDebug,
-		"rabbitmq: inserted alerts into mongodb",
-		slog.Any("insertedId", result.InsertedID),
-	)
 
 	return nil
 }
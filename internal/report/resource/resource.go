@@ -9,10 +9,9 @@ import (
 	"github.com/accuknox/rinc/internal/conf"
 	"github.com/accuknox/rinc/internal/db"
 	"github.com/accuknox/rinc/internal/report"
+	"github.com/accuknox/rinc/internal/store"
 	types "github.com/accuknox/rinc/types/resource"
 
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -29,7 +28,7 @@ type Config struct {
 	ResourceUtilizationConfig conf.ResourceUtilization
 	KubeClient                *kubernetes.Clientset
 	MetricsClient             *metrics.Clientset
-	MongoClient               *mongo.Client
+	Store                     store.Store
 }
 
 // NewReporter creates a new resource utilization reporter.
@@ -57,56 +56,41 @@ func (r Reporter) Report(ctx context.Context, now time.Time) error {
 		Containers: containers,
 	}
 
-	result, err := db.
-		Database(r.MongoClient).
-		Collection(db.CollectionResourceUtilization).
-		InsertOne(ctx, metrics)
+	id, err := r.Store.Insert(ctx, db.CollectionResourceUtilization, metrics)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"inserting into mongodb",
+			"inserting into database",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting into mongodb: %w", err)
+		return fmt.Errorf("inserting into database: %w", err)
 	}
 	slog.LogAttrs(
 		ctx,
 		slog.LevelDebug,
-		"resource: inserted document into mongodb",
-		slog.Any("insertedId", result.InsertedID),
+		"resource: inserted document into database",
+		slog.Any("insertedId", id),
 	)
 
+	history := report.NewHistory(r.Store, db.CollectionResourceUtilization, now, metrics)
 	alerts := report.SoftEvaluateAlerts(
 		ctx,
 		r.ResourceUtilizationConfig.Alerts,
-		metrics,
+		history,
 	)
-	result, err = db.
-		Database(r.MongoClient).
-		Collection(db.CollectionAlerts).
-		InsertOne(ctx, bson.M{
-			"timestamp": now,
-			"from":      db.CollectionResourceUtilization,
-			"alerts":    alerts,
-		})
+	err = report.StoreAlerts(ctx, r.Store, db.CollectionResourceUtilization, now, alerts)
 	if err != nil {
 		slog.LogAttrs(
 			ctx,
 			slog.LevelError,
-			"resource: inserting alerts into mongodb",
+			"resource: storing alerts",
 			slog.Time("timestamp", now),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("inserting alerts into mongodb: %w", err)
+		return fmt.Errorf("storing alerts: %w", err)
 	}
-	slog.LogAttrs(
-		ctx,
-		slog.LevelDebug,
-		"resource: inserted alerts into mongodb",
-		slog.Any("insertedId", result.InsertedID),
-	)
 
 	return nil
 }
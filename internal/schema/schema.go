@@ -22,34 +22,42 @@ func Generate(target string) ([]byte, error) {
 	r := new(jsonschema.Reflector)
 	r.FieldNameTag = "-"
 
-	var schema *jsonschema.Schema
+	metrics, err := New(target)
+	if err != nil {
+		return nil, err
+	}
+	schema := r.Reflect(metrics)
 
+	out, err := schema.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("marshalling schema to json: %w", err)
+	}
+	return out, nil
+}
+
+// New returns a pointer to a zero value of the metrics stored in the target
+// collection.
+func New(target string) (any, error) {
 	switch target {
 	case db.CollectionRabbitmq:
-		schema = r.Reflect(rabbitmq.Metrics{})
+		return new(rabbitmq.Metrics), nil
 	case db.CollectionCeph:
-		schema = r.Reflect(ceph.Metrics{})
+		return new(ceph.Metrics), nil
 	case db.CollectionImageTag:
-		schema = r.Reflect(imagetag.Metrics{})
+		return new(imagetag.Metrics), nil
 	case db.CollectionDass:
-		schema = r.Reflect(dass.Metrics{})
+		return new(dass.Metrics), nil
 	case db.CollectionLongJobs:
-		schema = r.Reflect(longjobs.Metrics{})
+		return new(longjobs.Metrics), nil
 	case db.CollectionPVUtilizaton:
-		schema = r.Reflect(pv.Metrics{})
+		return new(pv.Metrics), nil
 	case db.CollectionResourceUtilization:
-		schema = r.Reflect(resource.Metrics{})
+		return new(resource.Metrics), nil
 	case db.CollectionConnectivity:
-		schema = r.Reflect(connectivity.Metrics{})
+		return new(connectivity.Metrics), nil
 	case db.CollectionPodStatus:
-		schema = r.Reflect(pod.Metrics{})
+		return new(pod.Metrics), nil
 	default:
 		return nil, fmt.Errorf("invalid target: %q", target)
 	}
-
-	out, err := schema.MarshalJSON()
-	if err != nil {
-		return nil, fmt.Errorf("marshalling schema to json: %w", err)
-	}
-	return out, nil
 }
@@ -0,0 +1,723 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Bolt stores the documents in an embedded bbolt database file, so that
+// RINC runs without an external database.
+//
+// Every collection is a bucket holding two nested buckets: `docs`, mapping
+// the timestamp of each document followed by its ID to the document, and
+// `ids`, mapping the ID of each document to its key in `docs`. The alert
+// states are stored in the `alert_states` bucket, keyed by their ID.
+//
+// Documents are encoded in BSON, and their timestamps are truncated to
+// milliseconds, so that they behave the same as with MongoDB.
+//
+// The database file is locked by the process that opened it. The scraper and
+// the web server must therefore run in the same process, with
+// `rinc --daemon --serve`.
+type Bolt struct {
+	db *bolt.DB
+}
+
+var _ Store = Bolt{}
+
+// boltOpenTimeout is how long to wait for another process to release the
+// lock on the database file.
+const boltOpenTimeout = 10 * time.Second
+
+var (
+	bucketDocs = []byte("docs")
+	bucketIDs  = []byte("ids")
+)
+
+// errNoBucket is returned when writing to a bucket that is created by a
+// migration that was not applied.
+var errNoBucket = errors.New("bucket does not exist, the database must be migrated")
+
+// NewBolt opens the configured bbolt database file, creating it if it does
+// not exist.
+func NewBolt(c conf.Bolt) (Bolt, error) {
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o700); err != nil {
+		return Bolt{}, fmt.Errorf("creating directory of %q: %w", c.Path, err)
+	}
+	conn, err := bolt.Open(c.Path, 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return Bolt{}, fmt.Errorf("opening bolt database %q: %w", c.Path, err)
+	}
+	return Bolt{db: conn}, nil
+}
+
+// stampKey returns the key prefix of the documents written at the provided
+// timestamp, which sorts in chronological order.
+func stampKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixMilli())^1<<63)
+	return key
+}
+
+// idKey returns the key of the provided ID.
+func idKey(id int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+// docKey returns the key of a document in the `docs` bucket.
+func docKey(t time.Time, id int64) []byte {
+	return append(stampKey(t), idKey(id)...)
+}
+
+// parseDocKey returns the timestamp and the ID of a document from its key
+// in the `docs` bucket.
+func parseDocKey(key []byte) (time.Time, int64) {
+	stamp := int64(binary.BigEndian.Uint64(key[:8]) ^ 1<<63)
+	return time.UnixMilli(stamp).UTC(), int64(binary.BigEndian.Uint64(key[8:]))
+}
+
+// marshal returns the BSON representation of the document, along with its
+// `timestamp` field, if any.
+func marshal(doc any) (bson.Raw, time.Time, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("encoding document: %w", err)
+	}
+	var stamp time.Time
+	if dt, ok := bson.Raw(raw).Lookup("timestamp").DateTimeOK(); ok {
+		stamp = time.UnixMilli(dt).UTC()
+	}
+	return raw, stamp, nil
+}
+
+func unmarshal(data []byte, v any) error {
+	if err := bson.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decoding document: %w", err)
+	}
+	return nil
+}
+
+// bucket returns the nested bucket of the collection, or nil if the
+// collection does not exist.
+func bucket(tx *bolt.Tx, coll string, name []byte) *bolt.Bucket {
+	b := tx.Bucket([]byte(coll))
+	if b == nil {
+		return nil
+	}
+	return b.Bucket(name)
+}
+
+// Insert satisfies the Store interface.
+func (b Bolt) Insert(_ context.Context, coll string, doc any) (any, error) {
+	data, stamp, err := marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(coll))
+		if c == nil {
+			return errNoBucket
+		}
+		seq, err := c.NextSequence()
+		if err != nil {
+			return err
+		}
+		id = int64(seq)
+		key := docKey(stamp, id)
+		if err := c.Bucket(bucketDocs).Put(key, data); err != nil {
+			return err
+		}
+		return c.Bucket(bucketIDs).Put(idKey(id), key)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("inserting %q document: %w", coll, err)
+	}
+	return id, nil
+}
+
+// Find satisfies the Store interface.
+func (b Bolt) Find(_ context.Context, coll string, at time.Time, v any) error {
+	_, _, err := b.findOne(coll, stampKey(at), stampKey(ms(at).Add(time.Millisecond)), v)
+	return err
+}
+
+// FindByID satisfies the Store interface.
+func (b Bolt) FindByID(_ context.Context, coll, id string, v any) (time.Time, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing id %q: %w", id, err)
+	}
+	var stamp time.Time
+	err = b.db.View(func(tx *bolt.Tx) error {
+		ids := bucket(tx, coll, bucketIDs)
+		if ids == nil {
+			return ErrNotFound
+		}
+		key := ids.Get(idKey(n))
+		if key == nil {
+			return ErrNotFound
+		}
+		stamp, _ = parseDocKey(key)
+		if err := unmarshal(bucket(tx, coll, bucketDocs).Get(key), v); err != nil {
+			return fmt.Errorf("%q: %w", coll, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return stamp, nil
+}
+
+// DocumentID satisfies the Store interface.
+func (b Bolt) DocumentID(_ context.Context, coll string, at time.Time) (any, error) {
+	_, id, err := b.findOne(coll, stampKey(at), stampKey(ms(at).Add(time.Millisecond)), nil)
+	if err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// Latest satisfies the Store interface.
+func (b Bolt) Latest(_ context.Context, coll string, v any) (time.Time, error) {
+	stamp, _, err := b.findOne(coll, nil, nil, v)
+	return stamp, err
+}
+
+// Before satisfies the Store interface.
+func (b Bolt) Before(_ context.Context, coll string, before time.Time, v any) (time.Time, error) {
+	stamp, _, err := b.findOne(coll, nil, stampKey(before), v)
+	return stamp, err
+}
+
+// AtOrBefore satisfies the Store interface.
+func (b Bolt) AtOrBefore(_ context.Context, coll string, at time.Time, v any) (time.Time, error) {
+	stamp, _, err := b.findOne(coll, nil, stampKey(ms(at).Add(time.Millisecond)), v)
+	return stamp, err
+}
+
+// findOne decodes the last document of the collection whose key is between
+// from (inclusive) and to (exclusive) into v, unless v is nil, and returns
+// its timestamp and ID. Nil bounds leave the range unbounded.
+func (b Bolt) findOne(coll string, from, to []byte, v any) (time.Time, int64, error) {
+	var (
+		stamp time.Time
+		id    int64
+	)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		docs := bucket(tx, coll, bucketDocs)
+		if docs == nil {
+			return ErrNotFound
+		}
+		c := docs.Cursor()
+		var key, data []byte
+		if to == nil {
+			key, data = c.Last()
+		} else if key, _ = c.Seek(to); key == nil {
+			key, data = c.Last()
+		} else {
+			key, data = c.Prev()
+		}
+		if key == nil || (from != nil && bytes.Compare(key, from) < 0) {
+			return ErrNotFound
+		}
+		stamp, id = parseDocKey(key)
+		if v == nil {
+			return nil
+		}
+		if err := unmarshal(data, v); err != nil {
+			return fmt.Errorf("%q: %w", coll, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return stamp, id, nil
+}
+
+// List satisfies the Store interface.
+func (b Bolt) List(
+	_ context.Context,
+	coll string,
+	from, to time.Time,
+	offset, limit int,
+	newDoc func() any,
+) ([]any, int, error) {
+	var total int
+	docs := make([]any, 0, limit)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return eachDoc(tx, coll, stampKey(from), stampKey(to), func(_, data []byte) error {
+			total++
+			if total <= offset || (limit > 0 && len(docs) == limit) {
+				return nil
+			}
+			doc := newDoc()
+			if err := unmarshal(data, doc); err != nil {
+				return fmt.Errorf("%q: %w", coll, err)
+			}
+			docs = append(docs, doc)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("finding %q documents: %w", coll, err)
+	}
+	return docs, total, nil
+}
+
+// eachDoc calls fn with the key and the value of every document of the
+// collection whose key is between from (inclusive) and to (exclusive),
+// oldest first. A nil from leaves the range unbounded.
+func eachDoc(tx *bolt.Tx, coll string, from, to []byte, fn func(key, data []byte) error) error {
+	docs := bucket(tx, coll, bucketDocs)
+	if docs == nil {
+		return nil
+	}
+	c := docs.Cursor()
+	key, data := c.First()
+	if from != nil {
+		key, data = c.Seek(from)
+	}
+	for ; key != nil && bytes.Compare(key, to) < 0; key, data = c.Next() {
+		if err := fn(key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Timestamps satisfies the Store interface.
+func (b Bolt) Timestamps(_ context.Context, coll string, from, to time.Time) ([]time.Time, error) {
+	var lower []byte
+	if !from.IsZero() {
+		lower = stampKey(from)
+	}
+	var stamps []time.Time
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return eachDoc(tx, coll, lower, stampKey(to), func(key, _ []byte) error {
+			stamp, _ := parseDocKey(key)
+			if len(stamps) == 0 || !stamps[len(stamps)-1].Equal(stamp) {
+				stamps = append(stamps, stamp)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding %q timestamps: %w", coll, err)
+	}
+	return stamps, nil
+}
+
+// DeleteBefore satisfies the Store interface.
+func (b Bolt) DeleteBefore(_ context.Context, coll string, before time.Time) (int64, error) {
+	return b.delete(coll, func(tx *bolt.Tx) ([][]byte, error) {
+		var keys [][]byte
+		err := eachDoc(tx, coll, nil, stampKey(before), func(key, _ []byte) error {
+			keys = append(keys, slices.Clone(key))
+			return nil
+		})
+		return keys, err
+	})
+}
+
+// DeleteAt satisfies the Store interface.
+func (b Bolt) DeleteAt(_ context.Context, coll string, stamps []time.Time) (int64, error) {
+	return b.delete(coll, func(tx *bolt.Tx) ([][]byte, error) {
+		var keys [][]byte
+		for _, t := range stamps {
+			err := eachDoc(tx, coll, stampKey(t), stampKey(ms(t).Add(time.Millisecond)), func(key, _ []byte) error {
+				keys = append(keys, slices.Clone(key))
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return keys, nil
+	})
+}
+
+// delete deletes the documents of the collection whose keys are returned by
+// the provided function, and returns the number of deleted documents.
+func (b Bolt) delete(coll string, find func(tx *bolt.Tx) ([][]byte, error)) (int64, error) {
+	var deleted int64
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		keys, err := find(tx)
+		if err != nil || len(keys) == 0 {
+			return err
+		}
+		docs, ids := bucket(tx, coll, bucketDocs), bucket(tx, coll, bucketIDs)
+		for _, key := range keys {
+			_, id := parseDocKey(key)
+			if err := docs.Delete(key); err != nil {
+				return err
+			}
+			if err := ids.Delete(idKey(id)); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("deleting %q documents: %w", coll, err)
+	}
+	return deleted, nil
+}
+
+// Series satisfies the Store interface.
+func (b Bolt) Series(_ context.Context, coll string, from, to time.Time, q SeriesQuery) ([]Point, error) {
+	type pointKey struct {
+		at     time.Time
+		series string
+	}
+	points := []Point{}
+	summed := make(map[pointKey]int)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return eachDoc(tx, coll, stampKey(from), stampKey(to), func(key, data []byte) error {
+			stamp, _ := parseDocKey(key)
+			for _, pt := range seriesPoints(q, stamp, bson.Raw(data)) {
+				if len(q.Count) == 0 {
+					points = append(points, pt)
+					continue
+				}
+				// the counts of the documents written at the same time
+				// are summed.
+				k := pointKey{at: pt.Timestamp, series: pt.Series}
+				if idx, ok := summed[k]; ok {
+					points[idx].Value += pt.Value
+					continue
+				}
+				summed[k] = len(points)
+				points = append(points, pt)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aggregating %q documents: %w", coll, err)
+	}
+	return points, nil
+}
+
+// seriesPoints returns the points selected by the query from the document
+// written at the provided timestamp.
+func seriesPoints(q SeriesQuery, stamp time.Time, doc bson.Raw) []Point {
+	switch {
+	case len(q.Fields) != 0:
+		points := make([]Point, 0, len(q.Fields))
+		for _, f := range q.Fields {
+			points = append(points, Point{
+				Timestamp: stamp,
+				Series:    f.Name,
+				Value:     float(lookup(doc, f.Path)),
+			})
+		}
+		return points
+
+	case len(q.Count) != 0:
+		elems := elements(lookup(doc, q.List))
+		points := make([]Point, 0, len(q.Count))
+		for _, value := range q.Count {
+			var count int
+			for _, e := range elems {
+				if s, ok := lookup(e, q.CountBy).StringValueOK(); ok && s == value {
+					count++
+				}
+			}
+			points = append(points, Point{Timestamp: stamp, Series: value, Value: float64(count)})
+		}
+		return points
+
+	default:
+		elems := elements(lookup(doc, q.List))
+		points := make([]Point, 0, len(elems))
+		for _, e := range elems {
+			names := make([]string, 0, len(q.Name))
+			for _, n := range q.Name {
+				s, _ := lookup(e, n).StringValueOK()
+				names = append(names, s)
+			}
+			points = append(points, Point{
+				Timestamp: stamp,
+				Series:    strings.Join(names, "/"),
+				Value:     float(lookup(e, q.Value)),
+			})
+		}
+		return points
+	}
+}
+
+// lookup returns the value at the dot-separated path of the document.
+func lookup(doc bson.Raw, path string) bson.RawValue {
+	return doc.Lookup(strings.Split(path, ".")...)
+}
+
+// elements returns the documents of the array, or nil if the value is not
+// an array.
+func elements(v bson.RawValue) []bson.Raw {
+	arr, ok := v.ArrayOK()
+	if !ok {
+		return nil
+	}
+	values, err := arr.Values()
+	if err != nil {
+		return nil
+	}
+	docs := make([]bson.Raw, 0, len(values))
+	for _, v := range values {
+		if doc, ok := v.DocumentOK(); ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// float returns the value as a float, or 0 if it is not a number.
+func float(v bson.RawValue) float64 {
+	switch v.Type {
+	case bson.TypeDouble:
+		return v.Double()
+	case bson.TypeInt32:
+		return float64(v.Int32())
+	case bson.TypeInt64:
+		return float64(v.Int64())
+	default:
+		return 0
+	}
+}
+
+// Alerts satisfies the Store interface.
+func (b Bolt) Alerts(_ context.Context, at time.Time, from string) ([]db.AlertDocument, error) {
+	docs := []db.AlertDocument{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		upper := stampKey(ms(at).Add(time.Millisecond))
+		return eachDoc(tx, db.CollectionAlerts, stampKey(at), upper, func(_, data []byte) error {
+			if s, _ := bson.Raw(data).Lookup("from").StringValueOK(); from != "" && s != from {
+				return nil
+			}
+			var doc db.AlertDocument
+			if err := unmarshal(data, &doc); err != nil {
+				return fmt.Errorf("alerts: %w", err)
+			}
+			docs = append(docs, doc)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding alerts at %v: %w", at, err)
+	}
+	return docs, nil
+}
+
+// ActiveAlertStates satisfies the Store interface.
+func (b Bolt) ActiveAlertStates(_ context.Context, from string) ([]db.AlertStateDocument, error) {
+	var states []db.AlertStateDocument
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return eachAlertState(tx, func(_ []byte, s db.AlertStateDocument) error {
+			if s.From == from && active(s.State) {
+				states = append(states, s)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding alert states: %w", err)
+	}
+	return states, nil
+}
+
+// active reports whether the state is pending or firing.
+func active(s db.AlertState) bool {
+	return s == db.AlertStatePending || s == db.AlertStateFiring
+}
+
+// SaveAlertState satisfies the Store interface.
+func (b Bolt) SaveAlertState(_ context.Context, s db.AlertStateDocument) error {
+	data, _, err := marshal(s)
+	if err != nil {
+		return fmt.Errorf("alert state %q: %w", s.Fingerprint, err)
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		var key []byte
+		err := eachAlertState(tx, func(k []byte, state db.AlertStateDocument) error {
+			if key == nil && state.Fingerprint == s.Fingerprint && active(state.State) {
+				key = slices.Clone(k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		states := tx.Bucket([]byte(db.CollectionAlertStates))
+		if states == nil {
+			return errNoBucket
+		}
+		if key == nil {
+			id, err := states.NextSequence()
+			if err != nil {
+				return err
+			}
+			key = idKey(int64(id))
+		}
+		return states.Put(key, data)
+	})
+	if err != nil {
+		return fmt.Errorf("upserting alert state %q: %w", s.Fingerprint, err)
+	}
+	return nil
+}
+
+// ResolveAlertStates satisfies the Store interface.
+func (b Bolt) ResolveAlertStates(_ context.Context, fingerprints []string, at time.Time) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		resolved := make(map[string][]byte)
+		err := eachAlertState(tx, func(key []byte, s db.AlertStateDocument) error {
+			if s.State != db.AlertStateFiring || !slices.Contains(fingerprints, s.Fingerprint) {
+				return nil
+			}
+			s.State = db.AlertStateResolved
+			s.ResolvedAt = at
+			data, _, err := marshal(s)
+			if err != nil {
+				return fmt.Errorf("alert state %q: %w", s.Fingerprint, err)
+			}
+			resolved[string(key)] = data
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		states := tx.Bucket([]byte(db.CollectionAlertStates))
+		for key, data := range resolved {
+			if err := states.Put([]byte(key), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("resolving alert states: %w", err)
+	}
+	return nil
+}
+
+// DropAlertStates satisfies the Store interface.
+func (b Bolt) DropAlertStates(_ context.Context, fingerprints []string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		var keys [][]byte
+		err := eachAlertState(tx, func(key []byte, s db.AlertStateDocument) error {
+			if s.State == db.AlertStatePending && slices.Contains(fingerprints, s.Fingerprint) {
+				keys = append(keys, slices.Clone(key))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		states := tx.Bucket([]byte(db.CollectionAlertStates))
+		for _, key := range keys {
+			if err := states.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("deleting pending alert states: %w", err)
+	}
+	return nil
+}
+
+// AlertStates satisfies the Store interface.
+func (b Bolt) AlertStates(_ context.Context, from string, at time.Time) ([]db.AlertStateDocument, error) {
+	at = ms(at)
+	var states []db.AlertStateDocument
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return eachAlertState(tx, func(_ []byte, s db.AlertStateDocument) error {
+			if s.From != from {
+				return nil
+			}
+			if (s.State == db.AlertStateFiring && s.LastSeen.Equal(at)) ||
+				(s.State == db.AlertStateResolved && s.ResolvedAt.Equal(at)) {
+				states = append(states, s)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding alert states: %w", err)
+	}
+	return states, nil
+}
+
+// DeleteResolvedAlertStates satisfies the Store interface.
+func (b Bolt) DeleteResolvedAlertStates(_ context.Context, before time.Time) (int64, error) {
+	before = ms(before)
+	var deleted int64
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		var keys [][]byte
+		err := eachAlertState(tx, func(key []byte, s db.AlertStateDocument) error {
+			if s.State == db.AlertStateResolved && s.ResolvedAt.Before(before) {
+				keys = append(keys, slices.Clone(key))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		states := tx.Bucket([]byte(db.CollectionAlertStates))
+		for _, key := range keys {
+			if err := states.Delete(key); err != nil {
+				return err
+			}
+		}
+		deleted = int64(len(keys))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("deleting resolved alert states: %w", err)
+	}
+	return deleted, nil
+}
+
+// eachAlertState calls fn with the key and the decoded value of every alert
+// state, in the order they were inserted.
+func eachAlertState(tx *bolt.Tx, fn func(key []byte, s db.AlertStateDocument) error) error {
+	states := tx.Bucket([]byte(db.CollectionAlertStates))
+	if states == nil {
+		return nil
+	}
+	return states.ForEach(func(key, data []byte) error {
+		var s db.AlertStateDocument
+		if err := unmarshal(data, &s); err != nil {
+			return fmt.Errorf("alert state: %w", err)
+		}
+		return fn(key, s)
+	})
+}
+
+// Close satisfies the Store interface.
+func (b Bolt) Close(context.Context) error {
+	if err := b.db.Close(); err != nil {
+		return fmt.Errorf("closing bolt database: %w", err)
+	}
+	return nil
+}
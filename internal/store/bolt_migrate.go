@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/accuknox/rinc/internal/db"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltMigrations upgrade the database, boltMigrations[i] upgrading it to
+// version i+1. Migrations must never be removed or reordered.
+var boltMigrations = []struct {
+	description string
+	up          func(tx *bolt.Tx) error
+}{
+	{
+		description: "create buckets",
+		up:          createBuckets,
+	},
+}
+
+// createBuckets creates the buckets of the collections and of the alert
+// states.
+func createBuckets(tx *bolt.Tx) error {
+	colls := append([]string{db.CollectionRuns, db.CollectionAlerts}, db.Collections...)
+	for _, coll := range colls {
+		b, err := tx.CreateBucketIfNotExists([]byte(coll))
+		if err != nil {
+			return fmt.Errorf("creating %q bucket: %w", coll, err)
+		}
+		for _, name := range [][]byte{bucketDocs, bucketIDs} {
+			if _, err := b.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("creating %q bucket: %w", coll, err)
+			}
+		}
+	}
+	if _, err := tx.CreateBucketIfNotExists([]byte(db.CollectionAlertStates)); err != nil {
+		return fmt.Errorf("creating %q bucket: %w", db.CollectionAlertStates, err)
+	}
+	return nil
+}
+
+// Migrate satisfies the Store interface. Each migration is applied in a
+// transaction, along with the record of the new schema version in the
+// `migrations` bucket.
+func (b Bolt) Migrate(ctx context.Context) (int, int, error) {
+	from, err := b.version()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	version := from
+	for version < len(boltMigrations) {
+		m := boltMigrations[version]
+		err := b.db.Update(func(tx *bolt.Tx) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			migrations, err := tx.CreateBucketIfNotExists([]byte(db.CollectionMigrations))
+			if err != nil {
+				return err
+			}
+			data, _, err := marshal(db.MigrationDocument{
+				Version:     version + 1,
+				Description: m.description,
+				AppliedAt:   time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+			return migrations.Put(idKey(int64(version+1)), data)
+		})
+		if err != nil {
+			return from, version, fmt.Errorf("migrating to version %d (%s): %w", version+1, m.description, err)
+		}
+		slog.LogAttrs(
+			ctx,
+			slog.LevelInfo,
+			"migrated database",
+			slog.Int("version", version+1),
+			slog.String("description", m.description),
+		)
+		version++
+	}
+	if from > len(boltMigrations) {
+		slog.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"database schema is newer than this version of rinc",
+			slog.Int("version", from),
+			slog.Int("expected", len(boltMigrations)),
+		)
+	}
+	return from, version, nil
+}
+
+// version returns the schema version recorded in the database, which is 0
+// if no migration was ever applied.
+func (b Bolt) version() (int, error) {
+	var version int
+	err := b.db.View(func(tx *bolt.Tx) error {
+		migrations := tx.Bucket([]byte(db.CollectionMigrations))
+		if migrations == nil {
+			return nil
+		}
+		_, data := migrations.Cursor().Last()
+		if data == nil {
+			return nil
+		}
+		var m db.MigrationDocument
+		if err := unmarshal(data, &m); err != nil {
+			return err
+		}
+		version = m.Version
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("finding schema version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/types/pv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBolt(t *testing.T) Bolt {
+	t.Helper()
+	b, err := NewBolt(conf.Bolt{Path: filepath.Join(t.TempDir(), "rinc.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close(context.Background()) })
+	if _, _, err := b.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBoltDocuments(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	b := newBolt(t)
+	start := time.Date(2024, 7, 1, 12, 0, 0, 123456789, time.UTC)
+
+	for idx := range 3 {
+		_, err := b.Insert(ctx, db.CollectionPVUtilizaton, pv.Metrics{
+			Timestamp: start.Add(time.Duration(idx) * time.Hour),
+			PVs:       pv.PVs{{PVC: "data", PVCNamespace: "mongo", UtilizationPercent: float64(10 * (idx + 1))}},
+		})
+		a.NoError(err)
+	}
+
+	var m pv.Metrics
+	a.NoError(b.Find(ctx, db.CollectionPVUtilizaton, start.Add(time.Hour), &m))
+	a.Equal(20.0, m.PVs[0].UtilizationPercent)
+	a.ErrorIs(b.Find(ctx, db.CollectionPVUtilizaton, start.Add(time.Minute), nil), ErrNotFound)
+
+	id, err := b.DocumentID(ctx, db.CollectionPVUtilizaton, start)
+	a.NoError(err)
+	at, err := b.FindByID(ctx, db.CollectionPVUtilizaton, fmt.Sprint(id), &m)
+	a.NoError(err)
+	a.Equal(ms(start), at)
+	a.Equal(10.0, m.PVs[0].UtilizationPercent)
+
+	at, err = b.Latest(ctx, db.CollectionPVUtilizaton, nil)
+	a.NoError(err)
+	a.Equal(ms(start.Add(2*time.Hour)), at)
+	at, err = b.Before(ctx, db.CollectionPVUtilizaton, start.Add(2*time.Hour), nil)
+	a.NoError(err)
+	a.Equal(ms(start.Add(time.Hour)), at)
+	at, err = b.AtOrBefore(ctx, db.CollectionPVUtilizaton, start.Add(time.Hour), nil)
+	a.NoError(err)
+	a.Equal(ms(start.Add(time.Hour)), at)
+	_, err = b.Before(ctx, db.CollectionPVUtilizaton, start, nil)
+	a.ErrorIs(err, ErrNotFound)
+	_, err = b.Latest(ctx, db.CollectionCeph, nil)
+	a.ErrorIs(err, ErrNotFound)
+
+	docs, total, err := b.List(ctx, db.CollectionPVUtilizaton, start, start.Add(3*time.Hour), 1, 1, func() any {
+		return new(pv.Metrics)
+	})
+	a.NoError(err)
+	a.Equal(3, total)
+	a.Len(docs, 1)
+	a.Equal(20.0, docs[0].(*pv.Metrics).PVs[0].UtilizationPercent)
+
+	stamps, err := b.Timestamps(ctx, db.CollectionPVUtilizaton, time.Time{}, start.Add(2*time.Hour))
+	a.NoError(err)
+	a.Equal([]time.Time{ms(start), ms(start.Add(time.Hour))}, stamps)
+
+	points, err := b.Series(ctx, db.CollectionPVUtilizaton, start, start.Add(3*time.Hour), SeriesQuery{
+		List:  "pvs",
+		Name:  []string{"pvcnamespace", "pvc"},
+		Value: "utilizationpercent",
+	})
+	a.NoError(err)
+	a.Len(points, 3)
+	a.Equal(Point{Timestamp: ms(start), Series: "mongo/data", Value: 10}, points[0])
+
+	deleted, err := b.DeleteAt(ctx, db.CollectionPVUtilizaton, []time.Time{start.Add(time.Hour)})
+	a.NoError(err)
+	a.Equal(int64(1), deleted)
+	deleted, err = b.DeleteBefore(ctx, db.CollectionPVUtilizaton, start.Add(2*time.Hour))
+	a.NoError(err)
+	a.Equal(int64(1), deleted)
+	_, err = b.FindByID(ctx, db.CollectionPVUtilizaton, fmt.Sprint(id), &m)
+	a.ErrorIs(err, ErrNotFound)
+}
+
+func TestBoltAlerts(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	b := newBolt(t)
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, from := range []string{"ceph", "pvUtilization"} {
+		_, err := b.Insert(ctx, db.CollectionAlerts, db.AlertDocument{
+			Timestamp: now,
+			From:      from,
+			Alerts: []db.Alert{
+				{Message: "a", Severity: conf.SeverityCritical},
+				{Message: "b", Severity: conf.SeverityWarning},
+			},
+		})
+		a.NoError(err)
+	}
+	docs, err := b.Alerts(ctx, now, "ceph")
+	a.NoError(err)
+	a.Len(docs, 1)
+	docs, err = b.Alerts(ctx, now, "")
+	a.NoError(err)
+	a.Len(docs, 2)
+
+	points, err := b.Series(ctx, db.CollectionAlerts, now, now.Add(time.Hour), SeriesQuery{
+		List:    "alerts",
+		CountBy: "severity",
+		Count:   []string{string(conf.SeverityCritical), string(conf.SeverityWarning)},
+	})
+	a.NoError(err)
+	a.Equal([]Point{
+		{Timestamp: now, Series: string(conf.SeverityCritical), Value: 2},
+		{Timestamp: now, Series: string(conf.SeverityWarning), Value: 2},
+	}, points)
+
+	for _, fp := range []string{"pending", "firing"} {
+		state := db.AlertStatePending
+		if fp == "firing" {
+			state = db.AlertStateFiring
+		}
+		a.NoError(b.SaveAlertState(ctx, db.AlertStateDocument{
+			Fingerprint: fp,
+			From:        "ceph",
+			State:       state,
+			LastSeen:    now,
+		}))
+	}
+	a.NoError(b.SaveAlertState(ctx, db.AlertStateDocument{
+		Fingerprint: "firing",
+		From:        "ceph",
+		State:       db.AlertStateFiring,
+		LastSeen:    now,
+		Evaluations: 2,
+	}))
+	states, err := b.ActiveAlertStates(ctx, "ceph")
+	a.NoError(err)
+	a.Len(states, 2)
+	a.Equal(2, states[1].Evaluations)
+
+	a.NoError(b.DropAlertStates(ctx, []string{"pending", "firing"}))
+	a.NoError(b.ResolveAlertStates(ctx, []string{"firing"}, now.Add(time.Hour)))
+	states, err = b.ActiveAlertStates(ctx, "ceph")
+	a.NoError(err)
+	a.Empty(states)
+	states, err = b.AlertStates(ctx, "ceph", now.Add(time.Hour))
+	a.NoError(err)
+	a.Len(states, 1)
+	a.Equal(db.AlertStateResolved, states[0].State)
+
+	deleted, err := b.DeleteResolvedAlertStates(ctx, now.Add(time.Hour))
+	a.NoError(err)
+	a.Zero(deleted)
+	deleted, err = b.DeleteResolvedAlertStates(ctx, now.Add(2*time.Hour))
+	a.NoError(err)
+	a.Equal(int64(1), deleted)
+	states, err = b.AlertStates(ctx, "ceph", now.Add(time.Hour))
+	a.NoError(err)
+	a.Empty(states)
+}
@@ -0,0 +1,414 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Mongo stores the documents in the collections of the `rinc` MongoDB
+// database.
+type Mongo struct {
+	client *mongo.Client
+}
+
+var _ Store = Mongo{}
+
+// NewMongo connects to the configured MongoDB instance.
+func NewMongo(c conf.Mongodb) (Mongo, error) {
+	client, err := db.NewMongoDBClient(c)
+	if err != nil {
+		return Mongo{}, err
+	}
+	return Mongo{client: client}, nil
+}
+
+// Client returns the underlying MongoDB client.
+func (m Mongo) Client() *mongo.Client {
+	return m.client
+}
+
+func (m Mongo) coll(name string) *mongo.Collection {
+	return db.Database(m.client).Collection(name)
+}
+
+// Insert satisfies the Store interface.
+func (m Mongo) Insert(ctx context.Context, coll string, doc any) (any, error) {
+	result, err := m.coll(coll).InsertOne(ctx, doc)
+	if err != nil {
+		return nil, fmt.Errorf("inserting %q document: %w", coll, err)
+	}
+	return result.InsertedID, nil
+}
+
+// Find satisfies the Store interface.
+func (m Mongo) Find(ctx context.Context, coll string, at time.Time, v any) error {
+	_, err := m.findOne(ctx, coll, bson.M{"timestamp": at}, v)
+	return err
+}
+
+// FindByID satisfies the Store interface.
+func (m Mongo) FindByID(ctx context.Context, coll, id string, v any) (time.Time, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing id %q: %w", id, err)
+	}
+	return m.findOne(ctx, coll, bson.M{"_id": oid}, v)
+}
+
+// DocumentID satisfies the Store interface.
+func (m Mongo) DocumentID(ctx context.Context, coll string, at time.Time) (any, error) {
+	doc := struct {
+		ID any `bson:"_id"`
+	}{}
+	_, err := m.findOne(ctx, coll, bson.M{"timestamp": at}, &doc)
+	if err != nil {
+		return nil, err
+	}
+	return doc.ID, nil
+}
+
+// Latest satisfies the Store interface.
+func (m Mongo) Latest(ctx context.Context, coll string, v any) (time.Time, error) {
+	return m.findOne(ctx, coll, bson.M{}, v)
+}
+
+// Before satisfies the Store interface.
+func (m Mongo) Before(ctx context.Context, coll string, before time.Time, v any) (time.Time, error) {
+	return m.findOne(ctx, coll, bson.M{"timestamp": bson.M{"$lt": before}}, v)
+}
+
+// AtOrBefore satisfies the Store interface.
+func (m Mongo) AtOrBefore(ctx context.Context, coll string, at time.Time, v any) (time.Time, error) {
+	return m.findOne(ctx, coll, bson.M{"timestamp": bson.M{"$lte": at}}, v)
+}
+
+// findOne decodes the latest document matching the filter into v, unless v
+// is nil, and returns its timestamp.
+func (m Mongo) findOne(ctx context.Context, coll string, filter bson.M, v any) (time.Time, error) {
+	opts := options.FindOne().SetSort(bson.M{"timestamp": -1})
+	if v == nil {
+		opts.SetProjection(bson.M{"timestamp": 1})
+	}
+	result := m.coll(coll).FindOne(ctx, filter, opts)
+	if err := result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return time.Time{}, ErrNotFound
+		}
+		return time.Time{}, fmt.Errorf("finding %q document: %w", coll, err)
+	}
+	if v != nil {
+		if err := result.Decode(v); err != nil {
+			return time.Time{}, fmt.Errorf("decoding %q document: %w", coll, err)
+		}
+	}
+	var stamp struct {
+		Timestamp time.Time `bson:"timestamp"`
+	}
+	if err := result.Decode(&stamp); err != nil {
+		return time.Time{}, fmt.Errorf("decoding timestamp of %q document: %w", coll, err)
+	}
+	return stamp.Timestamp, nil
+}
+
+// List satisfies the Store interface.
+func (m Mongo) List(
+	ctx context.Context,
+	coll string,
+	from, to time.Time,
+	offset, limit int,
+	newDoc func() any,
+) ([]any, int, error) {
+	filter := timeRange(from, to)
+	c := m.coll(coll)
+	total, err := c.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("counting %q documents: %w", coll, err)
+	}
+	cursor, err := c.Find(ctx, filter, options.
+		Find().
+		SetSort(bson.M{"timestamp": 1}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("finding %q documents: %w", coll, err)
+	}
+	defer cursor.Close(ctx)
+
+	docs := make([]any, 0, limit)
+	for cursor.Next(ctx) {
+		doc := newDoc()
+		if err := cursor.Decode(doc); err != nil {
+			return nil, 0, fmt.Errorf("decoding document at cursor: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterating %q documents: %w", coll, err)
+	}
+	return docs, int(total), nil
+}
+
+// Timestamps satisfies the Store interface.
+func (m Mongo) Timestamps(ctx context.Context, coll string, from, to time.Time) ([]time.Time, error) {
+	var stamps []time.Time
+	err := m.coll(coll).
+		Distinct(ctx, "timestamp", timeRange(from, to)).
+		Decode(&stamps)
+	if err != nil {
+		return nil, fmt.Errorf("finding %q timestamps: %w", coll, err)
+	}
+	return stamps, nil
+}
+
+// DeleteBefore satisfies the Store interface.
+func (m Mongo) DeleteBefore(ctx context.Context, coll string, before time.Time) (int64, error) {
+	result, err := m.coll(coll).DeleteMany(ctx, bson.M{
+		"timestamp": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("deleting %q documents: %w", coll, err)
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteAt satisfies the Store interface.
+func (m Mongo) DeleteAt(ctx context.Context, coll string, stamps []time.Time) (int64, error) {
+	result, err := m.coll(coll).DeleteMany(ctx, bson.M{
+		"timestamp": bson.M{"$in": stamps},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("deleting %q documents: %w", coll, err)
+	}
+	return result.DeletedCount, nil
+}
+
+// timeRange returns the filter matching the documents written between from
+// (inclusive) and to (exclusive). A zero from leaves the range unbounded.
+func timeRange(from, to time.Time) bson.M {
+	stamp := bson.M{"$lt": to}
+	if !from.IsZero() {
+		stamp["$gte"] = from
+	}
+	return bson.M{"timestamp": stamp}
+}
+
+// Series satisfies the Store interface.
+func (m Mongo) Series(ctx context.Context, coll string, from, to time.Time, q SeriesQuery) ([]Point, error) {
+	pipeline := append([]bson.M{{"$match": timeRange(from, to)}}, seriesStages(q)...)
+	cursor, err := m.coll(coll).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("aggregating %q documents: %w", coll, err)
+	}
+	points := []Point{}
+	if err := cursor.All(ctx, &points); err != nil {
+		return nil, fmt.Errorf("decoding %q aggregation: %w", coll, err)
+	}
+	return points, nil
+}
+
+// seriesStages returns the aggregation stages reshaping the documents into
+// the points selected by the query.
+func seriesStages(q SeriesQuery) []bson.M {
+	switch {
+	case len(q.Fields) != 0:
+		points := make(bson.A, 0, len(q.Fields))
+		for _, f := range q.Fields {
+			points = append(points, bson.M{
+				"series": f.Name,
+				"value":  bson.M{"$toDouble": bson.M{"$ifNull": bson.A{"$" + f.Path, 0}}},
+			})
+		}
+		return []bson.M{
+			{"$project": bson.M{"timestamp": 1, "points": points}},
+			{"$unwind": "$points"},
+			{"$project": bson.M{
+				"_id":       0,
+				"timestamp": 1,
+				"series":    "$points.series",
+				"value":     "$points.value",
+			}},
+		}
+
+	case len(q.Count) != 0:
+		points := make(bson.A, 0, len(q.Count))
+		for _, value := range q.Count {
+			points = append(points, bson.M{
+				"series": value,
+				"value": bson.M{"$size": bson.M{"$filter": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$" + q.List, bson.A{}}},
+					"cond":  bson.M{"$eq": bson.A{"$$this." + q.CountBy, value}},
+				}}},
+			})
+		}
+		return []bson.M{
+			{"$project": bson.M{"timestamp": 1, "points": points}},
+			{"$unwind": "$points"},
+			{"$group": bson.M{
+				"_id":   bson.M{"timestamp": "$timestamp", "series": "$points.series"},
+				"value": bson.M{"$sum": "$points.value"},
+			}},
+			{"$project": bson.M{
+				"_id":       0,
+				"timestamp": "$_id.timestamp",
+				"series":    "$_id.series",
+				"value":     bson.M{"$toDouble": "$value"},
+			}},
+		}
+
+	default:
+		var series any = "$" + q.List + "." + q.Name[0]
+		if len(q.Name) > 1 {
+			parts := make(bson.A, 0, 2*len(q.Name)-1)
+			for idx, name := range q.Name {
+				if idx != 0 {
+					parts = append(parts, "/")
+				}
+				parts = append(parts, "$"+q.List+"."+name)
+			}
+			series = bson.M{"$concat": parts}
+		}
+		return []bson.M{
+			{"$unwind": "$" + q.List},
+			{"$project": bson.M{
+				"_id":       0,
+				"timestamp": 1,
+				"series":    series,
+				"value":     bson.M{"$toDouble": "$" + q.List + "." + q.Value},
+			}},
+		}
+	}
+}
+
+// Alerts satisfies the Store interface.
+func (m Mongo) Alerts(ctx context.Context, at time.Time, from string) ([]db.AlertDocument, error) {
+	filter := bson.M{"timestamp": at}
+	if from != "" {
+		filter["from"] = from
+	}
+	cursor, err := m.coll(db.CollectionAlerts).Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("finding alerts at %v: %w", at, err)
+	}
+	docs := []db.AlertDocument{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("decoding alert documents: %w", err)
+	}
+	return docs, nil
+}
+
+// activeStates matches the pending and firing alert states.
+var activeStates = bson.M{"$in": bson.A{db.AlertStatePending, db.AlertStateFiring}}
+
+// ActiveAlertStates satisfies the Store interface.
+func (m Mongo) ActiveAlertStates(ctx context.Context, from string) ([]db.AlertStateDocument, error) {
+	return m.findAlertStates(ctx, bson.M{
+		"from":  from,
+		"state": activeStates,
+	})
+}
+
+// SaveAlertState satisfies the Store interface.
+func (m Mongo) SaveAlertState(ctx context.Context, s db.AlertStateDocument) error {
+	_, err := m.coll(db.CollectionAlertStates).ReplaceOne(
+		ctx,
+		bson.M{
+			"fingerprint": s.Fingerprint,
+			"state":       activeStates,
+		},
+		s,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("upserting alert state %q: %w", s.Fingerprint, err)
+	}
+	return nil
+}
+
+// ResolveAlertStates satisfies the Store interface.
+func (m Mongo) ResolveAlertStates(ctx context.Context, fingerprints []string, at time.Time) error {
+	_, err := m.coll(db.CollectionAlertStates).UpdateMany(
+		ctx,
+		bson.M{
+			"fingerprint": bson.M{"$in": fingerprints},
+			"state":       db.AlertStateFiring,
+		},
+		bson.M{"$set": bson.M{
+			"state":      db.AlertStateResolved,
+			"resolvedAt": at,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("resolving alert states: %w", err)
+	}
+	return nil
+}
+
+// DropAlertStates satisfies the Store interface.
+func (m Mongo) DropAlertStates(ctx context.Context, fingerprints []string) error {
+	_, err := m.coll(db.CollectionAlertStates).DeleteMany(ctx, bson.M{
+		"fingerprint": bson.M{"$in": fingerprints},
+		"state":       db.AlertStatePending,
+	})
+	if err != nil {
+		return fmt.Errorf("deleting pending alert states: %w", err)
+	}
+	return nil
+}
+
+// AlertStates satisfies the Store interface.
+func (m Mongo) AlertStates(ctx context.Context, from string, at time.Time) ([]db.AlertStateDocument, error) {
+	return m.findAlertStates(ctx, bson.M{
+		"from": from,
+		"$or": bson.A{
+			bson.M{"state": db.AlertStateFiring, "lastSeen": at},
+			bson.M{"state": db.AlertStateResolved, "resolvedAt": at},
+		},
+	})
+}
+
+// DeleteResolvedAlertStates satisfies the Store interface.
+func (m Mongo) DeleteResolvedAlertStates(ctx context.Context, before time.Time) (int64, error) {
+	result, err := m.coll(db.CollectionAlertStates).DeleteMany(ctx, bson.M{
+		"state":      db.AlertStateResolved,
+		"resolvedAt": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("deleting resolved alert states: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+func (m Mongo) findAlertStates(ctx context.Context, filter bson.M) ([]db.AlertStateDocument, error) {
+	cursor, err := m.coll(db.CollectionAlertStates).Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("finding alert states: %w", err)
+	}
+	var states []db.AlertStateDocument
+	if err := cursor.All(ctx, &states); err != nil {
+		return nil, fmt.Errorf("decoding alert states: %w", err)
+	}
+	return states, nil
+}
+
+// Migrate satisfies the Store interface.
+func (m Mongo) Migrate(ctx context.Context) (int, int, error) {
+	return db.Migrate(ctx, m.client)
+}
+
+// Close satisfies the Store interface.
+func (m Mongo) Close(ctx context.Context) error {
+	if err := m.client.Disconnect(ctx); err != nil {
+		return fmt.Errorf("disconnecting from mongodb: %w", err)
+	}
+	return nil
+}
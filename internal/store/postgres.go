@@ -0,0 +1,566 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Postgres stores the documents as JSONB in a PostgreSQL database. The
+// documents of every collection are stored in the `documents` table, and the
+// alert states in the `alert_states` table.
+//
+// Documents are encoded in relaxed MongoDB Extended JSON, so that they are
+// decoded with the same `bson` struct tags as with MongoDB. Timestamps are
+// truncated to milliseconds, the precision of MongoDB dates, so that the
+// lookups by timestamp behave the same with both backends.
+type Postgres struct {
+	db *sql.DB
+}
+
+var _ Store = Postgres{}
+
+// NewPostgres connects to the configured PostgreSQL database.
+func NewPostgres(ctx context.Context, c conf.Postgres) (Postgres, error) {
+	conn, err := sql.Open("postgres", c.URI)
+	if err != nil {
+		return Postgres{}, fmt.Errorf("connecting to postgres: %w", err)
+	}
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return Postgres{}, fmt.Errorf("pinging postgres server: %w", err)
+	}
+	return Postgres{db: conn}, nil
+}
+
+// ms truncates t to the precision of the stored timestamps.
+func ms(t time.Time) time.Time {
+	return t.Truncate(time.Millisecond).UTC()
+}
+
+// encode returns the Extended JSON representation of the document, along
+// with its `timestamp` field, if any.
+func encode(doc any) ([]byte, time.Time, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("encoding document: %w", err)
+	}
+	var stamp time.Time
+	if dt, ok := bson.Raw(raw).Lookup("timestamp").DateTimeOK(); ok {
+		stamp = time.UnixMilli(dt).UTC()
+	}
+	data, err := bson.MarshalExtJSON(bson.Raw(raw), false, false)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("encoding document: %w", err)
+	}
+	return data, stamp, nil
+}
+
+func decode(data []byte, v any) error {
+	if err := bson.UnmarshalExtJSON(data, false, v); err != nil {
+		return fmt.Errorf("decoding document: %w", err)
+	}
+	return nil
+}
+
+// Insert satisfies the Store interface.
+func (p Postgres) Insert(ctx context.Context, coll string, doc any) (any, error) {
+	data, stamp, err := encode(doc)
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	err = p.db.QueryRowContext(
+		ctx,
+		`INSERT INTO documents (collection, timestamp, doc) VALUES ($1, $2, $3) RETURNING id`,
+		coll, stamp, string(data),
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("inserting %q document: %w", coll, err)
+	}
+	return id, nil
+}
+
+// Find satisfies the Store interface.
+func (p Postgres) Find(ctx context.Context, coll string, at time.Time, v any) error {
+	_, err := p.findOne(ctx, coll, "timestamp = $2", v, ms(at))
+	return err
+}
+
+// FindByID satisfies the Store interface.
+func (p Postgres) FindByID(ctx context.Context, coll, id string, v any) (time.Time, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing id %q: %w", id, err)
+	}
+	return p.findOne(ctx, coll, "id = $2", v, n)
+}
+
+// DocumentID satisfies the Store interface.
+func (p Postgres) DocumentID(ctx context.Context, coll string, at time.Time) (any, error) {
+	var id int64
+	err := p.db.QueryRowContext(
+		ctx,
+		`SELECT id FROM documents WHERE collection = $1 AND timestamp = $2 LIMIT 1`,
+		coll, ms(at),
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("finding %q document: %w", coll, err)
+	}
+	return id, nil
+}
+
+// Latest satisfies the Store interface.
+func (p Postgres) Latest(ctx context.Context, coll string, v any) (time.Time, error) {
+	return p.findOne(ctx, coll, "true", v)
+}
+
+// Before satisfies the Store interface.
+func (p Postgres) Before(ctx context.Context, coll string, before time.Time, v any) (time.Time, error) {
+	return p.findOne(ctx, coll, "timestamp < $2", v, ms(before))
+}
+
+// AtOrBefore satisfies the Store interface.
+func (p Postgres) AtOrBefore(ctx context.Context, coll string, at time.Time, v any) (time.Time, error) {
+	return p.findOne(ctx, coll, "timestamp <= $2", v, ms(at))
+}
+
+// findOne decodes the latest document of the collection matching the
+// condition into v, unless v is nil, and returns its timestamp. The
+// arguments of the condition start at $2.
+func (p Postgres) findOne(ctx context.Context, coll, cond string, v any, args ...any) (time.Time, error) {
+	var (
+		stamp time.Time
+		data  []byte
+	)
+	query := fmt.Sprintf(
+		`SELECT timestamp, doc FROM documents WHERE collection = $1 AND %s ORDER BY timestamp DESC, id DESC LIMIT 1`,
+		cond,
+	)
+	err := p.db.
+		QueryRowContext(ctx, query, append([]any{coll}, args...)...).
+		Scan(&stamp, &data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrNotFound
+		}
+		return time.Time{}, fmt.Errorf("finding %q document: %w", coll, err)
+	}
+	if v != nil {
+		if err := decode(data, v); err != nil {
+			return time.Time{}, fmt.Errorf("%q: %w", coll, err)
+		}
+	}
+	return stamp.UTC(), nil
+}
+
+// List satisfies the Store interface.
+func (p Postgres) List(
+	ctx context.Context,
+	coll string,
+	from, to time.Time,
+	offset, limit int,
+	newDoc func() any,
+) ([]any, int, error) {
+	var total int
+	err := p.db.QueryRowContext(
+		ctx,
+		`SELECT count(*) FROM documents WHERE collection = $1 AND timestamp >= $2 AND timestamp < $3`,
+		coll, ms(from), ms(to),
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("counting %q documents: %w", coll, err)
+	}
+
+	pageSize := sql.NullInt64{Int64: int64(limit), Valid: limit > 0}
+	rows, err := p.db.QueryContext(
+		ctx,
+		`SELECT doc FROM documents
+		WHERE collection = $1 AND timestamp >= $2 AND timestamp < $3
+		ORDER BY timestamp, id
+		OFFSET $4 LIMIT $5`,
+		coll, ms(from), ms(to), offset, pageSize,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("finding %q documents: %w", coll, err)
+	}
+	defer rows.Close()
+
+	docs := make([]any, 0, limit)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, 0, fmt.Errorf("scanning %q document: %w", coll, err)
+		}
+		doc := newDoc()
+		if err := decode(data, doc); err != nil {
+			return nil, 0, fmt.Errorf("%q: %w", coll, err)
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterating %q documents: %w", coll, err)
+	}
+	return docs, total, nil
+}
+
+// Timestamps satisfies the Store interface.
+func (p Postgres) Timestamps(ctx context.Context, coll string, from, to time.Time) ([]time.Time, error) {
+	rows, err := p.db.QueryContext(
+		ctx,
+		`SELECT DISTINCT timestamp FROM documents
+		WHERE collection = $1 AND ($2::timestamptz IS NULL OR timestamp >= $2) AND timestamp < $3`,
+		coll, nullTime(from), ms(to),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("finding %q timestamps: %w", coll, err)
+	}
+	defer rows.Close()
+
+	var stamps []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("scanning %q timestamp: %w", coll, err)
+		}
+		stamps = append(stamps, t.UTC())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating %q timestamps: %w", coll, err)
+	}
+	return stamps, nil
+}
+
+// nullTime returns NULL for the zero time.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: ms(t), Valid: !t.IsZero()}
+}
+
+// DeleteBefore satisfies the Store interface.
+func (p Postgres) DeleteBefore(ctx context.Context, coll string, before time.Time) (int64, error) {
+	return p.delete(ctx, coll, "timestamp < $2", ms(before))
+}
+
+// DeleteAt satisfies the Store interface.
+func (p Postgres) DeleteAt(ctx context.Context, coll string, stamps []time.Time) (int64, error) {
+	truncated := make([]string, 0, len(stamps))
+	for _, t := range stamps {
+		truncated = append(truncated, ms(t).Format(time.RFC3339Nano))
+	}
+	return p.delete(ctx, coll, "timestamp = ANY($2::timestamptz[])", pq.Array(truncated))
+}
+
+func (p Postgres) delete(ctx context.Context, coll, cond string, args ...any) (int64, error) {
+	result, err := p.db.ExecContext(
+		ctx,
+		fmt.Sprintf(`DELETE FROM documents WHERE collection = $1 AND %s`, cond),
+		append([]any{coll}, args...)...,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("deleting %q documents: %w", coll, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("deleting %q documents: %w", coll, err)
+	}
+	return deleted, nil
+}
+
+// Series satisfies the Store interface.
+func (p Postgres) Series(ctx context.Context, coll string, from, to time.Time, q SeriesQuery) ([]Point, error) {
+	query, args := seriesQuery(q, coll, ms(from), ms(to))
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("aggregating %q documents: %w", coll, err)
+	}
+	defer rows.Close()
+
+	points := []Point{}
+	for rows.Next() {
+		var pt Point
+		if err := rows.Scan(&pt.Timestamp, &pt.Series, &pt.Value); err != nil {
+			return nil, fmt.Errorf("scanning %q point: %w", coll, err)
+		}
+		pt.Timestamp = pt.Timestamp.UTC()
+		points = append(points, pt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating %q points: %w", coll, err)
+	}
+	return points, nil
+}
+
+// seriesQuery returns the SQL query selecting the points of the series,
+// along with its arguments.
+func seriesQuery(q SeriesQuery, coll string, from, to time.Time) (string, []any) {
+	args := []any{coll, from, to}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	path := func(p string) string {
+		return arg(pq.Array(strings.Split(p, "."))) + "::text[]"
+	}
+	const where = `WHERE d.collection = $1 AND d.timestamp >= $2 AND d.timestamp < $3`
+
+	switch {
+	case len(q.Fields) != 0:
+		values := make([]string, 0, len(q.Fields))
+		for _, f := range q.Fields {
+			values = append(values, fmt.Sprintf("(%s::text, %s)", arg(f.Name), path(f.Path)))
+		}
+		return fmt.Sprintf(
+			`SELECT d.timestamp, f.series, coalesce(%s, 0)
+			FROM documents d
+			CROSS JOIN (VALUES %s) AS f(series, path)
+			%s`,
+			number("d.doc #> f.path"),
+			strings.Join(values, ", "),
+			where,
+		), args
+
+	case len(q.Count) != 0:
+		return fmt.Sprintf(
+			`SELECT d.timestamp, c.series, (count(e.value) FILTER (WHERE e.value #>> %s = c.series))::float8
+			FROM documents d
+			CROSS JOIN unnest(%s::text[]) AS c(series)
+			LEFT JOIN LATERAL jsonb_array_elements(%s) AS e(value) ON true
+			%s
+			GROUP BY d.timestamp, c.series`,
+			path(q.CountBy),
+			arg(pq.Array(q.Count)),
+			array("d.doc #> "+path(q.List)),
+			where,
+		), args
+
+	default:
+		names := make([]string, 0, len(q.Name))
+		for _, n := range q.Name {
+			names = append(names, "e.value #>> "+path(n))
+		}
+		return fmt.Sprintf(
+			`SELECT d.timestamp, concat_ws('/', %s), coalesce(%s, 0)
+			FROM documents d
+			CROSS JOIN LATERAL jsonb_array_elements(%s) AS e(value)
+			%s`,
+			strings.Join(names, ", "),
+			number("e.value #> "+path(q.Value)),
+			array("d.doc #> "+path(q.List)),
+			where,
+		), args
+	}
+}
+
+// number returns the SQL expression converting the JSON value to a float,
+// or NULL if it is not a number.
+func number(expr string) string {
+	return fmt.Sprintf(`CASE WHEN jsonb_typeof(%[1]s) = 'number' THEN (%[1]s)::text::float8 END`, expr)
+}
+
+// array returns the SQL expression of the JSON value if it is an array, or
+// of an empty array otherwise.
+func array(expr string) string {
+	return fmt.Sprintf(`CASE WHEN jsonb_typeof(%[1]s) = 'array' THEN %[1]s ELSE '[]'::jsonb END`, expr)
+}
+
+// Alerts satisfies the Store interface.
+func (p Postgres) Alerts(ctx context.Context, at time.Time, from string) ([]db.AlertDocument, error) {
+	rows, err := p.db.QueryContext(
+		ctx,
+		`SELECT doc FROM documents
+		WHERE collection = $1 AND timestamp = $2 AND ($3 = '' OR doc->>'from' = $3)
+		ORDER BY id`,
+		db.CollectionAlerts, ms(at), from,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("finding alerts at %v: %w", at, err)
+	}
+	defer rows.Close()
+
+	docs := []db.AlertDocument{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("scanning alert document: %w", err)
+		}
+		var doc db.AlertDocument
+		if err := decode(data, &doc); err != nil {
+			return nil, fmt.Errorf("alerts: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating alert documents: %w", err)
+	}
+	return docs, nil
+}
+
+// ActiveAlertStates satisfies the Store interface.
+func (p Postgres) ActiveAlertStates(ctx context.Context, from string) ([]db.AlertStateDocument, error) {
+	states, _, err := p.findAlertStates(
+		ctx,
+		`source = $1 AND state IN ($2, $3)`,
+		from, db.AlertStatePending, db.AlertStateFiring,
+	)
+	return states, err
+}
+
+// SaveAlertState satisfies the Store interface.
+func (p Postgres) SaveAlertState(ctx context.Context, s db.AlertStateDocument) error {
+	data, _, err := encode(s)
+	if err != nil {
+		return fmt.Errorf("alert state %q: %w", s.Fingerprint, err)
+	}
+	result, err := p.db.ExecContext(
+		ctx,
+		`UPDATE alert_states
+		SET source = $2, state = $3, last_seen = $4, resolved_at = $5, doc = $6
+		WHERE fingerprint = $1 AND state IN ($7, $8)`,
+		s.Fingerprint, s.From, s.State, ms(s.LastSeen), nullTime(s.ResolvedAt), string(data),
+		db.AlertStatePending, db.AlertStateFiring,
+	)
+	if err != nil {
+		return fmt.Errorf("updating alert state %q: %w", s.Fingerprint, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n != 0 {
+		return nil
+	}
+	_, err = p.db.ExecContext(
+		ctx,
+		`INSERT INTO alert_states (fingerprint, source, state, last_seen, resolved_at, doc)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		s.Fingerprint, s.From, s.State, ms(s.LastSeen), nullTime(s.ResolvedAt), string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting alert state %q: %w", s.Fingerprint, err)
+	}
+	return nil
+}
+
+// ResolveAlertStates satisfies the Store interface.
+func (p Postgres) ResolveAlertStates(ctx context.Context, fingerprints []string, at time.Time) error {
+	states, ids, err := p.findAlertStates(
+		ctx,
+		`fingerprint = ANY($1) AND state = $2`,
+		pq.Array(fingerprints), db.AlertStateFiring,
+	)
+	if err != nil {
+		return fmt.Errorf("resolving alert states: %w", err)
+	}
+	for idx, s := range states {
+		s.State = db.AlertStateResolved
+		s.ResolvedAt = at
+		data, _, err := encode(s)
+		if err != nil {
+			return fmt.Errorf("resolving alert state %q: %w", s.Fingerprint, err)
+		}
+		_, err = p.db.ExecContext(
+			ctx,
+			`UPDATE alert_states SET state = $2, resolved_at = $3, doc = $4 WHERE id = $1`,
+			ids[idx], s.State, ms(at), string(data),
+		)
+		if err != nil {
+			return fmt.Errorf("resolving alert state %q: %w", s.Fingerprint, err)
+		}
+	}
+	return nil
+}
+
+// DropAlertStates satisfies the Store interface.
+func (p Postgres) DropAlertStates(ctx context.Context, fingerprints []string) error {
+	_, err := p.db.ExecContext(
+		ctx,
+		`DELETE FROM alert_states WHERE fingerprint = ANY($1) AND state = $2`,
+		pq.Array(fingerprints), db.AlertStatePending,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting pending alert states: %w", err)
+	}
+	return nil
+}
+
+// AlertStates satisfies the Store interface.
+func (p Postgres) AlertStates(ctx context.Context, from string, at time.Time) ([]db.AlertStateDocument, error) {
+	states, _, err := p.findAlertStates(
+		ctx,
+		`source = $1 AND ((state = $2 AND last_seen = $4) OR (state = $3 AND resolved_at = $4))`,
+		from, db.AlertStateFiring, db.AlertStateResolved, ms(at),
+	)
+	return states, err
+}
+
+// DeleteResolvedAlertStates satisfies the Store interface.
+func (p Postgres) DeleteResolvedAlertStates(ctx context.Context, before time.Time) (int64, error) {
+	result, err := p.db.ExecContext(
+		ctx,
+		`DELETE FROM alert_states WHERE state = $1 AND resolved_at < $2`,
+		db.AlertStateResolved, ms(before),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("deleting resolved alert states: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("deleting resolved alert states: %w", err)
+	}
+	return deleted, nil
+}
+
+// findAlertStates returns the alert states matching the condition, along
+// with their IDs.
+func (p Postgres) findAlertStates(ctx context.Context, cond string, args ...any) ([]db.AlertStateDocument, []int64, error) {
+	rows, err := p.db.QueryContext(
+		ctx,
+		fmt.Sprintf(`SELECT id, doc FROM alert_states WHERE %s ORDER BY id`, cond),
+		args...,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("finding alert states: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		states []db.AlertStateDocument
+		ids    []int64
+	)
+	for rows.Next() {
+		var (
+			id   int64
+			data []byte
+		)
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, nil, fmt.Errorf("scanning alert state: %w", err)
+		}
+		var s db.AlertStateDocument
+		if err := decode(data, &s); err != nil {
+			return nil, nil, fmt.Errorf("alert state: %w", err)
+		}
+		states = append(states, s)
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterating alert states: %w", err)
+	}
+	return states, ids, nil
+}
+
+// Close satisfies the Store interface.
+func (p Postgres) Close(context.Context) error {
+	if err := p.db.Close(); err != nil {
+		return fmt.Errorf("closing postgres connection: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// migrationLock is the key of the advisory lock held while migrating, so
+// that instances starting together apply each migration once.
+const migrationLock = 0x72696e63 // "rinc"
+
+// postgresMigrations upgrade the database, postgresMigrations[i] upgrading
+// it to version i+1. Migrations must never be removed or reordered.
+var postgresMigrations = []struct {
+	description string
+	statements  []string
+}{
+	{
+		description: "create tables",
+		statements: []string{
+			`CREATE TABLE documents (
+				id bigserial PRIMARY KEY,
+				collection text NOT NULL,
+				timestamp timestamptz NOT NULL,
+				doc jsonb NOT NULL
+			)`,
+			`CREATE INDEX documents_collection_timestamp ON documents (collection, timestamp)`,
+			`CREATE INDEX documents_alerts_timestamp_from ON documents (timestamp, (doc->>'from'))
+			WHERE collection = 'alerts'`,
+			`CREATE TABLE alert_states (
+				id bigserial PRIMARY KEY,
+				fingerprint text NOT NULL,
+				source text NOT NULL,
+				state text NOT NULL,
+				last_seen timestamptz NOT NULL,
+				resolved_at timestamptz,
+				doc jsonb NOT NULL
+			)`,
+			`CREATE INDEX alert_states_source_state ON alert_states (source, state)`,
+			`CREATE INDEX alert_states_fingerprint_state ON alert_states (fingerprint, state)`,
+		},
+	},
+}
+
+// Migrate satisfies the Store interface. Each migration is applied in a
+// transaction, along with the record of the new schema version in the
+// `schema_migrations` table.
+func (p Postgres) Migrate(ctx context.Context) (int, int, error) {
+	_, err := p.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version integer PRIMARY KEY,
+		description text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+	from, err := p.version(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	version := from
+	for version < len(postgresMigrations) {
+		applied, err := p.migrate(ctx, version+1)
+		if err != nil {
+			return from, version, err
+		}
+		if applied {
+			slog.LogAttrs(
+				ctx,
+				slog.LevelInfo,
+				"migrated database",
+				slog.Int("version", version+1),
+				slog.String("description", postgresMigrations[version].description),
+			)
+		}
+		version++
+	}
+	if from > len(postgresMigrations) {
+		slog.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"database schema is newer than this version of rinc",
+			slog.Int("version", from),
+			slog.Int("expected", len(postgresMigrations)),
+		)
+	}
+	return from, version, nil
+}
+
+// migrate applies the migration to the provided version, unless another
+// instance applied it first, and reports whether it was applied.
+func (p Postgres) migrate(ctx context.Context, version int) (bool, error) {
+	m := postgresMigrations[version-1]
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("migrating to version %d: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+		return false, fmt.Errorf("migrating to version %d: locking: %w", version, err)
+	}
+	var applied bool
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`,
+		version,
+	).Scan(&applied)
+	if err != nil {
+		return false, fmt.Errorf("migrating to version %d: %w", version, err)
+	}
+	if applied {
+		return false, nil
+	}
+	for _, stmt := range m.statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return false, fmt.Errorf("migrating to version %d (%s): %w", version, m.description, err)
+		}
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO schema_migrations (version, description) VALUES ($1, $2)`,
+		version, m.description,
+	)
+	if err != nil {
+		return false, fmt.Errorf("recording schema version %d: %w", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("migrating to version %d: %w", version, err)
+	}
+	return true, nil
+}
+
+// version returns the schema version recorded in the database, which is 0
+// if no migration was ever applied.
+func (p Postgres) version(ctx context.Context) (int, error) {
+	var version int
+	err := p.db.
+		QueryRowContext(ctx, `SELECT coalesce(max(version), 0) FROM schema_migrations`).
+		Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("finding schema version: %w", err)
+	}
+	return version, nil
+}
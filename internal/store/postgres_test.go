@@ -0,0 +1,82 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/types/pv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	a := assert.New(t)
+	now := time.Date(2024, 7, 1, 12, 30, 15, 123456789, time.UTC)
+
+	metrics := pv.Metrics{
+		Timestamp: now,
+		PVs:       pv.PVs{{PVC: "data", PVCNamespace: "mongo", Capacity: 1024, UtilizationPercent: 87.5}},
+	}
+	data, stamp, err := encode(metrics)
+	a.NoError(err)
+	a.Equal(now.Truncate(time.Millisecond), stamp)
+	a.Contains(string(data), `"pvcnamespace":"mongo"`)
+	decoded := pv.Metrics{}
+	a.NoError(decode(data, &decoded))
+	a.Equal(now.Truncate(time.Millisecond), decoded.Timestamp.UTC())
+	a.Equal(metrics.PVs, decoded.PVs)
+
+	state := db.AlertStateDocument{
+		Fingerprint: "abc",
+		Severity:    conf.SeverityCritical,
+		State:       db.AlertStateFiring,
+		LastSeen:    now,
+		Labels:      map[string]string{"team": "infra"},
+	}
+	data, stamp, err = encode(state)
+	a.NoError(err)
+	a.True(stamp.IsZero())
+	decodedState := db.AlertStateDocument{}
+	a.NoError(decode(data, &decodedState))
+	a.Equal(state.Labels, decodedState.Labels)
+	a.Equal(state.Severity, decodedState.Severity)
+	a.True(decodedState.ResolvedAt.IsZero())
+}
+
+func TestSeriesQuery(t *testing.T) {
+	a := assert.New(t)
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := map[string]struct {
+		q        SeriesQuery
+		args     int
+		contains string
+	}{
+		"list": {
+			q:        SeriesQuery{List: "pvs", Name: []string{"pvcnamespace", "pvc"}, Value: "utilizationpercent"},
+			args:     7,
+			contains: "jsonb_array_elements",
+		},
+		"count": {
+			q:        SeriesQuery{List: "alerts", CountBy: "severity", Count: []string{"critical", "warning"}},
+			args:     6,
+			contains: "GROUP BY",
+		},
+		"fields": {
+			q: SeriesQuery{Fields: []SeriesField{
+				{Name: "total", Path: "overview.queue_totals.messages"},
+				{Name: "ready", Path: "overview.queue_totals.messages_ready"},
+			}},
+			args:     7,
+			contains: "VALUES ($4::text, $5::text[]), ($6::text, $7::text[])",
+		},
+	}
+	for name, test := range tests {
+		query, args := seriesQuery(test.q, "coll", from, to)
+		a.Len(args, test.args, "INPUT=%s", name)
+		a.Contains(query, test.contains, "INPUT=%s", name)
+	}
+}
@@ -0,0 +1,144 @@
+// Package store persists the reports, alerts and runs written by the
+// scraper, and serves them to the web server. Documents are encoded with
+// their `bson` struct tags regardless of the backend, so that field paths
+// are the same in every backend.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+)
+
+// ErrNotFound is returned by the lookups of a Store when there is no
+// document to return.
+var ErrNotFound = errors.New("not found")
+
+// Store is a storage backend. Documents are written to collections, named
+// after the db.Collection* constants, and are looked up by the timestamp
+// they were written at.
+type Store interface {
+	// Insert writes the document to the collection and returns its ID.
+	Insert(ctx context.Context, coll string, doc any) (any, error)
+	// Find decodes the document written to the collection at the provided
+	// timestamp into v. v may be nil to only check that the document
+	// exists. It returns ErrNotFound if there is none.
+	Find(ctx context.Context, coll string, at time.Time, v any) error
+	// FindByID decodes the document with the provided ID into v, and
+	// returns the timestamp it was written at. It returns ErrNotFound if
+	// there is none.
+	FindByID(ctx context.Context, coll, id string, v any) (time.Time, error)
+	// DocumentID returns the ID of the document written to the collection
+	// at the provided timestamp. It returns ErrNotFound if there is none.
+	DocumentID(ctx context.Context, coll string, at time.Time) (any, error)
+	// Latest decodes the latest document of the collection into v, and
+	// returns the timestamp it was written at. v may be nil to only look up
+	// the timestamp. It returns ErrNotFound if there is none.
+	Latest(ctx context.Context, coll string, v any) (time.Time, error)
+	// Before is like Latest, but only considers the documents written
+	// before the provided timestamp.
+	Before(ctx context.Context, coll string, before time.Time, v any) (time.Time, error)
+	// AtOrBefore is like Latest, but only considers the documents written
+	// at or before the provided timestamp.
+	AtOrBefore(ctx context.Context, coll string, at time.Time, v any) (time.Time, error)
+	// List decodes the documents written to the collection between from
+	// (inclusive) and to (exclusive), oldest first, skipping the first
+	// offset documents and returning at most limit documents. newDoc
+	// returns the value to decode each document into. The total number of
+	// documents in the range is returned along with the page.
+	List(ctx context.Context, coll string, from, to time.Time, offset, limit int, newDoc func() any) ([]any, int, error)
+	// Timestamps returns the distinct timestamps of the documents written
+	// to the collection between from (inclusive) and to (exclusive). A zero
+	// from leaves the range unbounded.
+	Timestamps(ctx context.Context, coll string, from, to time.Time) ([]time.Time, error)
+	// DeleteBefore deletes the documents written to the collection before
+	// the provided timestamp, and returns the number of deleted documents.
+	DeleteBefore(ctx context.Context, coll string, before time.Time) (int64, error)
+	// DeleteAt deletes the documents written to the collection at the
+	// provided timestamps, and returns the number of deleted documents.
+	DeleteAt(ctx context.Context, coll string, stamps []time.Time) (int64, error)
+	// Series returns the points selected by the query from the documents
+	// written to the collection between from (inclusive) and to
+	// (exclusive).
+	Series(ctx context.Context, coll string, from, to time.Time, q SeriesQuery) ([]Point, error)
+
+	// Alerts returns the alert documents written at the provided timestamp
+	// by the provided source, or by every source if it is empty.
+	Alerts(ctx context.Context, at time.Time, from string) ([]db.AlertDocument, error)
+	// ActiveAlertStates returns the pending and firing alert states of the
+	// provided source.
+	ActiveAlertStates(ctx context.Context, from string) ([]db.AlertStateDocument, error)
+	// SaveAlertState replaces the active state with the same fingerprint,
+	// or inserts it if there is none.
+	SaveAlertState(ctx context.Context, s db.AlertStateDocument) error
+	// ResolveAlertStates marks the firing states with the provided
+	// fingerprints as resolved at the provided timestamp.
+	ResolveAlertStates(ctx context.Context, fingerprints []string, at time.Time) error
+	// DropAlertStates deletes the pending states with the provided
+	// fingerprints.
+	DropAlertStates(ctx context.Context, fingerprints []string) error
+	// AlertStates returns the states of the source's alerts that were
+	// firing or got resolved at the provided timestamp.
+	AlertStates(ctx context.Context, from string, at time.Time) ([]db.AlertStateDocument, error)
+	// DeleteResolvedAlertStates deletes the alert states resolved before
+	// the provided timestamp, and returns the number of deleted states.
+	DeleteResolvedAlertStates(ctx context.Context, before time.Time) (int64, error)
+
+	// Migrate applies the migrations newer than the schema version of the
+	// backend, and returns the schema version before and after migrating.
+	Migrate(ctx context.Context) (int, int, error)
+	// Close closes the connection to the backend.
+	Close(ctx context.Context) error
+}
+
+// New connects to the storage backend selected by the configuration.
+func New(ctx context.Context, c conf.C) (Store, error) {
+	switch c.Storage.Backend {
+	case conf.StorageMongodb:
+		return NewMongo(c.Mongodb)
+	case conf.StoragePostgres:
+		return NewPostgres(ctx, c.Postgres)
+	case conf.StorageBolt:
+		return NewBolt(c.Bolt)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", c.Storage.Backend)
+	}
+}
+
+// SeriesQuery selects the points charted from the documents of a
+// collection. Paths are dot-separated field names, as encoded by the `bson`
+// struct tags of the documents. It works in one of three modes:
+//
+//   - with List and Value, every element of the list yields a point, named
+//     after its Name fields joined with "/";
+//   - with List and Count, every document yields a point per value in
+//     Count, holding the number of elements of the list whose CountBy
+//     field equals the value, summed across the documents written at the
+//     same time;
+//   - with Fields, every document yields a point per field, missing fields
+//     being charted as 0.
+type SeriesQuery struct {
+	List    string
+	Name    []string
+	Value   string
+	CountBy string
+	Count   []string
+	Fields  []SeriesField
+}
+
+// SeriesField is a series charting a field of the documents.
+type SeriesField struct {
+	Name string
+	Path string
+}
+
+// Point is a value of a series at a point in time.
+type Point struct {
+	Timestamp time.Time `bson:"timestamp"`
+	Series    string    `bson:"series"`
+	Value     float64   `bson:"value"`
+}
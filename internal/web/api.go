@@ -0,0 +1,301 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/schema"
+	"github.com/accuknox/rinc/internal/util"
+
+	"github.com/labstack/echo/v4"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultPageLimit is the number of items returned by the paginated API
+	// endpoints when the `limit` query parameter is not provided.
+	DefaultPageLimit = 50
+	// MaxPageLimit is the largest `limit` accepted by the paginated API
+	// endpoints.
+	MaxPageLimit = 500
+)
+
+const (
+	mimeJSON = "application/json"
+	mimeYAML = "application/yaml"
+)
+
+// apiMediaTypes are the media types the API responds with, in order of
+// preference.
+var apiMediaTypes = []string{mimeJSON, mimeYAML}
+
+// Page is a page of the items returned by a paginated API endpoint.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// Total is the number of items across every page.
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// RunSummary identifies a run in the list of runs.
+type RunSummary struct {
+	// ID is the timestamp of the run in the format used by the URLs.
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	// Status is empty for reports generated before run manifests were
+	// recorded.
+	Status db.RunStatus `json:"status,omitempty"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// APIRuns lists the runs between the `from` and `to` query parameters,
+// oldest first.
+func (s Srv) APIRuns(c echo.Context) error {
+	from, to, err := parseRange(c)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	offset, limit, err := parsePagination(c)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	runs, total, err := s.fetchRuns(c.Request().Context(), from, to, offset, limit)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	page := Page[RunSummary]{
+		Items:  []RunSummary{},
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+	}
+	for _, run := range runs {
+		page.Items = append(page.Items, RunSummary{
+			ID:        run.Timestamp.Format(util.IsosecLayout),
+			Timestamp: run.Timestamp,
+			Status:    run.Status,
+		})
+	}
+	return respond(c, http.StatusOK, page)
+}
+
+// APIRun returns the manifest of the run with the `id` path parameter.
+func (s Srv) APIRun(c echo.Context) error {
+	at, err := time.Parse(util.IsosecLayout, c.Param("id"))
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, "failed to parse timestamp")
+	}
+	run, err := s.fetchRun(c.Request().Context(), at)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	if run == nil {
+		return respondError(c, http.StatusNotFound, "no run recorded at this timestamp")
+	}
+	return respond(c, http.StatusOK, run)
+}
+
+// APIAlerts returns the alerts fired during the run with the `id` path
+// parameter, grouped by reporter. The `reporter` query parameter limits the
+// alerts to the ones of a single reporter.
+func (s Srv) APIAlerts(c echo.Context) error {
+	at, err := time.Parse(util.IsosecLayout, c.Param("id"))
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, "failed to parse timestamp")
+	}
+
+	if reporter := c.QueryParam("reporter"); reporter != "" {
+		if !slices.Contains(db.Collections, reporter) {
+			return respondError(c, http.StatusNotFound, fmt.Sprintf("unknown reporter %q", reporter))
+		}
+		alerts, err := s.fetchAlerts(c.Request().Context(), reporter, at)
+		if err != nil {
+			return respondError(c, http.StatusInternalServerError, err.Error())
+		}
+		docs := []db.AlertDocument{}
+		if alerts != nil {
+			docs = append(docs, db.AlertDocument{
+				Timestamp: at,
+				From:      reporter,
+				Alerts:    alerts,
+			})
+		}
+		return respond(c, http.StatusOK, docs)
+	}
+
+	docs, err := s.fetchAllAlerts(c.Request().Context(), at)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return respond(c, http.StatusOK, docs)
+}
+
+// APIReporters lists the names of the reporters, as used by the API.
+func (s Srv) APIReporters(c echo.Context) error {
+	return respond(c, http.StatusOK, db.Collections)
+}
+
+// APIMetrics returns the raw metrics written by the reporter with the
+// `reporter` path parameter during the run with the `id` path parameter.
+func (s Srv) APIMetrics(c echo.Context) error {
+	at, err := time.Parse(util.IsosecLayout, c.Param("id"))
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, "failed to parse timestamp")
+	}
+	reporter := c.Param("reporter")
+	metrics, err := schema.New(reporter)
+	if err != nil {
+		return respondError(c, http.StatusNotFound, fmt.Sprintf("unknown reporter %q", reporter))
+	}
+
+	err = s.fetchMetrics(c.Request().Context(), reporter, at, metrics)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return respondError(c, http.StatusNotFound, "no metrics recorded at this timestamp")
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return respond(c, http.StatusOK, metrics)
+}
+
+// APIMetricsRange returns the raw metrics written by the reporter with the
+// `reporter` path parameter between the `from` and `to` query parameters,
+// oldest first.
+func (s Srv) APIMetricsRange(c echo.Context) error {
+	reporter := c.Param("reporter")
+	if _, err := schema.New(reporter); err != nil {
+		return respondError(c, http.StatusNotFound, fmt.Sprintf("unknown reporter %q", reporter))
+	}
+	from, to, err := parseRange(c)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	offset, limit, err := parsePagination(c)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	items, total, err := s.fetchMetricsRange(
+		c.Request().Context(),
+		reporter,
+		from, to,
+		offset, limit,
+		func() any {
+			metrics, _ := schema.New(reporter)
+			return metrics
+		},
+	)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return respond(c, http.StatusOK, Page[any]{
+		Items:  items,
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+	})
+}
+
+// respond writes v with the provided status, encoded in the media type
+// negotiated with the Accept header of the request. It responds with 406
+// Not Acceptable if the client accepts none of the media types of the API.
+func respond(c echo.Context, status int, v any) error {
+	switch negotiate(c.Request().Header.Get(echo.HeaderAccept), apiMediaTypes) {
+	case mimeJSON:
+		return c.JSON(status, v)
+	case mimeYAML:
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, apiError{
+				Error: fmt.Sprintf("marshalling response to yaml: %s", err),
+			})
+		}
+		return c.Blob(status, mimeYAML, out)
+	default:
+		return c.JSON(http.StatusNotAcceptable, apiError{
+			Error: fmt.Sprintf("acceptable media types are %v", apiMediaTypes),
+		})
+	}
+}
+
+func respondError(c echo.Context, status int, msg string) error {
+	return respond(c, status, apiError{Error: msg})
+}
+
+// parseRange parses the `from` and `to` query parameters, see parseTime.
+// They default to the beginning and the end of time respectively.
+func parseRange(c echo.Context) (time.Time, time.Time, error) {
+	from := time.Unix(0, 0).UTC()
+	to := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+	if v := c.QueryParam("from"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return from, to, fmt.Errorf("parsing `from`: %w", err)
+		}
+		from = t
+	}
+	if v := c.QueryParam("to"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return from, to, fmt.Errorf("parsing `to`: %w", err)
+		}
+		to = t
+	}
+	if !from.Before(to) {
+		return from, to, errors.New("`from` must be before `to`")
+	}
+	return from, to, nil
+}
+
+// parseTime parses a timestamp in the format used by the URLs, an RFC 3339
+// timestamp, or a date.
+func parseTime(v string) (time.Time, error) {
+	for _, layout := range []string{
+		util.IsosecLayout,
+		time.RFC3339,
+		util.HTMLFormDateLayout,
+	} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(
+		"want a timestamp formatted as %q, %q or %q, got %q",
+		util.IsosecLayout,
+		time.RFC3339,
+		util.HTMLFormDateLayout,
+		v,
+	)
+}
+
+// parsePagination parses the `offset` and `limit` query parameters. The
+// limit defaults to DefaultPageLimit and may not exceed MaxPageLimit.
+func parsePagination(c echo.Context) (int, int, error) {
+	offset, limit := 0, DefaultPageLimit
+	if v := c.QueryParam("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("want a non-negative integer `offset`, got %q", v)
+		}
+		offset = n
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageLimit {
+			return 0, 0, fmt.Errorf("want a `limit` between 1 and %d, got %q", MaxPageLimit, v)
+		}
+		limit = n
+	}
+	return offset, limit, nil
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	a := assert.New(t)
+	inputs := map[string]string{
+		"":                                   mimeJSON,
+		"*/*":                                mimeJSON,
+		"application/json":                   mimeJSON,
+		"application/yaml":                   mimeYAML,
+		"application/*":                      mimeJSON,
+		"application/yaml, application/json": mimeJSON,
+		"application/json;q=0.5, application/yaml": mimeYAML,
+		"application/*;q=0.5, application/yaml":    mimeYAML,
+		"application/json;q=0, */*":                mimeYAML,
+		"text/html":                                "",
+		"text/html, */*;q=0.1":                     mimeJSON,
+		"Application/YAML":                         mimeYAML,
+	}
+	for accept, expected := range inputs {
+		a.Equal(expected, negotiate(accept, apiMediaTypes), "INPUT=%s", accept)
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	a := assert.New(t)
+	type result struct {
+		offset, limit int
+		err           bool
+	}
+	inputs := map[string]result{
+		"":                   {0, DefaultPageLimit, false},
+		"offset=10&limit=20": {10, 20, false},
+		"limit=500":          {0, 500, false},
+		"limit=501":          {err: true},
+		"limit=0":            {err: true},
+		"offset=-1":          {err: true},
+		"offset=abc":         {err: true},
+	}
+	e := echo.New()
+	for query, expected := range inputs {
+		req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+		offset, limit, err := parsePagination(c)
+		if expected.err {
+			a.Error(err, "INPUT=%s", query)
+			continue
+		}
+		a.NoError(err, "INPUT=%s", query)
+		a.Equal(expected.offset, offset, "INPUT=%s", query)
+		a.Equal(expected.limit, limit, "INPUT=%s", query)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	a := assert.New(t)
+	type result struct {
+		from, to time.Time
+		err      bool
+	}
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	inputs := map[string]result{
+		"from=2024-07-01&to=2024-07-02":               {day, day.Add(24 * time.Hour), false},
+		"from=20240701000000&to=2024-07-01T12:00:00Z": {day, day.Add(12 * time.Hour), false},
+		"from=2024-07-02&to=2024-07-01":               {err: true},
+		"from=yesterday":                              {err: true},
+	}
+	e := echo.New()
+	for query, expected := range inputs {
+		req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+		from, to, err := parseRange(c)
+		if expected.err {
+			a.Error(err, "INPUT=%s", query)
+			continue
+		}
+		a.NoError(err, "INPUT=%s", query)
+		a.True(expected.from.Equal(from), "INPUT=%s", query)
+		a.True(expected.to.Equal(to), "INPUT=%s", query)
+	}
+}
+
+func TestRespond(t *testing.T) {
+	a := assert.New(t)
+	type result struct {
+		status      int
+		contentType string
+		body        string
+	}
+	inputs := map[string]result{
+		mimeJSON:    {http.StatusOK, mimeJSON, `{"items":[],"total":0,"offset":0,"limit":50}`},
+		mimeYAML:    {http.StatusOK, mimeYAML, "items: []\nlimit: 50\noffset: 0\ntotal: 0\n"},
+		"text/html": {http.StatusNotAcceptable, mimeJSON, `{"error":"acceptable media types are [application/json application/yaml]"}`},
+	}
+	e := echo.New()
+	for accept, expected := range inputs {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAccept, accept)
+		rec := httptest.NewRecorder()
+		err := respond(e.NewContext(req, rec), http.StatusOK, Page[RunSummary]{
+			Items: []RunSummary{},
+			Limit: DefaultPageLimit,
+		})
+		a.NoError(err, "INPUT=%s", accept)
+		a.Equal(expected.status, rec.Code, "INPUT=%s", accept)
+		a.Contains(rec.Header().Get(echo.HeaderContentType), expected.contentType, "INPUT=%s", accept)
+		a.Equal(expected.body, string(rec.Body.Bytes()[:len(expected.body)]), "INPUT=%s", accept)
+	}
+}
@@ -0,0 +1,315 @@
+package web
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/view"
+	"github.com/accuknox/rinc/view/layout"
+	"github.com/accuknox/rinc/view/partial"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	sessionCookie = "rinc_session"
+	stateCookie   = "rinc_oidc_state"
+	// loginTimeout is how long users have to log in with the provider.
+	loginTimeout = 10 * time.Minute
+	// userKey is the key of the name of the authenticated user in the
+	// echo context.
+	userKey = "user"
+)
+
+// authenticator authenticates the requests to the web server using the
+// methods enabled in the configuration.
+type authenticator struct {
+	conf conf.Auth
+	// users maps usernames to bcrypt hashes.
+	users map[string][]byte
+	// oidc is nil unless OIDC is enabled.
+	oidc    *oidcProvider
+	cookies cookieSigner
+
+	// verified holds a digest of the last password that matched the hash of
+	// each user, since bcrypt is slow by design and browsers send the
+	// credentials with every request.
+	mu       sync.Mutex
+	verified map[string][sha256.Size]byte
+}
+
+func newAuthenticator(c conf.Auth) (*authenticator, error) {
+	a := &authenticator{
+		conf:     c,
+		users:    make(map[string][]byte, len(c.Basic.Users)),
+		verified: make(map[string][sha256.Size]byte),
+	}
+	if c.Basic.Enable && c.Basic.HtpasswdFile != "" {
+		f, err := os.Open(c.Basic.HtpasswdFile)
+		if err != nil {
+			return nil, fmt.Errorf("opening htpasswd file: %w", err)
+		}
+		defer f.Close()
+		a.users, err = parseHtpasswd(f)
+		if err != nil {
+			return nil, fmt.Errorf("parsing htpasswd file %q: %w", c.Basic.HtpasswdFile, err)
+		}
+	}
+	for user, hash := range c.Basic.Users {
+		a.users[user] = []byte(hash)
+	}
+	if c.OIDC.Enable {
+		a.oidc = newOIDCProvider(c.OIDC)
+		a.cookies = cookieSigner{
+			key:    []byte(c.OIDC.SessionKey),
+			secure: strings.HasPrefix(c.OIDC.RedirectURL, "https://"),
+		}
+	}
+	return a, nil
+}
+
+// parseHtpasswd parses the users of an htpasswd file. Only bcrypt hashes are
+// supported.
+func parseHtpasswd(r io.Reader) (map[string][]byte, error) {
+	users := make(map[string][]byte)
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want `user:hash`", line)
+		}
+		if !strings.HasPrefix(hash, "$2") {
+			return nil, fmt.Errorf("line %d: unsupported hash for user %q, only bcrypt is supported", line, user)
+		}
+		users[user] = []byte(hash)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading htpasswd: %w", err)
+	}
+	return users, nil
+}
+
+// middleware rejects the requests that are not authenticated by any of the
+// enabled methods. Browsers are redirected to the OIDC login, if enabled.
+// Static assets and the OIDC endpoints are not authenticated.
+func (a *authenticator) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		path := req.URL.Path
+		if !a.conf.Enabled() ||
+			strings.HasPrefix(path, "/static/") ||
+			strings.HasPrefix(path, "/auth/") {
+			return next(c)
+		}
+		machine := strings.HasPrefix(path, "/api/") || path == "/metrics"
+
+		if user, password, ok := req.BasicAuth(); ok && a.conf.Basic.Enable {
+			if !a.checkPassword(user, password) {
+				a.logFailure(c, "basic", user)
+				return a.unauthorized(c, machine)
+			}
+			c.Set(userKey, user)
+			return next(c)
+		}
+
+		if token, ok := strings.CutPrefix(req.Header.Get(echo.HeaderAuthorization), "Bearer "); ok && machine {
+			name, ok := a.checkToken(token)
+			if !ok {
+				a.logFailure(c, "bearer", "")
+				return a.unauthorized(c, machine)
+			}
+			c.Set(userKey, name)
+			return next(c)
+		}
+
+		if a.oidc != nil {
+			var sess session
+			if err := a.cookies.get(req, sessionCookie, &sess); err == nil {
+				c.Set(userKey, sess.Name)
+				return next(c)
+			}
+			if !machine && req.Method == http.MethodGet {
+				return c.Redirect(
+					http.StatusFound,
+					"/auth/login?next="+url.QueryEscape(req.URL.RequestURI()),
+				)
+			}
+		}
+
+		return a.unauthorized(c, machine)
+	}
+}
+
+// checkPassword reports whether the password matches the hash of the user.
+func (a *authenticator) checkPassword(user, password string) bool {
+	hash, ok := a.users[user]
+	if !ok {
+		return false
+	}
+	digest := sha256.Sum256([]byte(password))
+	a.mu.Lock()
+	last, ok := a.verified[user]
+	a.mu.Unlock()
+	if ok && subtle.ConstantTimeCompare(last[:], digest[:]) == 1 {
+		return true
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return false
+	}
+	a.mu.Lock()
+	a.verified[user] = digest
+	a.mu.Unlock()
+	return true
+}
+
+// checkToken returns the name of the bearer token, if it is valid.
+func (a *authenticator) checkToken(token string) (string, bool) {
+	var name string
+	for _, t := range a.conf.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			name = t.Name
+		}
+	}
+	return name, name != ""
+}
+
+func (a *authenticator) logFailure(c echo.Context, method, user string) {
+	slog.LogAttrs(
+		c.Request().Context(),
+		slog.LevelWarn,
+		"authentication failed",
+		slog.String("method", method),
+		slog.String("user", user),
+		slog.String("path", c.Request().URL.Path),
+		slog.String("remoteAddr", c.RealIP()),
+	)
+}
+
+// unauthorized responds with 401 Unauthorized, challenging the client to
+// authenticate with basic auth or a bearer token.
+func (a *authenticator) unauthorized(c echo.Context, machine bool) error {
+	switch {
+	case a.conf.Basic.Enable:
+		c.Response().Header().Set(
+			echo.HeaderWWWAuthenticate,
+			fmt.Sprintf("Basic realm=%q", a.conf.Basic.Realm),
+		)
+	case machine && len(a.conf.Tokens) != 0:
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+	}
+	if machine {
+		return respondError(c, http.StatusUnauthorized, "unauthorized")
+	}
+	return renderAuthError(c, "You need to log in to see this page", http.StatusUnauthorized)
+}
+
+// OIDCLogin redirects the user to the login page of the OIDC provider. Once
+// logged in, the user is redirected to the path in the `next` query
+// parameter.
+func (s Srv) OIDCLogin(c echo.Context) error {
+	state := loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: oauth2.GenerateVerifier(),
+		Next:     safeRedirect(c.QueryParam("next")),
+		Expires:  time.Now().Add(loginTimeout),
+	}
+	u, err := s.auth.oidc.authCodeURL(c.Request().Context(), state)
+	if err != nil {
+		return renderAuthError(c, err.Error(), http.StatusBadGateway)
+	}
+	err = s.auth.cookies.set(c.Response(), stateCookie, state, state.Expires)
+	if err != nil {
+		return renderAuthError(c, err.Error(), http.StatusInternalServerError)
+	}
+	return c.Redirect(http.StatusFound, u)
+}
+
+// OIDCCallback completes the login of the user redirected back by the OIDC
+// provider, and starts their session.
+func (s Srv) OIDCCallback(c echo.Context) error {
+	var state loginState
+	err := s.auth.cookies.get(c.Request(), stateCookie, &state)
+	s.auth.cookies.clear(c.Response(), stateCookie)
+	if err != nil {
+		return renderAuthError(c, "The login has expired, kindly try again", http.StatusBadRequest)
+	}
+	if msg := c.QueryParam("error"); msg != "" {
+		if desc := c.QueryParam("error_description"); desc != "" {
+			msg = fmt.Sprintf("%s: %s", msg, desc)
+		}
+		return renderAuthError(c, msg, http.StatusUnauthorized)
+	}
+	if subtle.ConstantTimeCompare([]byte(c.QueryParam("state")), []byte(state.State)) != 1 {
+		return renderAuthError(c, "The login state does not match, kindly try again", http.StatusBadRequest)
+	}
+
+	claims, err := s.auth.oidc.exchange(c.Request().Context(), c.QueryParam("code"), state)
+	if err != nil {
+		slog.LogAttrs(
+			c.Request().Context(),
+			slog.LevelWarn,
+			"authentication failed",
+			slog.String("method", "oidc"),
+			slog.String("error", err.Error()),
+		)
+		return renderAuthError(c, "Failed to log in with the identity provider", http.StatusUnauthorized)
+	}
+
+	sess := session{
+		Subject: claims.Subject,
+		Name:    claims.name(),
+		Expires: time.Now().Add(s.conf.Web.Auth.OIDC.SessionTTL),
+	}
+	err = s.auth.cookies.set(c.Response(), sessionCookie, sess, sess.Expires)
+	if err != nil {
+		return renderAuthError(c, err.Error(), http.StatusInternalServerError)
+	}
+	return c.Redirect(http.StatusFound, state.Next)
+}
+
+// OIDCLogout ends the session of the user.
+func (s Srv) OIDCLogout(c echo.Context) error {
+	s.auth.cookies.clear(c.Response(), sessionCookie)
+	return c.Redirect(http.StatusFound, "/")
+}
+
+// safeRedirect returns next if it is a path on this server, and "/"
+// otherwise, so that the login cannot redirect users to another site.
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") ||
+		strings.HasPrefix(next, "//") ||
+		strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+func renderAuthError(c echo.Context, msg string, status int) error {
+	return render(renderParams{
+		Ctx: c,
+		Component: layout.Base(
+			"AccuKnox Reports",
+			partial.Navbar(false),
+			view.Error(msg, status),
+		),
+		Status: status,
+	})
+}
@@ -0,0 +1,315 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestParseHtpasswd(t *testing.T) {
+	a := assert.New(t)
+	inputs := map[string]int{
+		"alice:$2y$05$abc\nbob:$2a$05$def\n":      2,
+		"# comment\n\nalice:$2y$05$abc":           1,
+		"alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=": -1,
+		"alice": -1,
+	}
+	for input, expected := range inputs {
+		users, err := parseHtpasswd(strings.NewReader(input))
+		if expected == -1 {
+			a.Error(err, "INPUT=%s", input)
+			continue
+		}
+		a.NoError(err, "INPUT=%s", input)
+		a.Len(users, expected, "INPUT=%s", input)
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	a := assert.New(t)
+	inputs := map[string]string{
+		"":                     "/",
+		"/":                    "/",
+		"/20240701120000/ceph": "/20240701120000/ceph",
+		"/api/v1/runs?limit=1": "/api/v1/runs?limit=1",
+		"//evil.example.com":   "/",
+		"/\\evil.example.com":  "/",
+		"https://evil.example": "/",
+		"javascript:alert(1)":  "/",
+	}
+	for input, expected := range inputs {
+		a.Equal(expected, safeRedirect(input), "INPUT=%s", input)
+	}
+}
+
+func TestBasicAndBearerAuth(t *testing.T) {
+	a := assert.New(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	a.NoError(err)
+	c := conf.C{}
+	c.Web.Auth = conf.Auth{
+		Basic: conf.BasicAuth{
+			Enable: true,
+			Users:  map[string]string{"alice": string(hash)},
+			Realm:  "RINC",
+		},
+		Tokens: []conf.BearerToken{{Name: "prometheus", Token: "t0ken"}},
+	}
+	s, err := NewSrv(c, nil)
+	a.NoError(err)
+
+	type request struct {
+		path, user, password, token string
+	}
+	inputs := map[request]int{
+		{path: "/"}: http.StatusUnauthorized,
+		{path: "/", user: "alice", password: "s3cret"}:                 http.StatusOK,
+		{path: "/", user: "alice", password: "wrong"}:                  http.StatusUnauthorized,
+		{path: "/", user: "bob", password: "s3cret"}:                   http.StatusUnauthorized,
+		{path: "/", token: "t0ken"}:                                    http.StatusUnauthorized,
+		{path: "/api/v1/reporters"}:                                    http.StatusUnauthorized,
+		{path: "/api/v1/reporters", token: "t0ken"}:                    http.StatusOK,
+		{path: "/api/v1/reporters", token: "wrong"}:                    http.StatusUnauthorized,
+		{path: "/api/v1/reporters", user: "alice", password: "s3cret"}: http.StatusOK,
+	}
+	for input, expected := range inputs {
+		req := httptest.NewRequest(http.MethodGet, input.path, nil)
+		if input.user != "" {
+			req.SetBasicAuth(input.user, input.password)
+		}
+		if input.token != "" {
+			req.Header.Set("Authorization", "Bearer "+input.token)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		a.Equal(expected, rec.Code, "INPUT=%+v", input)
+		if expected == http.StatusUnauthorized {
+			a.Equal(`Basic realm="RINC"`, rec.Header().Get("WWW-Authenticate"), "INPUT=%+v", input)
+		}
+	}
+}
+
+func TestOIDC(t *testing.T) {
+	a := assert.New(t)
+	issuer := newMockIssuer(t, "rinc")
+	defer issuer.Close()
+
+	var handler http.Handler
+	rinc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	defer rinc.Close()
+
+	c := conf.C{}
+	c.Web.Auth.OIDC = conf.OIDCAuth{
+		Enable:       true,
+		IssuerURL:    issuer.URL,
+		ClientID:     "rinc",
+		ClientSecret: "client-secret",
+		RedirectURL:  rinc.URL + "/auth/callback",
+		Scopes:       []string{"email"},
+		SessionKey:   strings.Repeat("k", 32),
+		SessionTTL:   time.Hour,
+	}
+	s, err := NewSrv(c, nil)
+	a.NoError(err)
+	handler = s.router
+
+	jar, err := cookiejar.New(nil)
+	a.NoError(err)
+	client := &http.Client{Jar: jar}
+
+	// browsers are redirected to the provider, and back once logged in
+	resp, err := client.Get(rinc.URL + "/?from=login")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusOK, resp.StatusCode)
+	a.Equal("/", resp.Request.URL.Path)
+	a.Equal("from=login", resp.Request.URL.RawQuery)
+	a.Equal(1, issuer.logins)
+
+	// the session is used by the following requests
+	resp, err = client.Get(rinc.URL + "/api/v1/reporters")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusOK, resp.StatusCode)
+	a.Equal(1, issuer.logins)
+
+	// API clients without a session are not redirected
+	resp, err = http.Get(rinc.URL + "/api/v1/reporters")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	// forged sessions are rejected
+	u, _ := url.Parse(rinc.URL)
+	forged, _ := json.Marshal(session{Subject: "mallory", Expires: time.Now().Add(time.Hour)})
+	jar.SetCookies(u, []*http.Cookie{{
+		Name:  sessionCookie,
+		Value: base64.RawURLEncoding.EncodeToString(forged) + ".c2lnbmF0dXJl",
+		Path:  "/",
+	}})
+	noRedirect := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err = noRedirect.Get(rinc.URL + "/")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusFound, resp.StatusCode)
+	a.Equal("/auth/login?next=%2F", resp.Header.Get("Location"))
+
+	// logging out ends the session
+	resp, err = noRedirect.Get(rinc.URL + "/auth/logout")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Empty(jar.Cookies(u))
+	resp, err = noRedirect.Get(rinc.URL + "/")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusFound, resp.StatusCode)
+
+	// tokens issued for another client, or with another nonce, are rejected
+	issuer.audience = "another-client"
+	resp, err = client.Get(rinc.URL + "/")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusUnauthorized, resp.StatusCode)
+	issuer.audience, issuer.nonce = "", "replayed"
+	resp, err = client.Get(rinc.URL + "/")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusUnauthorized, resp.StatusCode)
+	a.Equal(3, issuer.logins)
+}
+
+// mockIssuer is a minimal OpenID Connect provider, which logs users in
+// without prompting them.
+type mockIssuer struct {
+	*httptest.Server
+	t        *testing.T
+	clientID string
+	key      *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]url.Values
+	logins int
+	// audience and nonce override the claims of the issued ID tokens.
+	audience, nonce string
+}
+
+func newMockIssuer(t *testing.T, clientID string) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{
+		t:        t,
+		clientID: clientID,
+		key:      key,
+		codes:    make(map[string]url.Values),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                m.URL,
+			AuthorizationEndpoint: m.URL + "/authorize",
+			TokenEndpoint:         m.URL + "/token",
+			JWKSURI:               m.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+			Key:       &m.key.PublicKey,
+			KeyID:     "test",
+			Algorithm: "RS256",
+			Use:       "sig",
+		}}})
+	})
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	m.Server = httptest.NewServer(mux)
+	return m
+}
+
+func (m *mockIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != m.clientID || q.Get("code_challenge_method") != "S256" ||
+		!strings.Contains(q.Get("scope"), "openid") {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	m.mu.Lock()
+	code := randomString()
+	m.codes[code] = q
+	m.logins++
+	m.mu.Unlock()
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	redirect.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	m.mu.Lock()
+	auth, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+	id, secret, _ := r.BasicAuth()
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || id != m.clientID || secret == "" ||
+		auth.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(verifier[:]) {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	audience, nonce := m.clientID, auth.Get("nonce")
+	if m.audience != "" {
+		audience = m.audience
+	}
+	if m.nonce != "" {
+		nonce = m.nonce
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, idTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.URL,
+			Subject:   "alice",
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		Nonce: nonce,
+		Email: "alice@example.com",
+	})
+	token.Header["kid"] = "test"
+	raw, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Error(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     raw,
+	})
+}
@@ -0,0 +1,204 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/diff"
+	"github.com/accuknox/rinc/internal/schema"
+	"github.com/accuknox/rinc/internal/util"
+	"github.com/accuknox/rinc/view"
+	diffView "github.com/accuknox/rinc/view/diff"
+	"github.com/accuknox/rinc/view/layout"
+	"github.com/accuknox/rinc/view/partial"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultDiffRange is how far back the run compared with the latest one is,
+// when none is selected.
+const defaultDiffRange = 24 * time.Hour
+
+// diffReporters are the reporters that can be compared, the alerts of every
+// reporter being compared as a whole.
+var diffReporters = append(slices.Clone(db.Collections), db.CollectionAlerts)
+
+// errDiffParams is returned by diffReport when the query parameters are
+// invalid.
+var errDiffParams = errors.New("invalid parameters")
+
+// DiffPage shows the changes between the reports of two runs.
+func (s Srv) DiffPage(c echo.Context) error {
+	report, err := s.diffReport(c)
+	fromValue, toValue := c.QueryParam("from"), c.QueryParam("to")
+	if err == nil {
+		fromValue = report.From.Format(util.IsosecLayout)
+		toValue = report.To.Format(util.IsosecLayout)
+	}
+	form := diffView.Form(diffReporters, c.QueryParam("reporter"), fromValue, toValue)
+
+	status := http.StatusOK
+	result := diffView.Report(report)
+	switch {
+	case errors.Is(err, errDiffParams):
+		status, result = http.StatusBadRequest, view.Error(err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errNotFound):
+		status, result = http.StatusNotFound, view.Error("no reports found", http.StatusNotFound)
+	case err != nil:
+		status, result = http.StatusInternalServerError, view.Error(err.Error(), http.StatusInternalServerError)
+	}
+	return render(renderParams{
+		Ctx: c,
+		Component: layout.Base(
+			"Changes | AccuKnox Reports",
+			partial.Navbar(false),
+			form,
+			result,
+		),
+		Status: status,
+	})
+}
+
+// APIDiff returns the changes between the reports of two runs.
+func (s Srv) APIDiff(c echo.Context) error {
+	report, err := s.diffReport(c)
+	switch {
+	case errors.Is(err, errDiffParams):
+		return respondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errNotFound):
+		return respondError(c, http.StatusNotFound, "no reports found")
+	case err != nil:
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return respond(c, http.StatusOK, report)
+}
+
+// diffReport compares the reports at the points in time selected by the
+// `from` and `to` query parameters, `to` defaulting to now and `from` to a
+// day before `to`. The `reporter` query parameter limits the comparison to a
+// single reporter.
+func (s Srv) diffReport(c echo.Context) (diff.Report, error) {
+	ctx := c.Request().Context()
+	reporters := diffReporters
+	if reporter := c.QueryParam("reporter"); reporter != "" {
+		if !slices.Contains(diffReporters, reporter) {
+			return diff.Report{}, fmt.Errorf("%w: unknown reporter %q", errDiffParams, reporter)
+		}
+		reporters = []string{reporter}
+	}
+
+	to := time.Now().UTC()
+	if v := c.QueryParam("to"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return diff.Report{}, fmt.Errorf("%w: parsing `to`: %w", errDiffParams, err)
+		}
+		to = t
+	}
+	from := to.Add(-defaultDiffRange)
+	if v := c.QueryParam("from"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return diff.Report{}, fmt.Errorf("%w: parsing `from`: %w", errDiffParams, err)
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return diff.Report{}, fmt.Errorf("%w: `from` must be before `to`", errDiffParams)
+	}
+	return s.diffRuns(ctx, from, to, reporters)
+}
+
+// diffRuns compares the latest reports of the reporters at or before the
+// provided points in time. Reporters may run on different schedules, so the
+// reports are looked up separately for each reporter. It returns errNotFound
+// if none of the reporters has a report at or before `to`.
+func (s Srv) diffRuns(ctx context.Context, from, to time.Time, reporters []string) (diff.Report, error) {
+	report := diff.Report{
+		From:      from,
+		To:        to,
+		Reporters: make([]diff.ReporterReport, 0, len(reporters)),
+	}
+	var found bool
+	for _, reporter := range reporters {
+		r := diff.ReporterReport{Reporter: reporter}
+		snapshots := make([]any, 2)
+		for idx, at := range []time.Time{from, to} {
+			snapshot, stamp, err := s.fetchSnapshot(ctx, reporter, at)
+			if errors.Is(err, errNotFound) {
+				r.Missing = append(r.Missing, at)
+				continue
+			}
+			if err != nil {
+				return diff.Report{}, err
+			}
+			found = true
+			snapshots[idx] = snapshot
+			if idx == 0 {
+				r.From = &stamp
+			} else {
+				r.To = &stamp
+			}
+		}
+		if len(r.Missing) == 0 {
+			changes, err := diff.Compare(snapshots[0], snapshots[1])
+			if err != nil {
+				return diff.Report{}, fmt.Errorf("comparing %q reports: %w", reporter, err)
+			}
+			r.Changes = changes
+		}
+		report.Reporters = append(report.Reporters, r)
+	}
+	if !found {
+		return diff.Report{}, errNotFound
+	}
+	return report, nil
+}
+
+// fetchSnapshot returns the latest metrics written by the reporter at or
+// before the provided time, along with their timestamp. The snapshot of the
+// alerts maps each reporter to the alerts of its latest report, along with
+// the timestamp of the latest of these reports.
+func (s Srv) fetchSnapshot(ctx context.Context, reporter string, at time.Time) (any, time.Time, error) {
+	if reporter == db.CollectionAlerts {
+		alerts := make(map[string][]db.Alert)
+		var latest time.Time
+		for _, coll := range db.Collections {
+			stamp, err := s.fetchMetricsAtOrBefore(ctx, coll, at, nil)
+			if errors.Is(err, errNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			docs, err := s.store.Alerts(ctx, stamp, coll)
+			if err != nil {
+				return nil, time.Time{}, fmt.Errorf("finding alerts from %q at %v: %w", coll, stamp, err)
+			}
+			for _, doc := range docs {
+				alerts[doc.From] = doc.Alerts
+			}
+			if stamp.After(latest) {
+				latest = stamp
+			}
+		}
+		if latest.IsZero() {
+			return nil, time.Time{}, errNotFound
+		}
+		return alerts, latest, nil
+	}
+	metrics, err := schema.New(reporter)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	stamp, err := s.fetchMetricsAtOrBefore(ctx, reporter, at, metrics)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return metrics, stamp, nil
+}
@@ -0,0 +1,95 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/diff"
+	"github.com/accuknox/rinc/types/imagetag"
+	"github.com/accuknox/rinc/types/pod"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIDiffParams(t *testing.T) {
+	a := assert.New(t)
+	s, err := NewSrv(conf.C{}, nil)
+	a.NoError(err)
+	// the parameters are validated before looking up any run
+	inputs := []string{
+		"reporter=unknown",
+		"reporter=runs",
+		"to=yesterday",
+		"to=20240701120000&from=2024/07/01",
+	}
+	for _, input := range inputs {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/diff?"+input, nil)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		a.Equal(http.StatusBadRequest, rec.Code, "INPUT=%s", input)
+	}
+}
+
+func TestAPIDiff(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	b := newBolt(t)
+
+	// the image tags are reported hourly at :30 and the pods every 10
+	// minutes, so that their timestamps never match.
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	for idx, at := range []time.Time{start.Add(30 * time.Minute), start.Add(90 * time.Minute)} {
+		_, err := b.Insert(ctx, db.CollectionImageTag, imagetag.Metrics{
+			Timestamp:   at,
+			Deployments: []imagetag.Resource{{Name: "nginx", Namespace: "default", Images: []imagetag.Image{{Name: []string{"nginx:1.26", "nginx:1.27"}[idx]}}}},
+		})
+		a.NoError(err)
+	}
+	for at := start; at.Before(start.Add(2 * time.Hour)); at = at.Add(10 * time.Minute) {
+		_, err := b.Insert(ctx, db.CollectionPodStatus, pod.Metrics{Timestamp: at})
+		a.NoError(err)
+	}
+
+	s, err := NewSrv(conf.C{}, b)
+	a.NoError(err)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/diff?from=20240701124500&to=20240701135500", nil)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if !a.Equal(http.StatusOK, rec.Code, rec.Body.String()) {
+		return
+	}
+	var report diff.Report
+	a.NoError(json.Unmarshal(rec.Body.Bytes(), &report))
+	reporters := make(map[string]diff.ReporterReport)
+	for _, r := range report.Reporters {
+		reporters[r.Reporter] = r
+	}
+
+	tags := reporters[db.CollectionImageTag]
+	a.Empty(tags.Missing)
+	if a.NotNil(tags.From) && a.NotNil(tags.To) {
+		a.True(tags.From.Equal(start.Add(30 * time.Minute)))
+		a.True(tags.To.Equal(start.Add(90 * time.Minute)))
+	}
+	a.Len(tags.Changes, 2, "an image is removed and another one is added")
+
+	pods := reporters[db.CollectionPodStatus]
+	a.Empty(pods.Missing)
+	if a.NotNil(pods.From) && a.NotNil(pods.To) {
+		a.True(pods.From.Equal(start.Add(40 * time.Minute)))
+		a.True(pods.To.Equal(start.Add(110 * time.Minute)))
+	}
+
+	a.Equal([]time.Time{report.From, report.To}, reporters[db.CollectionCeph].Missing)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/diff?from=20240601000000&to=20240601120000", nil)
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	a.Equal(http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,291 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/schema"
+	"github.com/accuknox/rinc/types/ceph"
+	"github.com/accuknox/rinc/types/connectivity"
+	"github.com/accuknox/rinc/types/dass"
+	"github.com/accuknox/rinc/types/imagetag"
+	"github.com/accuknox/rinc/types/longjobs"
+	"github.com/accuknox/rinc/types/pod"
+	"github.com/accuknox/rinc/types/pv"
+	"github.com/accuknox/rinc/types/rabbitmq"
+	"github.com/accuknox/rinc/types/resource"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "rinc"
+
+func newDesc(name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", name),
+		help,
+		labels,
+		nil,
+	)
+}
+
+var (
+	descRunTimestamp      = newDesc("run_timestamp_seconds", "Timestamp of the latest scraper run.")
+	descRunDuration       = newDesc("run_duration_seconds", "Duration of the latest scraper run.")
+	descRunSuccess        = newDesc("run_success", "Whether every reporter of the latest scraper run succeeded.")
+	descReporterDuration  = newDesc("reporter_duration_seconds", "Duration of the reporter in the latest scraper run.", "reporter")
+	descReporterSuccess   = newDesc("reporter_success", "Whether the reporter succeeded in the latest scraper run.", "reporter")
+	descReportTimestamp   = newDesc("report_timestamp_seconds", "Timestamp of the latest report of the reporter.", "reporter")
+	descAlerts            = newDesc("alerts", "Number of alerts fired by the latest report of the reporter.", "reporter", "severity")
+	descPVCapacity        = newDesc("pv_capacity_bytes", "Capacity of the persistent volume.", "namespace", "pvc")
+	descPVUsed            = newDesc("pv_used_bytes", "Used bytes of the persistent volume.", "namespace", "pvc")
+	descPVAvailable       = newDesc("pv_available_bytes", "Available bytes of the persistent volume.", "namespace", "pvc")
+	descPVUtilization     = newDesc("pv_utilization_percent", "Utilization of the persistent volume.", "namespace", "pvc")
+	descNodeCPU           = newDesc("node_cpu_used_percent", "CPU utilization of the node.", "node")
+	descNodeMemory        = newDesc("node_memory_used_percent", "Memory utilization of the node.", "node")
+	descContainerCPU      = newDesc("container_cpu_used_percent", "CPU utilization of the container relative to its limit.", "namespace", "pod", "container")
+	descContainerMemory   = newDesc("container_memory_used_percent", "Memory utilization of the container relative to its limit.", "namespace", "pod", "container")
+	descRabbitmqUp        = newDesc("rabbitmq_up", "Whether the RabbitMQ cluster is up.")
+	descRabbitmqMessages  = newDesc("rabbitmq_queue_messages", "Number of messages in the queue.", "queue")
+	descRabbitmqReady     = newDesc("rabbitmq_queue_messages_ready", "Number of messages ready to be delivered in the queue.", "queue")
+	descRabbitmqUnacked   = newDesc("rabbitmq_queue_messages_unacknowledged", "Number of unacknowledged messages in the queue.", "queue")
+	descRabbitmqConsumers = newDesc("rabbitmq_consumers", "Number of consumers.")
+	descCephHealth        = newDesc("ceph_health", "Health status of the CEPH cluster.", "status")
+	descCephTotal         = newDesc("ceph_total_bytes", "Total capacity of the CEPH cluster.")
+	descCephAvailable     = newDesc("ceph_available_bytes", "Available capacity of the CEPH cluster.")
+	descCephUsed          = newDesc("ceph_used_bytes", "Used raw capacity of the CEPH cluster.")
+	descWorkloadReplicas  = newDesc("workload_ready_replicas", "Number of ready replicas of the workload.", "kind", "namespace", "name")
+	descWorkloadDesired   = newDesc("workload_desired_replicas", "Number of desired replicas of the workload.", "kind", "namespace", "name")
+	descWorkloadAvailable = newDesc("workload_available", "Whether the workload is available.", "kind", "namespace", "name")
+	descLongJobs          = newDesc("long_running_jobs", "Number of jobs running for longer than the configured duration.")
+	descConnectivity      = newDesc("connectivity_up", "Whether the service is reachable.", "service")
+	descLookupErrors      = newDesc("exporter_lookup_errors", "Number of lookups that failed while collecting the metrics.")
+)
+
+// Metrics serves the values of the latest report of each reporter, along
+// with the outcome of the latest scraper run, in the Prometheus exposition
+// format. The values are looked up when the endpoint is scraped.
+func (s Srv) Metrics(c echo.Context) error {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(exporter{
+		ctx:       c.Request().Context(),
+		latest:    s.fetchLatestMetrics,
+		latestRun: s.fetchLatestRun,
+		alerts:    s.fetchAlerts,
+	})
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{
+		ErrorLog:      promLogger{},
+		ErrorHandling: promhttp.ContinueOnError,
+	}).ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// exporter is a prometheus.Collector exposing the latest reports.
+type exporter struct {
+	ctx context.Context
+	// latest decodes the latest document of the collection into v. It
+	// returns errNotFound if there is none.
+	latest func(ctx context.Context, coll string, v any) error
+	// latestRun returns the latest run manifest, or nil if there is none.
+	latestRun func(ctx context.Context) (*db.RunDocument, error)
+	// alerts returns the alerts fired by the reporter at the timestamp.
+	alerts func(ctx context.Context, from string, at time.Time) ([]db.Alert, error)
+}
+
+func (e exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		descRunTimestamp, descRunDuration, descRunSuccess,
+		descReporterDuration, descReporterSuccess,
+		descReportTimestamp, descAlerts,
+		descPVCapacity, descPVUsed, descPVAvailable, descPVUtilization,
+		descNodeCPU, descNodeMemory, descContainerCPU, descContainerMemory,
+		descRabbitmqUp, descRabbitmqMessages, descRabbitmqReady,
+		descRabbitmqUnacked, descRabbitmqConsumers,
+		descCephHealth, descCephTotal, descCephAvailable, descCephUsed,
+		descWorkloadReplicas, descWorkloadDesired, descWorkloadAvailable,
+		descLongJobs, descConnectivity, descLookupErrors,
+	} {
+		ch <- desc
+	}
+}
+
+func (e exporter) Collect(ch chan<- prometheus.Metric) {
+	var failed int
+	logError := func(msg, coll string, err error) {
+		failed++
+		slog.LogAttrs(
+			e.ctx,
+			slog.LevelError,
+			msg,
+			slog.String("collection", coll),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	run, err := e.latestRun(e.ctx)
+	if err != nil {
+		logError("looking up latest run", db.CollectionRuns, err)
+	}
+	if run != nil {
+		collectRun(ch, *run)
+	}
+
+	for _, coll := range db.Collections {
+		metrics, err := schema.New(coll)
+		if err != nil {
+			logError("looking up latest report", coll, err)
+			continue
+		}
+		err = e.latest(e.ctx, coll, metrics)
+		if err != nil {
+			if !errors.Is(err, errNotFound) {
+				logError("looking up latest report", coll, err)
+			}
+			continue
+		}
+		at := collectReport(ch, metrics)
+		ch <- gauge(descReportTimestamp, float64(at.Unix()), coll)
+
+		alerts, err := e.alerts(e.ctx, coll, at)
+		if err != nil {
+			logError("looking up alerts", coll, err)
+			continue
+		}
+		counts := map[conf.Severity]int{
+			conf.SeverityInfo:     0,
+			conf.SeverityWarning:  0,
+			conf.SeverityCritical: 0,
+		}
+		for _, a := range alerts {
+			counts[a.Severity]++
+		}
+		for severity, n := range counts {
+			ch <- gauge(descAlerts, float64(n), coll, string(severity))
+		}
+	}
+	ch <- gauge(descLookupErrors, float64(failed))
+}
+
+// collectRun exposes the outcome of the run.
+func collectRun(ch chan<- prometheus.Metric, run db.RunDocument) {
+	ch <- gauge(descRunTimestamp, float64(run.Timestamp.Unix()))
+	ch <- gauge(descRunDuration, run.Duration.Seconds())
+	ch <- gauge(descRunSuccess, boolValue(run.Status == db.RunStatusSuccess))
+	for _, r := range run.Reporters {
+		if r.Status == db.RunStatusSkipped {
+			continue
+		}
+		ch <- gauge(descReporterDuration, r.Duration.Seconds(), r.Name)
+		ch <- gauge(descReporterSuccess, boolValue(r.Status == db.RunStatusSuccess), r.Name)
+	}
+}
+
+// collectReport exposes the values of the report, and returns the time it
+// was scraped at.
+func collectReport(ch chan<- prometheus.Metric, metrics any) time.Time {
+	switch m := metrics.(type) {
+	case *pv.Metrics:
+		for _, v := range m.PVs {
+			ch <- gauge(descPVCapacity, v.Capacity, v.PVCNamespace, v.PVC)
+			ch <- gauge(descPVUsed, v.Used, v.PVCNamespace, v.PVC)
+			ch <- gauge(descPVAvailable, v.Available, v.PVCNamespace, v.PVC)
+			ch <- gauge(descPVUtilization, v.UtilizationPercent, v.PVCNamespace, v.PVC)
+		}
+		return m.Timestamp
+
+	case *resource.Metrics:
+		for _, n := range m.Nodes {
+			ch <- gauge(descNodeCPU, n.CPUUsedPercent, n.Name)
+			ch <- gauge(descNodeMemory, n.MemUsedPercent, n.Name)
+		}
+		for _, c := range m.Containers {
+			ch <- gauge(descContainerCPU, c.CPUUsedPercent, c.Namespace, c.PodName, c.Name)
+			ch <- gauge(descContainerMemory, c.MemUsedPercent, c.Namespace, c.PodName, c.Name)
+		}
+		return m.Timestamp
+
+	case *rabbitmq.Metrics:
+		ch <- gauge(descRabbitmqUp, boolValue(m.IsClusterUp))
+		for _, q := range m.Queues {
+			ch <- gauge(descRabbitmqMessages, float64(q.Messages), q.Name)
+			ch <- gauge(descRabbitmqReady, float64(q.ReadyMessages), q.Name)
+			ch <- gauge(descRabbitmqUnacked, float64(q.UnacknowledgedMessages), q.Name)
+		}
+		ch <- gauge(descRabbitmqConsumers, float64(m.Overview.ObjectTotals.Consumers))
+		return m.Timestamp
+
+	case *ceph.Metrics:
+		if status := m.Status.Health.Status; status != "" {
+			ch <- gauge(descCephHealth, 1, status)
+		}
+		stats := m.Status.DF.Stats
+		ch <- gauge(descCephTotal, float64(stats.TotalBytes))
+		ch <- gauge(descCephAvailable, float64(stats.TotalAvailBytes))
+		ch <- gauge(descCephUsed, float64(stats.TotalUsedBytes))
+		return m.Timestamp
+
+	case *dass.Metrics:
+		collectWorkloads(ch, "deployment", m.Deployments)
+		collectWorkloads(ch, "statefulset", m.Statefulsets)
+		return m.Timestamp
+
+	case *longjobs.Metrics:
+		ch <- gauge(descLongJobs, float64(len(m.Jobs)))
+		return m.Timestamp
+
+	case *connectivity.Metrics:
+		ch <- gauge(descConnectivity, boolValue(m.Vault.Reachable), "vault")
+		ch <- gauge(descConnectivity, boolValue(m.Mongodb.Reachable), "mongodb")
+		ch <- gauge(descConnectivity, boolValue(m.Neo4j.Reachable), "neo4j")
+		ch <- gauge(descConnectivity, boolValue(m.Postgres.Reachable), "postgres")
+		ch <- gauge(descConnectivity, boolValue(m.Redis.Reachable), "redis")
+		ch <- gauge(descConnectivity, boolValue(m.Metabase.Healthy), "metabase")
+		return m.Timestamp
+
+	// the remaining reports hold no values worth graphing; only their
+	// timestamp is exposed.
+	case *imagetag.Metrics:
+		return m.Timestamp
+	case *pod.Metrics:
+		return m.Timestamp
+	}
+	return time.Time{}
+}
+
+func collectWorkloads(ch chan<- prometheus.Metric, kind string, workloads []dass.Resource) {
+	for _, w := range workloads {
+		ch <- gauge(descWorkloadReplicas, float64(w.ReadyReplicas), kind, w.Namespace, w.Name)
+		ch <- gauge(descWorkloadDesired, float64(w.DesiredReplicas), kind, w.Namespace, w.Name)
+		ch <- gauge(descWorkloadAvailable, boolValue(w.IsAvailable), kind, w.Namespace, w.Name)
+	}
+}
+
+func gauge(desc *prometheus.Desc, v float64, labels ...string) prometheus.Metric {
+	return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// promLogger logs the errors of the metrics handler.
+type promLogger struct{}
+
+func (promLogger) Println(v ...any) {
+	slog.LogAttrs(
+		context.Background(),
+		slog.LevelError,
+		"serving metrics",
+		slog.String("error", fmt.Sprint(v...)),
+	)
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/types/pv"
+	"github.com/accuknox/rinc/types/rabbitmq"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporter(t *testing.T) {
+	a := assert.New(t)
+	at := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	e := exporter{
+		ctx: context.TODO(),
+		latestRun: func(context.Context) (*db.RunDocument, error) {
+			return &db.RunDocument{
+				Timestamp: at,
+				Duration:  90 * time.Second,
+				Status:    db.RunStatusPartial,
+				Reporters: []db.ReporterRun{
+					{Name: db.CollectionPVUtilizaton, Status: db.RunStatusSuccess, Duration: 2 * time.Second},
+					{Name: db.CollectionRabbitmq, Status: db.RunStatusFailed},
+					{Name: db.CollectionCeph, Status: db.RunStatusSkipped},
+				},
+			}, nil
+		},
+		latest: func(_ context.Context, coll string, v any) error {
+			switch m := v.(type) {
+			case *pv.Metrics:
+				m.Timestamp = at
+				m.PVs = pv.PVs{{PVC: "data", PVCNamespace: "mongo", Capacity: 1024, UtilizationPercent: 87.5}}
+				return nil
+			case *rabbitmq.Metrics:
+				m.Timestamp = at
+				m.IsClusterUp = true
+				m.Queues = rabbitmq.Queues{{Name: "events", Messages: 42}}
+				return nil
+			}
+			if coll == db.CollectionCeph {
+				return errors.New("connection refused")
+			}
+			return errNotFound
+		},
+		alerts: func(_ context.Context, from string, _ time.Time) ([]db.Alert, error) {
+			if from != db.CollectionPVUtilizaton {
+				return nil, nil
+			}
+			return []db.Alert{{Severity: conf.SeverityCritical}, {Severity: conf.SeverityCritical}}, nil
+		},
+	}
+	reg := prometheus.NewRegistry()
+	a.NoError(reg.Register(e))
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	a.Equal(http.StatusOK, rec.Code)
+
+	expected := []string{
+		`rinc_run_timestamp_seconds 1.7198352e+09`,
+		`rinc_run_duration_seconds 90`,
+		`rinc_run_success 0`,
+		`rinc_reporter_duration_seconds{reporter="pv_utilization"} 2`,
+		`rinc_reporter_success{reporter="pv_utilization"} 1`,
+		`rinc_reporter_success{reporter="rabbitmq"} 0`,
+		`rinc_report_timestamp_seconds{reporter="pv_utilization"} 1.7198352e+09`,
+		`rinc_pv_capacity_bytes{namespace="mongo",pvc="data"} 1024`,
+		`rinc_pv_utilization_percent{namespace="mongo",pvc="data"} 87.5`,
+		`rinc_alerts{reporter="pv_utilization",severity="critical"} 2`,
+		`rinc_alerts{reporter="pv_utilization",severity="info"} 0`,
+		`rinc_alerts{reporter="rabbitmq",severity="critical"} 0`,
+		`rinc_rabbitmq_up 1`,
+		`rinc_rabbitmq_queue_messages{queue="events"} 42`,
+		`rinc_exporter_lookup_errors 1`,
+	}
+	body := rec.Body.String()
+	for _, line := range expected {
+		a.Contains(body, line+"\n", "INPUT=%s", line)
+	}
+	a.NotContains(body, `reporter="ceph"`)
+}
@@ -0,0 +1,225 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/store"
+	"github.com/accuknox/rinc/view/trend"
+)
+
+// errNotFound is returned by the lookups below when there is no document to
+// return.
+var errNotFound = store.ErrNotFound
+
+// fetchMetrics decodes the document written to the provided collection at
+// the provided timestamp into metrics. It returns errNotFound if there is
+// none.
+func (s Srv) fetchMetrics(ctx context.Context, coll string, at time.Time, metrics any) error {
+	err := s.store.Find(ctx, coll, at, metrics)
+	if err != nil && !errors.Is(err, errNotFound) {
+		return fmt.Errorf("finding %q document at %v: %w", coll, at, err)
+	}
+	return err
+}
+
+// fetchLatestMetrics decodes the latest document written to the provided
+// collection into metrics. It returns errNotFound if there is none.
+func (s Srv) fetchLatestMetrics(ctx context.Context, coll string, metrics any) error {
+	_, err := s.store.Latest(ctx, coll, metrics)
+	if err != nil && !errors.Is(err, errNotFound) {
+		return fmt.Errorf("finding latest %q document: %w", coll, err)
+	}
+	return err
+}
+
+// fetchMetricsAtOrBefore decodes the latest document written to the provided
+// collection at or before the provided time into metrics, unless metrics is
+// nil, and returns its timestamp. It returns errNotFound if there is none.
+func (s Srv) fetchMetricsAtOrBefore(ctx context.Context, coll string, at time.Time, metrics any) (time.Time, error) {
+	stamp, err := s.store.AtOrBefore(ctx, coll, at, metrics)
+	if err != nil && !errors.Is(err, errNotFound) {
+		return time.Time{}, fmt.Errorf("finding latest %q document at or before %v: %w", coll, at, err)
+	}
+	return stamp, err
+}
+
+// fetchMetricsRange decodes the documents written to the provided collection
+// between from (inclusive) and to (exclusive), oldest first, skipping the
+// first offset documents and returning at most limit documents. newMetrics
+// returns the value to decode each document into. The total number of
+// documents in the range is returned along with the page.
+func (s Srv) fetchMetricsRange(
+	ctx context.Context,
+	coll string,
+	from, to time.Time,
+	offset, limit int,
+	newMetrics func() any,
+) ([]any, int, error) {
+	return s.store.List(ctx, coll, from, to, offset, limit, newMetrics)
+}
+
+// fetchAlerts returns the alerts fired by the reporter writing to the
+// provided collection at the provided timestamp. It returns nil if there are
+// none.
+func (s Srv) fetchAlerts(ctx context.Context, from string, at time.Time) ([]db.Alert, error) {
+	docs, err := s.store.Alerts(ctx, at, from)
+	if err != nil {
+		return nil, fmt.Errorf("finding alerts from %q at %v: %w", from, at, err)
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	return docs[0].Alerts, nil
+}
+
+// fetchAllAlerts returns the alert documents of every reporter at the
+// provided timestamp.
+func (s Srv) fetchAllAlerts(ctx context.Context, at time.Time) ([]db.AlertDocument, error) {
+	return s.store.Alerts(ctx, at, "")
+}
+
+// fetchRun returns the run manifest recorded at the provided timestamp. It
+// returns nil if there is none.
+func (s Srv) fetchRun(ctx context.Context, at time.Time) (*db.RunDocument, error) {
+	run := new(db.RunDocument)
+	err := s.fetchMetrics(ctx, db.CollectionRuns, at, run)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+// fetchLatestRun returns the latest run manifest. It returns nil if there is
+// none.
+func (s Srv) fetchLatestRun(ctx context.Context) (*db.RunDocument, error) {
+	run := new(db.RunDocument)
+	err := s.fetchLatestMetrics(ctx, db.CollectionRuns, run)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+// fetchRuns returns the runs recorded between from (inclusive) and to
+// (exclusive), oldest first, skipping the first offset runs and returning at
+// most limit runs, 0 meaning no limit. The total number of runs in the range
+// is returned along with them. Reports generated before the earliest run
+// manifest, e.g., before run manifests were recorded, are returned as runs
+// holding only a timestamp.
+func (s Srv) fetchRuns(ctx context.Context, from, to time.Time, offset, limit int) ([]db.RunDocument, int, error) {
+	legacy, err := s.fetchLegacyRuns(ctx, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+	var runs []db.RunDocument
+	if offset < len(legacy) {
+		end := len(legacy)
+		if limit != 0 {
+			end = min(end, offset+limit)
+		}
+		runs = append(runs, legacy[offset:end]...)
+	}
+
+	listLimit := 0
+	if limit != 0 {
+		// the manifests are still listed when the page is filled with
+		// legacy runs, as their total is needed.
+		listLimit = max(limit-len(runs), 1)
+	}
+	docs, total, err := s.store.List(ctx, db.CollectionRuns, from, to, max(offset-len(legacy), 0), listLimit, newRunDocument)
+	if err != nil {
+		return nil, 0, fmt.Errorf("finding runs: %w", err)
+	}
+	for _, doc := range docs {
+		if limit != 0 && len(runs) == limit {
+			break
+		}
+		runs = append(runs, *doc.(*db.RunDocument))
+	}
+	return runs, len(legacy) + total, nil
+}
+
+// fetchLegacyRuns returns the runs between from (inclusive) and to
+// (exclusive) that were generated before the earliest run manifest, oldest
+// first. They are found by probing every collection, and hold only a
+// timestamp.
+func (s Srv) fetchLegacyRuns(ctx context.Context, from, to time.Time) ([]db.RunDocument, error) {
+	first, _, err := s.store.List(ctx, db.CollectionRuns, time.Time{}, to, 0, 1, newRunDocument)
+	if err != nil {
+		return nil, fmt.Errorf("finding earliest run: %w", err)
+	}
+	if len(first) != 0 {
+		to = first[0].(*db.RunDocument).Timestamp
+	}
+	if !from.Before(to) {
+		return nil, nil
+	}
+
+	var runs []db.RunDocument
+	seen := make(map[int64]bool)
+	for _, coll := range db.Collections {
+		stamps, err := s.store.Timestamps(ctx, coll, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range stamps {
+			if seen[t.UnixNano()] {
+				continue
+			}
+			seen[t.UnixNano()] = true
+			runs = append(runs, db.RunDocument{Timestamp: t})
+		}
+	}
+	slices.SortFunc(runs, func(a, b db.RunDocument) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return runs, nil
+}
+
+func newRunDocument() any {
+	return new(db.RunDocument)
+}
+
+// fetchSeries returns the points selected by the query from the documents
+// written to the collection between from (inclusive) and to (exclusive),
+// grouped into series.
+func (s Srv) fetchSeries(ctx context.Context, coll string, from, to time.Time, q store.SeriesQuery) ([]trend.Series, error) {
+	points, err := s.store.Series(ctx, coll, from, to, q)
+	if err != nil {
+		return nil, err
+	}
+	return groupSeries(points), nil
+}
+
+// groupSeries groups the points into series sorted by name, each holding its
+// points oldest first.
+func groupSeries(points []store.Point) []trend.Series {
+	byName := make(map[string][]trend.Point)
+	for _, p := range points {
+		byName[p.Series] = append(byName[p.Series], trend.Point{
+			Timestamp: p.Timestamp,
+			Value:     p.Value,
+		})
+	}
+	series := make([]trend.Series, 0, len(byName))
+	for _, name := range slices.Sorted(maps.Keys(byName)) {
+		pts := byName[name]
+		slices.SortFunc(pts, func(a, b trend.Point) int {
+			return a.Timestamp.Compare(b.Timestamp)
+		})
+		series = append(series, trend.Series{Name: name, Points: pts})
+	}
+	return series
+}
@@ -0,0 +1,86 @@
+package web
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/store"
+	"github.com/accuknox/rinc/types/pv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchRuns(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	b := newBolt(t)
+	s, err := NewSrv(conf.C{}, b)
+	a.NoError(err)
+
+	// the reports of 12:00 and 13:00 predate the run manifests, while the
+	// report of 15:00 has no manifest but is not probed for.
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	for idx := range 4 {
+		_, err := b.Insert(ctx, db.CollectionPVUtilizaton, pv.Metrics{
+			Timestamp: start.Add(time.Duration(idx) * time.Hour),
+		})
+		a.NoError(err)
+	}
+	_, err = b.Insert(ctx, db.CollectionRuns, db.RunDocument{
+		Timestamp: start.Add(2 * time.Hour),
+		Status:    db.RunStatusSuccess,
+	})
+	a.NoError(err)
+
+	runs, total, err := s.fetchRuns(ctx, start, start.Add(24*time.Hour), 0, 0)
+	a.NoError(err)
+	a.Equal(3, total)
+	a.Equal([]db.RunDocument{
+		{Timestamp: start},
+		{Timestamp: start.Add(time.Hour)},
+		{Timestamp: start.Add(2 * time.Hour), Status: db.RunStatusSuccess},
+	}, runs)
+
+	runs, total, err = s.fetchRuns(ctx, start.Add(2*time.Hour), start.Add(24*time.Hour), 0, 0)
+	a.NoError(err)
+	a.Equal(1, total)
+	a.Len(runs, 1)
+
+	// pages may span both the legacy runs and the run manifests.
+	type page struct {
+		offset, limit int
+	}
+	inputs := map[page][]time.Time{
+		{0, 1}: {start},
+		{1, 2}: {start.Add(time.Hour), start.Add(2 * time.Hour)},
+		{2, 5}: {start.Add(2 * time.Hour)},
+		{3, 1}: nil,
+	}
+	for input, expected := range inputs {
+		runs, total, err := s.fetchRuns(ctx, start, start.Add(24*time.Hour), input.offset, input.limit)
+		a.NoError(err, "INPUT=%v", input)
+		a.Equal(3, total, "INPUT=%v", input)
+		var stamps []time.Time
+		for _, run := range runs {
+			stamps = append(stamps, run.Timestamp)
+		}
+		a.Equal(expected, stamps, "INPUT=%v", input)
+	}
+}
+
+func newBolt(t *testing.T) store.Bolt {
+	t.Helper()
+	b, err := store.NewBolt(conf.Bolt{Path: filepath.Join(t.TempDir(), "rinc.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close(context.Background()) })
+	if _, _, err := b.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
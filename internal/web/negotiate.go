@@ -0,0 +1,74 @@
+package web
+
+import (
+	"strconv"
+	"strings"
+)
+
+// negotiate returns the offered media type the client prefers according to
+// the provided Accept header, or an empty string if it accepts none of them.
+// The quality of an offer is the one of the most specific media range
+// matching it, and offers of equal quality are preferred in the order they
+// are provided. A missing Accept header accepts any media type.
+func negotiate(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		accept = "*/*"
+	}
+
+	type mediaRange struct {
+		typ, subtype string
+		q            float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.TrimSpace(params[0]), "/")
+		if !ok {
+			continue
+		}
+		r := mediaRange{
+			typ:     strings.ToLower(strings.TrimSpace(typ)),
+			subtype: strings.ToLower(strings.TrimSpace(subtype)),
+			q:       1,
+		}
+		for _, param := range params[1:] {
+			k, v, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(k) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				q = 0
+			}
+			r.q = q
+		}
+		ranges = append(ranges, r)
+	}
+
+	var best string
+	var bestQ float64
+	for _, offer := range offers {
+		typ, subtype, _ := strings.Cut(offer, "/")
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			var s int
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 2
+			case r.typ == typ && r.subtype == "*":
+				s = 1
+			case r.typ == "*" && r.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,242 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// jwksRefreshInterval is the minimum interval between two fetches of the
+// provider's signing keys, so that tokens signed with unknown keys cannot
+// be used to flood the provider.
+const jwksRefreshInterval = time.Minute
+
+// oidcProvider logs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE.
+type oidcProvider struct {
+	conf   conf.OIDCAuth
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        jose.JSONWebKeySet
+	keysFetched time.Time
+}
+
+// oidcDiscovery is the subset of the provider's discovery document used to
+// log users in.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims are the claims of an ID token used to identify the user.
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// name returns the name the user is identified by in the logs.
+func (c idTokenClaims) name() string {
+	switch {
+	case c.Email != "":
+		return c.Email
+	case c.PreferredUsername != "":
+		return c.PreferredUsername
+	default:
+		return c.Subject
+	}
+}
+
+func newOIDCProvider(c conf.OIDCAuth) *oidcProvider {
+	return &oidcProvider{
+		conf:   c,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// authCodeURL returns the URL of the provider's login page. The user is
+// redirected back to the callback with the provided state.
+func (p *oidcProvider) authCodeURL(ctx context.Context, state loginState) (string, error) {
+	cfg, err := p.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cfg.AuthCodeURL(
+		state.State,
+		oauth2.SetAuthURLParam("nonce", state.Nonce),
+		oauth2.S256ChallengeOption(state.Verifier),
+	), nil
+}
+
+// exchange exchanges the authorization code for an ID token, and returns
+// its claims once verified.
+func (p *oidcProvider) exchange(ctx context.Context, code string, state loginState) (idTokenClaims, error) {
+	cfg, err := p.oauth2Config(ctx)
+	if err != nil {
+		return idTokenClaims{}, err
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	token, err := cfg.Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return idTokenClaims{}, fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return idTokenClaims{}, errors.New("token response holds no ID token")
+	}
+	return p.verify(ctx, raw, state.Nonce)
+}
+
+// verify verifies the signature and the claims of the ID token.
+func (p *oidcProvider) verify(ctx context.Context, raw, nonce string) (idTokenClaims, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return idTokenClaims{}, err
+	}
+	claims := idTokenClaims{}
+	_, err = jwt.ParseWithClaims(
+		raw,
+		&claims,
+		func(t *jwt.Token) (any, error) {
+			kid, _ := t.Header["kid"].(string)
+			return p.key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.conf.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return idTokenClaims{}, fmt.Errorf("verifying ID token: %w", err)
+	}
+	if claims.Nonce != nonce {
+		return idTokenClaims{}, errors.New("verifying ID token: nonce mismatch")
+	}
+	return claims, nil
+}
+
+func (p *oidcProvider) oauth2Config(ctx context.Context) (*oauth2.Config, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     p.conf.ClientID,
+		ClientSecret: p.conf.ClientSecret,
+		RedirectURL:  p.conf.RedirectURL,
+		Scopes:       append([]string{"openid"}, p.conf.Scopes...),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  d.AuthorizationEndpoint,
+			TokenURL: d.TokenEndpoint,
+		},
+	}, nil
+}
+
+// discover fetches the discovery document of the provider. It is fetched
+// once, when first needed, so that the web server starts even if the
+// provider is unreachable.
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	issuer := strings.TrimSuffix(p.conf.IssuerURL, "/")
+	d := new(oidcDiscovery)
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, fmt.Errorf("fetching discovery document: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document issuer %q does not match %q", d.Issuer, p.conf.IssuerURL)
+	}
+	p.discovery = d
+	return d, nil
+}
+
+// key returns the signing key of the provider with the provided ID. The
+// keys are fetched again if there is no such key, in case the provider
+// rotated them.
+func (p *oidcProvider) key(ctx context.Context, kid string) (any, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := findKey(p.keys, kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	keys := jose.JSONWebKeySet{}
+	if err := p.getJSON(ctx, d.JWKSURI, &keys); err != nil {
+		return nil, fmt.Errorf("fetching signing keys: %w", err)
+	}
+	p.keys, p.keysFetched = keys, time.Now()
+	if key, ok := findKey(p.keys, kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// findKey returns the public signing key with the provided ID. If kid is
+// empty, the set must hold a single signing key.
+func findKey(keys jose.JSONWebKeySet, kid string) (any, bool) {
+	var found []jose.JSONWebKey
+	for _, k := range keys.Keys {
+		if k.Use == "enc" || (kid != "" && k.KeyID != kid) {
+			continue
+		}
+		found = append(found, k)
+	}
+	if len(found) != 1 {
+		return nil, false
+	}
+	return found[0].Key, true
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("requesting %q: unexpected status %q", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding %q: %w", url, err)
+	}
+	return nil
+}
+
+// randomString returns a random URL-safe string.
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
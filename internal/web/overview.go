@@ -14,8 +14,6 @@ import (
 	"github.com/accuknox/rinc/view/partial"
 
 	"github.com/labstack/echo/v4"
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 func (s Srv) Overview(c echo.Context) error {
@@ -36,109 +34,99 @@ func (s Srv) Overview(c echo.Context) error {
 		})
 	}
 
+	run, err := s.fetchRun(c.Request().Context(), at)
+	if err != nil {
+		return render(renderParams{
+			Ctx: c,
+			Component: layout.Base(
+				"AccuKnox Reports",
+				view.Error(
+					err.Error(),
+					http.StatusInternalServerError,
+				),
+			),
+			Status: http.StatusInternalServerError,
+		})
+	}
+
 	var statuses []view.OverviewStatus
+	var runStatus db.RunStatus
 
-	for _, coll := range db.Collections {
-		result := db.
-			Database(s.mongo).
-			Collection(coll).
-			FindOne(c.Request().Context(), bson.M{
-				"timestamp": at,
-			})
-		if err := result.Err(); err != nil {
-			if errors.Is(err, mongo.ErrNoDocuments) {
+	if run != nil {
+		runStatus = run.Status
+		for _, r := range run.Reporters {
+			if r.Status == db.RunStatusSkipped {
+				continue
+			}
+			status, ok := overviewStatus(r.Name, id)
+			if !ok {
 				continue
 			}
-			return render(renderParams{
-				Ctx: c,
-				Component: layout.Base(
-					"AccuKnox Reports",
-					view.Error(
-						err.Error(),
-						http.StatusInternalServerError,
+			status.Status = r.Status
+			if r.Status == db.RunStatusFailed {
+				status.Error = r.Error
+				statuses = append(statuses, status)
+				continue
+			}
+			status.AlertsCount, err = s.fetchAlertsCount(c.Request().Context(), r.Name, at)
+			if err != nil {
+				return render(renderParams{
+					Ctx: c,
+					Component: layout.Base(
+						"AccuKnox Reports",
+						view.Error(
+							err.Error(),
+							http.StatusInternalServerError,
+						),
 					),
-				),
-				Status: http.StatusInternalServerError,
-			})
+					Status: http.StatusInternalServerError,
+				})
+			}
+			statuses = append(statuses, status)
 		}
-		count, err := s.fetchAlertsCount(c.Request().Context(), coll, at)
-		if err != nil {
-			return render(renderParams{
-				Ctx: c,
-				Component: layout.Base(
-					"AccuKnox Reports",
-					view.Error(
-						err.Error(),
-						http.StatusInternalServerError,
+	}
+
+	if run == nil {
+		// reports generated before run manifests were recorded are looked up
+		// by probing every collection.
+		for _, coll := range db.Collections {
+			err := s.store.Find(c.Request().Context(), coll, at, nil)
+			if err != nil {
+				if errors.Is(err, errNotFound) {
+					continue
+				}
+				return render(renderParams{
+					Ctx: c,
+					Component: layout.Base(
+						"AccuKnox Reports",
+						view.Error(
+							err.Error(),
+							http.StatusInternalServerError,
+						),
 					),
-				),
-				Status: http.StatusInternalServerError,
-			})
-		}
-		switch coll {
-		case db.CollectionRabbitmq:
-			statuses = append(statuses, view.OverviewStatus{
-				Name:        "RabbitMQ",
-				Slug:        "rabbitmq",
-				ID:          id,
-				AlertsCount: count,
-			})
-		case db.CollectionCeph:
-			statuses = append(statuses, view.OverviewStatus{
-				Name:        "CEPH",
-				Slug:        "ceph",
-				ID:          id,
-				AlertsCount: count,
-			})
-		case db.CollectionDass:
-			statuses = append(statuses, view.OverviewStatus{
-				Name:        "Deployment & Statefulset Status",
-				Slug:        "deployment-and-statefulset-status",
-				ID:          id,
-				AlertsCount: count,
-			})
-		case db.CollectionLongJobs:
-			statuses = append(statuses, view.OverviewStatus{
-				Name:        "Long Running Jobs",
-				Slug:        "longjobs",
-				ID:          id,
-				AlertsCount: count,
-			})
-		case db.CollectionImageTag:
-			statuses = append(statuses, view.OverviewStatus{
-				Name:        "Image Tags",
-				Slug:        "imagetags",
-				ID:          id,
-				AlertsCount: count,
-			})
-		case db.CollectionPVUtilizaton:
-			statuses = append(statuses, view.OverviewStatus{
-				Name:        "PV Utilization",
-				Slug:        "pv-utilization",
-				ID:          id,
-				AlertsCount: count,
-			})
-		case db.CollectionResourceUtilization:
-			statuses = append(statuses, view.OverviewStatus{
-				Name:        "Resource Utilization",
-				Slug:        "resource-utilization",
-				ID:          id,
-				AlertsCount: count,
-			})
-		case db.CollectionConnectivity:
-			statuses = append(statuses, view.OverviewStatus{
-				Name:        "Connectivity",
-				Slug:        "connectivity",
-				ID:          id,
-				AlertsCount: count,
-			})
-		case db.CollectionPodStatus:
-			statuses = append(statuses, view.OverviewStatus{
-				Name:        "Pod Status",
-				Slug:        "podstatus",
-				ID:          id,
-				AlertsCount: count,
-			})
+					Status: http.StatusInternalServerError,
+				})
+			}
+			count, err := s.fetchAlertsCount(c.Request().Context(), coll, at)
+			if err != nil {
+				return render(renderParams{
+					Ctx: c,
+					Component: layout.Base(
+						"AccuKnox Reports",
+						view.Error(
+							err.Error(),
+							http.StatusInternalServerError,
+						),
+					),
+					Status: http.StatusInternalServerError,
+				})
+			}
+			status, ok := overviewStatus(coll, id)
+			if !ok {
+				continue
+			}
+			status.AlertsCount = count
+			statuses = append(statuses, status)
 		}
 	}
 
@@ -162,34 +150,60 @@ func (s Srv) Overview(c echo.Context) error {
 		Component: layout.Base(
 			title,
 			partial.Navbar(true),
-			view.Overview(statuses),
+			view.Overview(runStatus, statuses),
 			partial.Footer(at),
 		),
 	})
 }
 
 func (s Srv) fetchAlertsCount(ctx context.Context, from string, at time.Time) (view.AlertsCount, error) {
-	cursor, err := db.Database(s.mongo).Collection("alerts").Find(ctx, bson.M{
-		"from":      from,
-		"timestamp": at,
-	})
+	docs, err := s.store.Alerts(ctx, at, from)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, nil
-		}
 		return nil, fmt.Errorf("finding alerts from %q at %v: %w", from, at, err)
 	}
-	defer cursor.Close(ctx)
 	count := make(view.AlertsCount, 3)
-	for cursor.Next(ctx) {
-		alerts := new(db.AlertDocument)
-		err := cursor.Decode(alerts)
-		if err != nil {
-			return nil, fmt.Errorf("decoding document at cursor: %w", err)
-		}
-		for _, alert := range alerts.Alerts {
+	for _, doc := range docs {
+		for _, alert := range doc.Alerts {
 			count[alert.Severity]++
 		}
 	}
 	return count, nil
 }
+
+// overviewStatus returns the overview card for the report stored in the
+// provided collection.
+func overviewStatus(coll, id string) (view.OverviewStatus, bool) {
+	status := view.OverviewStatus{ID: id}
+	switch coll {
+	case db.CollectionRabbitmq:
+		status.Name = "RabbitMQ"
+		status.Slug = "rabbitmq"
+	case db.CollectionCeph:
+		status.Name = "CEPH"
+		status.Slug = "ceph"
+	case db.CollectionDass:
+		status.Name = "Deployment & Statefulset Status"
+		status.Slug = "deployment-and-statefulset-status"
+	case db.CollectionLongJobs:
+		status.Name = "Long Running Jobs"
+		status.Slug = "longjobs"
+	case db.CollectionImageTag:
+		status.Name = "Image Tags"
+		status.Slug = "imagetags"
+	case db.CollectionPVUtilizaton:
+		status.Name = "PV Utilization"
+		status.Slug = "pv-utilization"
+	case db.CollectionResourceUtilization:
+		status.Name = "Resource Utilization"
+		status.Slug = "resource-utilization"
+	case db.CollectionConnectivity:
+		status.Name = "Connectivity"
+		status.Slug = "connectivity"
+	case db.CollectionPodStatus:
+		status.Name = "Pod Status"
+		status.Slug = "podstatus"
+	default:
+		return status, false
+	}
+	return status, true
+}
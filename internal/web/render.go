@@ -18,6 +18,8 @@ func render(p renderParams) error {
 	if p.Status != 0 {
 		stat = p.Status
 	}
-	c := templ.Handler(p.Component, templ.WithStatus(stat)).Component
-	return c.Render(p.Ctx.Request().Context(), p.Ctx.Response())
+	templ.
+		Handler(p.Component, templ.WithStatus(stat)).
+		ServeHTTP(p.Ctx.Response(), p.Ctx.Request())
+	return nil
 }
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/util"
+	"github.com/accuknox/rinc/view"
+	"github.com/accuknox/rinc/view/layout"
+	"github.com/accuknox/rinc/view/partial"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+// reportPage renders the report written to the provided collection at the
+// timestamp in the `id` path parameter. The metrics are decoded into an M,
+// and rendered by report along with the alerts fired alongside them. name
+// is the name of the report shown in the title of the page.
+func reportPage[M any](
+	s Srv,
+	c echo.Context,
+	coll, name string,
+	report func(M, []db.Alert) templ.Component,
+) error {
+	id := c.Param("id")
+	title := fmt.Sprintf("%s - %s | AccuKnox Reports", id, name)
+	renderError := func(msg string, status int) error {
+		return render(renderParams{
+			Ctx: c,
+			Component: layout.Base(
+				title,
+				partial.Navbar(false),
+				view.Error(msg, status),
+			),
+			Status: status,
+		})
+	}
+
+	timestamp, err := time.Parse(util.IsosecLayout, id)
+	if err != nil {
+		return renderError("failed to parse timestamp", http.StatusBadRequest)
+	}
+
+	metrics := new(M)
+	err = s.fetchMetrics(c.Request().Context(), coll, timestamp, metrics)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return renderError(
+				"Kindly make sure that the URL is correct",
+				http.StatusNotFound,
+			)
+		}
+		return renderError(err.Error(), http.StatusInternalServerError)
+	}
+
+	alerts, err := s.fetchAlerts(c.Request().Context(), coll, timestamp)
+	if err != nil {
+		return renderError(err.Error(), http.StatusInternalServerError)
+	}
+
+	return render(renderParams{
+		Ctx: c,
+		Component: layout.Base(
+			title,
+			partial.Navbar(false),
+			report(*metrics, alerts),
+		),
+	})
+}
@@ -0,0 +1,115 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errInvalidCookie is returned when a signed cookie is malformed, has been
+// tampered with or has expired.
+var errInvalidCookie = errors.New("invalid cookie")
+
+// session identifies a user logged in with OIDC.
+type session struct {
+	// Subject is the `sub` claim of the ID token.
+	Subject string `json:"sub"`
+	// Name is the name the user is identified by in the logs, e.g., their
+	// email address.
+	Name    string    `json:"name"`
+	Expires time.Time `json:"exp"`
+}
+
+// loginState is stored in a cookie while the user logs in with the
+// provider, and checked when they are redirected back.
+type loginState struct {
+	State    string    `json:"state"`
+	Nonce    string    `json:"nonce"`
+	Verifier string    `json:"verifier"`
+	Next     string    `json:"next"`
+	Expires  time.Time `json:"exp"`
+}
+
+// cookieSigner encodes values into cookies signed with HMAC-SHA256, so that
+// they cannot be forged without the key.
+type cookieSigner struct {
+	key    []byte
+	secure bool
+}
+
+// set sets the cookie called name to the signed value, which should hold
+// an `exp` field. The cookie expires along with the value.
+func (s cookieSigner) set(w http.ResponseWriter, name string, v any, expires time.Time) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshalling cookie %q: %w", name, err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString(payload)
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    enc + "." + s.sign(name, enc),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// get decodes the signed value of the cookie called name into v. It returns
+// errInvalidCookie if the signature does not match, or if the `exp` field
+// of the value is in the past.
+func (s cookieSigner) get(r *http.Request, name string, v any) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+	enc, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(name, enc))) {
+		return errInvalidCookie
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return errInvalidCookie
+	}
+	var exp struct {
+		Expires time.Time `json:"exp"`
+	}
+	if json.Unmarshal(payload, &exp) != nil || time.Now().After(exp.Expires) {
+		return errInvalidCookie
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return errInvalidCookie
+	}
+	return nil
+}
+
+// clear removes the cookie called name.
+func (s cookieSigner) clear(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.secure,
+	})
+}
+
+// sign returns the signature of the encoded value of the cookie called
+// name. The name is signed along with the value so that the value of one
+// cookie cannot be replayed as another.
+func (s cookieSigner) sign(name, enc string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(enc))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,246 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/db"
+	"github.com/accuknox/rinc/internal/store"
+	"github.com/accuknox/rinc/internal/util"
+	"github.com/accuknox/rinc/view"
+	"github.com/accuknox/rinc/view/layout"
+	"github.com/accuknox/rinc/view/partial"
+	"github.com/accuknox/rinc/view/trend"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultTrendRange is the time range charted when none is selected.
+const defaultTrendRange = 7 * 24 * time.Hour
+
+// trendDef is a set of charts that can be selected on the trends page.
+type trendDef struct {
+	slug   string
+	name   string
+	charts []chartDef
+}
+
+// chartDef is a chart of the points selected by a query over the documents
+// of a collection.
+type chartDef struct {
+	title string
+	unit  trend.Unit
+	coll  string
+	query store.SeriesQuery
+}
+
+var trends = []trendDef{
+	{
+		slug: "pv-utilization",
+		name: "PV Utilization",
+		charts: []chartDef{{
+			title: "PVC utilization",
+			unit:  trend.UnitPercent,
+			coll:  db.CollectionPVUtilizaton,
+			query: store.SeriesQuery{
+				List:  "pvs",
+				Name:  []string{"pvcnamespace", "pvc"},
+				Value: "utilizationpercent",
+			},
+		}},
+	},
+	{
+		slug: "node-utilization",
+		name: "Node Utilization",
+		charts: []chartDef{
+			{
+				title: "Node CPU utilization",
+				unit:  trend.UnitPercent,
+				coll:  db.CollectionResourceUtilization,
+				query: store.SeriesQuery{
+					List:  "nodes",
+					Name:  []string{"name"},
+					Value: "cpuusedpercent",
+				},
+			},
+			{
+				title: "Node memory utilization",
+				unit:  trend.UnitPercent,
+				coll:  db.CollectionResourceUtilization,
+				query: store.SeriesQuery{
+					List:  "nodes",
+					Name:  []string{"name"},
+					Value: "memusedpercent",
+				},
+			},
+		},
+	},
+	{
+		slug: "rabbitmq",
+		name: "RabbitMQ",
+		charts: []chartDef{{
+			title: "RabbitMQ queued messages",
+			unit:  trend.UnitCount,
+			coll:  db.CollectionRabbitmq,
+			query: store.SeriesQuery{Fields: []store.SeriesField{
+				{Name: "total", Path: "overview.queue_totals.messages"},
+				{Name: "ready", Path: "overview.queue_totals.messages_ready"},
+				{Name: "unacknowledged", Path: "overview.queue_totals.messages_unacknowledged"},
+			}},
+		}},
+	},
+	{
+		slug: "ceph",
+		name: "CEPH",
+		charts: []chartDef{{
+			title: "CEPH raw capacity",
+			unit:  trend.UnitBytes,
+			coll:  db.CollectionCeph,
+			query: store.SeriesQuery{Fields: []store.SeriesField{
+				{Name: "total", Path: "status.df.stats.total_bytes"},
+				{Name: "used", Path: "status.df.stats.total_used_raw_bytes"},
+				{Name: "available", Path: "status.df.stats.total_avail_bytes"},
+			}},
+		}},
+	},
+	{
+		slug: "alerts",
+		name: "Alerts",
+		charts: []chartDef{{
+			title: "Firing alerts by severity",
+			unit:  trend.UnitCount,
+			coll:  db.CollectionAlerts,
+			query: store.SeriesQuery{
+				List:    "alerts",
+				CountBy: "severity",
+				Count: []string{
+					string(conf.SeverityCritical),
+					string(conf.SeverityWarning),
+					string(conf.SeverityInfo),
+				},
+			},
+		}},
+	},
+}
+
+func findTrend(slug string) (trendDef, bool) {
+	for _, t := range trends {
+		if t.slug == slug {
+			return t, true
+		}
+	}
+	return trendDef{}, false
+}
+
+type trendParams struct {
+	Trend string `query:"trend"`
+	From  string `query:"from"`
+	To    string `query:"to"`
+}
+
+// TrendPage charts the selected trend over the selected range of dates,
+// which defaults to the last week. Only the charts are rendered for htmx
+// requests.
+func (s Srv) TrendPage(c echo.Context) error {
+	params := new(trendParams)
+	if err := c.Bind(params); err != nil {
+		return renderTrendError(c, "failed to parse query", http.StatusBadRequest)
+	}
+	def, ok := trends[0], true
+	if params.Trend != "" {
+		def, ok = findTrend(params.Trend)
+	}
+	if !ok {
+		return renderTrendError(c, fmt.Sprintf("unknown trend %q", params.Trend), http.StatusNotFound)
+	}
+	from, to, err := parseTrendRange(params.From, params.To, time.Now())
+	if err != nil {
+		return renderTrendError(c, err.Error(), http.StatusBadRequest)
+	}
+
+	charts := make([]trend.Chart, 0, len(def.charts))
+	for _, cd := range def.charts {
+		series, err := s.fetchSeries(c.Request().Context(), cd.coll, from, to, cd.query)
+		if err != nil {
+			return renderTrendError(c, err.Error(), http.StatusInternalServerError)
+		}
+		charts = append(charts, trend.Chart{
+			Title:  cd.title,
+			Unit:   cd.unit,
+			Series: series,
+		})
+	}
+
+	if c.Request().Header.Get("HX-Request") == "true" {
+		return render(renderParams{
+			Ctx:       c,
+			Component: trend.Charts(charts),
+		})
+	}
+	options := make([]trend.Option, 0, len(trends))
+	for _, t := range trends {
+		options = append(options, trend.Option{Slug: t.slug, Name: t.name})
+	}
+	return render(renderParams{
+		Ctx: c,
+		Component: layout.Base(
+			fmt.Sprintf("%s - Trends | AccuKnox Reports", def.name),
+			partial.Navbar(false),
+			trend.Page(
+				options,
+				def.slug,
+				from.Format(util.HTMLFormDateLayout),
+				to.Add(-24*time.Hour).Format(util.HTMLFormDateLayout),
+				charts,
+			),
+		),
+	})
+}
+
+// parseTrendRange parses the dates of the range, both inclusive, into the
+// start of the first day and the end of the last day. The range defaults
+// to the week up to now.
+func parseTrendRange(from, to string, now time.Time) (time.Time, time.Time, error) {
+	end := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if to != "" {
+		t, err := time.Parse(util.HTMLFormDateLayout, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("failed to parse `to` date %q", to)
+		}
+		end = t.Add(24 * time.Hour)
+	}
+	start := end.Add(-defaultTrendRange)
+	if from != "" {
+		t, err := time.Parse(util.HTMLFormDateLayout, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("failed to parse `from` date %q", from)
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, errors.New("`from` must not be after `to`")
+	}
+	return start, end, nil
+}
+
+func renderTrendError(c echo.Context, msg string, status int) error {
+	if c.Request().Header.Get("HX-Request") == "true" {
+		return render(renderParams{
+			Ctx:       c,
+			Component: view.Error(msg, status),
+			Status:    status,
+		})
+	}
+	return render(renderParams{
+		Ctx: c,
+		Component: layout.Base(
+			"Trends | AccuKnox Reports",
+			partial.Navbar(false),
+			view.Error(msg, status),
+		),
+		Status: status,
+	})
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"github.com/accuknox/rinc/internal/store"
+	"github.com/accuknox/rinc/view/trend"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTrendRange(t *testing.T) {
+	a := assert.New(t)
+	now := time.Date(2024, 7, 10, 15, 4, 5, 0, time.UTC)
+	day := func(d int) time.Time {
+		return time.Date(2024, 7, d, 0, 0, 0, 0, time.UTC)
+	}
+	type input struct{ from, to string }
+	inputs := map[input][2]time.Time{
+		{}:                                     {day(4), day(11)},
+		{from: "2024-07-01"}:                   {day(1), day(11)},
+		{to: "2024-07-05"}:                     {day(-1), day(6)},
+		{from: "2024-07-01", to: "2024-07-01"}: {day(1), day(2)},
+		{from: "2024-07-02", to: "2024-07-01"}: {},
+		{from: "07/01/2024"}:                   {},
+		{to: "yesterday"}:                      {},
+	}
+	for input, expected := range inputs {
+		from, to, err := parseTrendRange(input.from, input.to, now)
+		if expected[0].IsZero() {
+			a.Error(err, "INPUT=%+v", input)
+			continue
+		}
+		a.NoError(err, "INPUT=%+v", input)
+		a.Equal(expected[0], from, "INPUT=%+v", input)
+		a.Equal(expected[1], to, "INPUT=%+v", input)
+	}
+}
+
+func TestGroupSeries(t *testing.T) {
+	a := assert.New(t)
+	t0 := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	series := groupSeries([]store.Point{
+		{Timestamp: t1, Series: "worker-1", Value: 40},
+		{Timestamp: t0, Series: "worker-2", Value: 10},
+		{Timestamp: t0, Series: "worker-1", Value: 30},
+	})
+	a.Equal([]trend.Series{
+		{Name: "worker-1", Points: []trend.Point{{Timestamp: t0, Value: 30}, {Timestamp: t1, Value: 40}}},
+		{Name: "worker-2", Points: []trend.Point{{Timestamp: t0, Value: 10}}},
+	}, series)
+	a.Empty(groupSeries(nil))
+}
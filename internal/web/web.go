@@ -3,6 +3,7 @@ package web
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -12,37 +13,55 @@ import (
 	"syscall"
 
 	"github.com/accuknox/rinc/internal/conf"
+	"github.com/accuknox/rinc/internal/store"
 	"github.com/accuknox/rinc/internal/util"
 
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 type Srv struct {
 	conf   conf.C
 	router *echo.Echo
-	mongo  *mongo.Client
+	store  store.Store
+	auth   *authenticator
 }
 
-func NewSrv(c conf.C, mongo *mongo.Client) (*Srv, error) {
+func NewSrv(c conf.C, s store.Store) (*Srv, error) {
+	auth, err := newAuthenticator(c.Web.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("configuring authentication: %w", err)
+	}
 	r := echo.New()
 	r.Pre(echoMiddleware.RemoveTrailingSlash()) // trim trailing slash
-	return &Srv{
+	r.Use(auth.middleware)
+	srv := &Srv{
 		conf:   c,
 		router: r,
-		mongo:  mongo,
-	}, nil
+		store:  s,
+		auth:   auth,
+	}
+	srv.routes()
+	return srv, nil
 }
 
-func (s Srv) Run(ctx context.Context) {
-	// configure logger
-	slog.SetDefault(util.NewLogger(s.conf.Log))
-
-	// setup routes
+func (s Srv) routes() {
 	s.router.Static("/static", filepath.Join("static"))
 	s.router.GET("/", s.HistoryPage)
 	s.router.POST("/history/search", s.HistorySearch)
+	s.router.GET("/trends", s.TrendPage)
+	s.router.GET("/diff", s.DiffPage)
+	s.router.GET("/metrics", s.Metrics)
+
+	api := s.router.Group("/api/v1")
+	api.GET("/runs", s.APIRuns)
+	api.GET("/runs/:id", s.APIRun)
+	api.GET("/runs/:id/alerts", s.APIAlerts)
+	api.GET("/runs/:id/reporters/:reporter", s.APIMetrics)
+	api.GET("/reporters", s.APIReporters)
+	api.GET("/reporters/:reporter", s.APIMetricsRange)
+	api.GET("/diff", s.APIDiff)
+
 	s.router.GET("/:id", s.Overview)
 	s.router.GET("/:id/rabbitmq", s.RabbitMQ)
 	s.router.GET("/:id/ceph", s.Ceph)
@@ -54,6 +73,17 @@ func (s Srv) Run(ctx context.Context) {
 	s.router.GET("/:id/connectivity", s.Connectivity)
 	s.router.GET("/:id/podstatus", s.PodStatus)
 
+	if s.auth.oidc != nil {
+		s.router.GET("/auth/login", s.OIDCLogin)
+		s.router.GET("/auth/callback", s.OIDCCallback)
+		s.router.GET("/auth/logout", s.OIDCLogout)
+	}
+}
+
+func (s Srv) Run(ctx context.Context) {
+	// configure logger
+	slog.SetDefault(util.NewLogger(s.conf.Log))
+
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
@@ -44,7 +44,7 @@ func Report(metrics types.Metrics, alerts []db.Alert, conf conf.Connectivity) te
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = partial.Alerts(alerts).Render(ctx, templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = partial.Alerts(metrics.Timestamp, alerts).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
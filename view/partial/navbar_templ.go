@@ -56,7 +56,7 @@ func Navbar(roundedBottom bool) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("\"><div class=\"flex-1\"><a href=\"/\" class=\"text-xl font-bold\"><img class=\"w-36\" src=\"/static/accuknox-logo.svg\" alt=\"AccuKnox Logo\"></a></div></nav></header>")
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("\"><div class=\"flex-1\"><a href=\"/\" class=\"text-xl font-bold\"><img class=\"w-36\" src=\"/static/accuknox-logo.svg\" alt=\"AccuKnox Logo\"></a></div><div class=\"flex gap-4\"><a href=\"/trends\" class=\"text-lg\">Trends</a> <a href=\"/diff\" class=\"text-lg\">Changes</a></div></nav></header>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,195 @@
+package trend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unit is the unit of the values of a chart.
+type Unit string
+
+const (
+	UnitPercent Unit = "percent" // values between 0 and 100
+	UnitBytes   Unit = "bytes"   // values in bytes
+	UnitCount   Unit = "count"   // counts of things, such as messages
+)
+
+// Format formats a value of the unit for the axes and tooltips.
+func (u Unit) Format(v float64) string {
+	switch u {
+	case UnitPercent:
+		return round(v) + "%"
+	case UnitBytes:
+		units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+		idx := 0
+		for math.Abs(v) >= 1024 && idx < len(units)-1 {
+			v /= 1024
+			idx++
+		}
+		if idx == 0 {
+			return fmt.Sprintf("%.0f %s", v, units[idx])
+		}
+		return fmt.Sprintf("%.1f %s", v, units[idx])
+	default:
+		return round(v)
+	}
+}
+
+// round formats v with at most two decimals.
+func round(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// Chart is a line chart of one or more series over time.
+type Chart struct {
+	Title  string
+	Unit   Unit
+	Series []Series
+}
+
+// Series is a named series of values over time.
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+// Point is the value of a series at a point in time.
+type Point struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// Option is a trend that can be selected.
+type Option struct {
+	Slug string
+	Name string
+}
+
+// dimensions of the charts, in SVG user units.
+const (
+	width      = 800
+	height     = 260
+	padLeft    = 80
+	padRight   = 40
+	padTop     = 12
+	padBottom  = 28
+	maxCircles = 60 // points are only marked on sparse series
+)
+
+// palette holds the colors of the series, reused in order.
+var palette = []string{
+	"#2563eb", "#dc2626", "#16a34a", "#d97706", "#7c3aed",
+	"#0891b2", "#db2777", "#4d7c0f", "#9a3412", "#475569",
+}
+
+func color(idx int) string {
+	return palette[idx%len(palette)]
+}
+
+// plot maps the values of a chart to SVG coordinates.
+type plot struct {
+	from, to time.Time
+	max      float64
+	unit     Unit
+}
+
+func newPlot(c Chart) plot {
+	p := plot{unit: c.Unit}
+	for _, s := range c.Series {
+		for _, pt := range s.Points {
+			if p.from.IsZero() || pt.Timestamp.Before(p.from) {
+				p.from = pt.Timestamp
+			}
+			if pt.Timestamp.After(p.to) {
+				p.to = pt.Timestamp
+			}
+			p.max = max(p.max, pt.Value)
+		}
+	}
+	if c.Unit == UnitPercent {
+		p.max = max(p.max, 100)
+	}
+	ticks := niceTicks(p.max, 4)
+	p.max = ticks[len(ticks)-1]
+	return p
+}
+
+func (p plot) x(t time.Time) float64 {
+	span := p.to.Sub(p.from)
+	if span <= 0 {
+		return padLeft + float64(width-padLeft-padRight)/2
+	}
+	return padLeft + float64(width-padLeft-padRight)*float64(t.Sub(p.from))/float64(span)
+}
+
+func (p plot) y(v float64) float64 {
+	return padTop + float64(height-padTop-padBottom)*(1-v/p.max)
+}
+
+// points returns the points of the series in the format of the `points`
+// attribute of an SVG polyline.
+func (p plot) points(s Series) string {
+	var b strings.Builder
+	for idx, pt := range s.Points {
+		if idx != 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f", p.x(pt.Timestamp), p.y(pt.Value))
+	}
+	return b.String()
+}
+
+// yTicks returns the values marked on the vertical axis.
+func (p plot) yTicks() []float64 {
+	return niceTicks(p.max, 4)
+}
+
+// xTicks returns the times marked on the horizontal axis.
+func (p plot) xTicks() []time.Time {
+	if !p.to.After(p.from) {
+		return []time.Time{p.from}
+	}
+	const n = 5
+	ticks := make([]time.Time, n)
+	for idx := range ticks {
+		ticks[idx] = p.from.Add(p.to.Sub(p.from) * time.Duration(idx) / (n - 1))
+	}
+	return ticks
+}
+
+// timeLabel formats a time marked on the horizontal axis, with more detail
+// on shorter time ranges.
+func (p plot) timeLabel(t time.Time) string {
+	if p.to.Sub(p.from) > 72*time.Hour {
+		return t.UTC().Format("Jan 02")
+	}
+	return t.UTC().Format("Jan 02 15:04")
+}
+
+// niceTicks returns n+1 evenly spaced round values from 0 to at least max.
+func niceTicks(max float64, n int) []float64 {
+	if max <= 0 {
+		max = 1
+	}
+	raw := max / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := mag * 10
+	for _, m := range []float64{1, 2, 2.5, 5, 10} {
+		if raw <= m*mag {
+			step = m * mag
+			break
+		}
+	}
+	ticks := make([]float64, n+1)
+	for idx := range ticks {
+		ticks[idx] = step * float64(idx)
+	}
+	return ticks
+}
+
+func ftoa(v float64) string {
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
@@ -0,0 +1,40 @@
+package trend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNiceTicks(t *testing.T) {
+	a := assert.New(t)
+	inputs := map[float64][]float64{
+		0:    {0, 0.25, 0.5, 0.75, 1},
+		100:  {0, 25, 50, 75, 100},
+		101:  {0, 50, 100, 150, 200},
+		7:    {0, 2, 4, 6, 8},
+		1234: {0, 500, 1000, 1500, 2000},
+	}
+	for input, expected := range inputs {
+		a.Equal(expected, niceTicks(input, 4), "INPUT=%v", input)
+	}
+}
+
+func TestUnitFormat(t *testing.T) {
+	a := assert.New(t)
+	type input struct {
+		unit Unit
+		v    float64
+	}
+	inputs := map[input]string{
+		{UnitPercent, 33.3333}: "33.33%",
+		{UnitPercent, 100}:     "100%",
+		{UnitBytes, 512}:       "512 B",
+		{UnitBytes, 1536}:      "1.5 KiB",
+		{UnitBytes, 3 << 40}:   "3.0 TiB",
+		{UnitCount, 42}:        "42",
+	}
+	for input, expected := range inputs {
+		a.Equal(expected, input.unit.Format(input.v), "INPUT=%+v", input)
+	}
+}